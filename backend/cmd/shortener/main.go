@@ -2,20 +2,38 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
-	"os/signal"   // For graceful shutdown on OS signals
-	"syscall"     // For signal constants like SIGINT, SIGTERM
+	"os/signal" // For graceful shutdown on OS signals
+	"syscall"   // For signal constants like SIGINT, SIGTERM
 	"time"
-	
-	"github.com/ConstantineCTF/URLSecure/backend/internal/api"    // HTTP router and handlers
-	"github.com/ConstantineCTF/URLSecure/backend/internal/store"  // Database and redis clients
-	"github.com/ConstantineCTF/URLSecure/backend/pkg/config"     // Config loading from env
-	"github.com/gin-gonic/gin"                                  // HTTP web framework
-	"github.com/joho/godotenv"                                  // Load .env file for env vars
+
+	"github.com/ConstantineCTF/URLSecure/backend/internal/analytics" // Click event pipeline
+	"github.com/ConstantineCTF/URLSecure/backend/internal/api"       // HTTP router and handlers
+	"github.com/ConstantineCTF/URLSecure/backend/internal/ratelimit" // Request rate limiters
+	"github.com/ConstantineCTF/URLSecure/backend/internal/store"     // Database and redis clients
+	"github.com/ConstantineCTF/URLSecure/backend/internal/uniques"   // Unique visitor estimation
+	"github.com/ConstantineCTF/URLSecure/backend/pkg/auth"           // Access token signing keys
+	"github.com/ConstantineCTF/URLSecure/backend/pkg/blocklist"      // Threat-intel lists
+	"github.com/ConstantineCTF/URLSecure/backend/pkg/config"         // Config loading from env
+	"github.com/ConstantineCTF/URLSecure/backend/pkg/geoip"          // Country lookups for click events
+	"github.com/ConstantineCTF/URLSecure/backend/pkg/mailer"         // Verification and password reset emails
+	"github.com/gin-gonic/gin"                                       // HTTP web framework
+	"github.com/joho/godotenv"                                       // Load .env file for env vars
 )
 
+// commands are the "urlsecure <name> ..." subcommands.
+var commands = map[string]func(st store.Store, args []string) error{
+	"migrate": runMigrate, // Manage the schema
+	"review":  runReview,  // Approve or reject links held by the phishing heuristics
+	"plan":    runPlan,    // List plans and move users between them
+	"mfa":     runMFA,     // Require or reset two-factor authentication of a user
+}
+
 func main() {
 	// Load environment variables from .env file if present; safe to ignore errors if missing
 	if err := godotenv.Load(); err != nil {
@@ -31,23 +49,113 @@ func main() {
 		log.Fatalf("failed to load config: %v", err)
 	}
 
-	// Connect to MySQL with config credentials
-	db, err := store.ConnectMySQL(cfg.DBUser, cfg.DBPass, cfg.DBHost, cfg.DBPort, cfg.DBName)
+	// Open the configured storage backend (MySQL, PostgreSQL, SQLite or in-memory)
+	st, err := openStore(cfg)
+	if err != nil {
+		log.Fatalf("failed to open %s store: %v", cfg.DBDriver, err)
+	}
+	defer st.Close() // Close DB connection on program exit
+
+	// Subcommands run against the store and exit instead of serving
+	if len(os.Args) > 1 {
+		if run, ok := commands[os.Args[1]]; ok {
+			if err := run(st, os.Args[2:]); err != nil {
+				log.Fatalf("%s: %v", os.Args[1], err)
+			}
+			return
+		}
+	}
+
+	// SQLite databases are owned by this binary, so they are always migrated on start
+	flags := flag.NewFlagSet("urlsecure", flag.ExitOnError)
+	autoMigrate := flags.Bool("auto-migrate", cfg.AutoMigrate || cfg.DBDriver == "sqlite", "apply pending migrations before serving")
+	flags.Parse(os.Args[1:])
+
+	if *autoMigrate {
+		if err := runMigrate(st, []string{"up"}); err != nil {
+			log.Fatalf("auto-migrate: %v", err)
+		}
+	}
+
+	// Use Redis for caching, rate limits and unique visitor counts when configured,
+	// otherwise fall back to in-process versions
+	uniquesRetention := time.Duration(cfg.UniquesRetention) * 24 * time.Hour
+	var cache store.Cache = store.NewMemoryCache()
+	var limiter ratelimit.Limiter = ratelimit.NewMemoryLimiter()
+	var visitors uniques.Counter = uniques.NewMemoryCounter(uniquesRetention)
+	if cfg.RedisHost != "" {
+		redisClient := store.NewRedisClient(cfg.RedisHost, cfg.RedisPort)
+		defer redisClient.Close() // Close Redis client on exit
+		cache = store.NewRedisCache(redisClient)
+		limiter = ratelimit.NewRedisLimiter(redisClient)
+		visitors = uniques.NewRedisCounter(redisClient, uniquesRetention)
+	}
+
+	// Load threat-intel blocklists; they are re-read when the files change or on SIGHUP
+	threats, err := openBlocklist(cfg)
 	if err != nil {
-		log.Fatalf("failed to connect to MySQL: %v", err)
+		log.Fatalf("failed to load blocklist: %v", err)
+	}
+	watchCtx, stopWatch := context.WithCancel(context.Background())
+	defer stopWatch()
+	if len(cfg.BlocklistFiles) > 0 && cfg.BlocklistReloadSec > 0 {
+		go threats.Watch(watchCtx, time.Duration(cfg.BlocklistReloadSec)*time.Second)
+	}
+	go reloadOnHangup(threats)
+
+	// Click events are parsed and written in batches off the redirect path; closing the
+	// recorder on exit flushes whatever is still queued
+	geo, err := geoip.Open(cfg.GeoIPDB)
+	if err != nil {
+		log.Fatalf("failed to open GeoIP database: %v", err)
+	}
+	defer geo.Close()
+	clicks := analytics.NewRecorder(st, visitors, geo, analytics.Options{
+		BatchSize:     cfg.ClickBatchSize,
+		FlushInterval: time.Duration(cfg.ClickFlushSec) * time.Second,
+	})
+	defer clicks.Close()
+
+	// Fold click events into the hourly rollups behind /api/stats
+	if cfg.ClickRollupSec > 0 {
+		go analytics.Aggregate(watchCtx, st, time.Duration(cfg.ClickRollupSec)*time.Second)
+	}
+
+	// Delete raw click events past each owner's retention; the rollups stay
+	if cfg.ClickPurgeSec > 0 {
+		go analytics.Purge(watchCtx, st, time.Duration(cfg.ClickPurgeSec)*time.Second)
 	}
-	// Set MySQL connection pool parameters for efficiency
-	db.SetMaxOpenConns(25)
-	db.SetMaxIdleConns(25)
-	db.SetConnMaxLifetime(time.Hour)
-	defer db.Close() // Close DB connection on program exit
 
-	// Initialize Redis client
-	redisClient := store.NewRedisClient(cfg.RedisHost, cfg.RedisPort)
-	defer redisClient.Close() // Close Redis client on exit
+	// Expired refresh tokens are useless; drop them hourly
+	go purgeRefreshTokens(watchCtx, st, time.Hour)
+
+	// Access token signing keys; asymmetric keys are rotated and retired in the background
+	if cfg.JWTSecret == "" {
+		log.Fatal("JWT_SECRET must be set")
+	}
+	signer, err := auth.NewKeyManager(auth.KeyConfig{
+		Algorithm: cfg.JWTAlgorithm,
+		Secret:    cfg.JWTSecret,
+		Dir:       cfg.JWTKeyDir,
+		Rotation:  time.Duration(cfg.JWTRotationDays) * 24 * time.Hour,
+		Overlap:   time.Duration(cfg.JWTKeyOverlapHours) * time.Hour,
+	})
+	if err != nil {
+		log.Fatalf("failed to set up JWT keys: %v", err)
+	}
+	if overlap, ttl := time.Duration(cfg.JWTKeyOverlapHours)*time.Hour, time.Duration(cfg.AccessTokenSec)*time.Second; overlap < ttl {
+		log.Printf("warning: JWT_KEY_OVERLAP_HOURS is shorter than ACCESS_TOKEN_TTL; tokens may outlive their key")
+	}
+	go signer.Watch(watchCtx, time.Minute)
+
+	// Outgoing email for verification and password resets
+	mail, err := openMailer(cfg)
+	if err != nil {
+		log.Fatalf("failed to set up mailer: %v", err)
+	}
 
 	// Create HTTP router with all routes and middleware
-	router := api.NewRouter(cfg, db, redisClient)
+	router := api.NewRouter(cfg, api.Deps{Links: st, Users: st, Signer: signer, Tokens: st, APIKeys: st, MFA: st, Mailer: mail, Quotas: st, Analytics: st, Uniques: visitors, Cache: cache, Blocklist: threats, Limiter: limiter, Clicks: clicks})
 
 	// Setup HTTP server with address from config and our router
 	srv := &http.Server{
@@ -83,3 +191,104 @@ func main() {
 	// Confirm server shutdown and exit
 	log.Println("server exiting properly")
 }
+
+// purgeRefreshTokens deletes expired refresh tokens every interval until ctx is done.
+func purgeRefreshTokens(ctx context.Context, tokens store.TokenStore, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if n, err := tokens.DeleteExpiredRefreshTokens(ctx, time.Now()); err != nil {
+			log.Printf("refresh token purge failed: %v", err)
+		} else if n > 0 {
+			log.Printf("deleted %d expired refresh tokens", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// openStore connects to the storage backend selected by cfg.DBDriver.
+func openStore(cfg *config.Config) (store.Store, error) {
+	switch cfg.DBDriver {
+	case "mysql":
+		// Connect to MySQL with config credentials
+		db, err := store.ConnectMySQL(cfg.DBUser, cfg.DBPass, cfg.DBHost, cfg.DBPort, cfg.DBName)
+		if err != nil {
+			return nil, err
+		}
+		return store.NewMySQLStore(db), nil
+	case "postgres":
+		// Connect to PostgreSQL with the same credentials
+		db, err := store.ConnectPostgres(cfg.DBUser, cfg.DBPass, cfg.DBHost, cfg.DBPort, cfg.DBName, cfg.DBSSLMode)
+		if err != nil {
+			return nil, err
+		}
+		return store.NewPostgresStore(db), nil
+	case "sqlite":
+		// Embedded database file, no external services required
+		return store.OpenSQLite(cfg.DBPath)
+	case "memory":
+		// Non-persistent store for local development and tests
+		return store.NewMemoryStore(), nil
+	default:
+		return nil, fmt.Errorf("unknown DB_DRIVER %q", cfg.DBDriver)
+	}
+}
+
+// openMailer returns the mailer selected by cfg.Mailer. The log mailer's file stays
+// open for the life of the process.
+func openMailer(cfg *config.Config) (mailer.Mailer, error) {
+	switch cfg.Mailer {
+	case "smtp":
+		if cfg.SMTPHost == "" {
+			return nil, errors.New("SMTP_HOST must be set for the smtp mailer")
+		}
+		return &mailer.SMTP{Host: cfg.SMTPHost, Port: cfg.SMTPPort, Username: cfg.SMTPUsername, Password: cfg.SMTPPassword, From: cfg.MailFrom}, nil
+	case "log":
+		if cfg.MailLogFile == "" {
+			return mailer.NewLog(cfg.MailFrom, os.Stdout), nil
+		}
+		f, err := os.OpenFile(cfg.MailLogFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+		if err != nil {
+			return nil, err
+		}
+		return mailer.NewLog(cfg.MailFrom, f), nil
+	default:
+		return nil, fmt.Errorf("unknown MAILER %q (want smtp or log)", cfg.Mailer)
+	}
+}
+
+// openBlocklist loads the lists named in cfg.BlocklistFiles. With none configured the
+// list is empty and matches nothing.
+func openBlocklist(cfg *config.Config) (*blocklist.List, error) {
+	sources, err := blocklist.ParseSources(cfg.BlocklistFiles)
+	if err != nil {
+		return nil, err
+	}
+	threats, err := blocklist.New(sources)
+	if err != nil {
+		return nil, err
+	}
+	if len(sources) > 0 {
+		log.Printf("blocklist loaded: %d domains from %d files", threats.Size(), len(sources))
+	}
+	return threats, nil
+}
+
+// reloadOnHangup re-reads the blocklists whenever the process receives SIGHUP.
+func reloadOnHangup(threats *blocklist.List) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	for range hup {
+		if err := threats.Reload(); err != nil {
+			log.Printf("blocklist reload failed: %v", err)
+			continue
+		}
+		log.Printf("blocklist reloaded: %d domains", threats.Size())
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ConstantineCTF/URLSecure/backend/internal/store"
+)
+
+// mfaUsage documents the mfa subcommand.
+const mfaUsage = `usage: urlsecure mfa <command> USER
+
+USER is a username or email. commands:
+  status     show whether USER uses and must use two-factor authentication
+  require    make USER set up 2FA at their next login; signs USER out everywhere
+  optional   stop requiring 2FA for USER (unless REQUIRE_2FA is set)
+  reset      turn USER's 2FA off, e.g. after they lost their authenticator
+             and recovery codes; if 2FA is required they enroll again`
+
+// runMFA implements "urlsecure mfa status|require|optional|reset USER" against the open store.
+func runMFA(st store.Store, args []string) error {
+	if len(args) < 2 {
+		return errors.New(mfaUsage)
+	}
+
+	ctx := context.Background()
+	user, err := st.GetUserByLogin(ctx, args[1])
+	if err != nil {
+		return fmt.Errorf("user %s: %w", args[1], err)
+	}
+
+	switch args[0] {
+	case "status":
+		codes, err := st.CountRecoveryCodes(ctx, user.ID)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("user:            %s\nenabled:         %t\nrequired:        %t\nrecovery codes:  %d left\n",
+			user.Username, user.TwoFactorEnabled(), user.MFARequired, codes)
+		return nil
+
+	case "require", "optional":
+		required := args[0] == "require"
+		if err := st.SetMFARequired(ctx, user.ID, required); err != nil {
+			return err
+		}
+		if required {
+			// Sessions from before must not carry on without a second factor
+			if err := st.RevokeUserTokens(ctx, user.ID, time.Now()); err != nil {
+				return err
+			}
+			log.Printf("%s must now use 2FA and was signed out everywhere", user.Username)
+		} else {
+			log.Printf("2FA is now optional for %s", user.Username)
+		}
+		return nil
+
+	case "reset":
+		if err := st.DisableTOTP(ctx, user.ID); err != nil {
+			return err
+		}
+		log.Printf("turned off 2FA for %s", user.Username)
+		return nil
+
+	default:
+		return errors.New(mfaUsage)
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/ConstantineCTF/URLSecure/backend/internal/migrate" // Embedded schema migrations
+	"github.com/ConstantineCTF/URLSecure/backend/internal/store"
+)
+
+// migrateUsage documents the migrate subcommand.
+const migrateUsage = `usage: urlsecure migrate <command>
+
+commands:
+  up        apply all pending migrations
+  down N    revert the N most recently applied migrations (default 1)
+  status    list migrations and whether they are applied`
+
+// runMigrate implements "urlsecure migrate up|down N|status" against the open store.
+func runMigrate(st store.Store, args []string) error {
+	// Only SQL backends have a schema; the in-memory store has nothing to migrate
+	sqlStore, ok := st.(*store.SQLStore)
+	if !ok {
+		return errors.New("the configured DB_DRIVER has no schema to migrate")
+	}
+
+	m, err := migrate.New(sqlStore.DB(), sqlStore.Driver())
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	ctx := context.Background()
+	switch args[0] {
+	case "up":
+		applied, err := m.Up(ctx)
+		for _, mig := range applied {
+			log.Printf("applied %03d_%s", mig.Version, mig.Name)
+		}
+		if err == nil && len(applied) == 0 {
+			log.Println("schema is up to date")
+		}
+		return err
+
+	case "down":
+		n := 1
+		if len(args) > 1 {
+			if n, err = strconv.Atoi(args[1]); err != nil || n < 1 {
+				return fmt.Errorf("invalid step count %q", args[1])
+			}
+		}
+		reverted, err := m.Down(ctx, n)
+		for _, mig := range reverted {
+			log.Printf("reverted %03d_%s", mig.Version, mig.Name)
+		}
+		return err
+
+	case "status":
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+
+		// Print an aligned table of version, name and applied time
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED")
+		for _, s := range statuses {
+			applied := "pending"
+			if s.Applied {
+				applied = s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(w, "%03d\t%s\t%s\n", s.Version, s.Name, applied)
+		}
+		return w.Flush()
+
+	default:
+		return errors.New(migrateUsage)
+	}
+}
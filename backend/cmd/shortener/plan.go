@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/ConstantineCTF/URLSecure/backend/internal/store"
+)
+
+// planUsage documents the plan subcommand.
+const planUsage = `usage: urlsecure plan <command>
+
+commands:
+  list             list plans and their limits
+  set USER PLAN    move USER (username or email) to PLAN`
+
+// runPlan implements "urlsecure plan list|set USER PLAN" against the open store.
+func runPlan(st store.Store, args []string) error {
+	if len(args) == 0 {
+		return errors.New(planUsage)
+	}
+
+	ctx := context.Background()
+	switch args[0] {
+	case "list":
+		plans, err := st.ListPlans(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "PLAN\tDAILY\tMONTHLY\tACTIVE\tALIASES\tPASSWORDS\tRETENTION")
+		for _, p := range plans {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%t\t%t\t%s\n", p.Name, limitString(p.DailyLinks),
+				limitString(p.MonthlyLinks), limitString(p.MaxActiveLinks), p.CustomAliases,
+				p.PasswordLinks, limitString(p.AnalyticsRetentionDays))
+		}
+		return w.Flush()
+
+	case "set":
+		if len(args) < 3 {
+			return errors.New(planUsage)
+		}
+		user, err := st.GetUserByLogin(ctx, args[1])
+		if err != nil {
+			return fmt.Errorf("user %s: %w", args[1], err)
+		}
+		if err := st.SetUserPlan(ctx, user.ID, args[2]); err != nil {
+			return fmt.Errorf("plan %s: %w", args[2], err)
+		}
+		log.Printf("moved %s to the %s plan", user.Username, args[2])
+		return nil
+
+	default:
+		return errors.New(planUsage)
+	}
+}
+
+// limitString formats an optional limit, where nil means unlimited.
+func limitString(limit *int) string {
+	if limit == nil {
+		return "-"
+	}
+	return strconv.Itoa(*limit)
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ConstantineCTF/URLSecure/backend/internal/model"
+	"github.com/ConstantineCTF/URLSecure/backend/internal/store"
+)
+
+// reviewUsage documents the review subcommand.
+const reviewUsage = `usage: urlsecure review <command>
+
+commands:
+  list           list links held for review by the phishing heuristics
+  approve CODE   activate a held link
+  reject CODE    delete a held link`
+
+// runReview implements "urlsecure review list|approve CODE|reject CODE" against the open store.
+func runReview(st store.Store, args []string) error {
+	if len(args) == 0 {
+		return errors.New(reviewUsage)
+	}
+
+	ctx := context.Background()
+	switch args[0] {
+	case "list":
+		// Page through every owner's pending links, oldest first
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "CODE\tSCORE\tCREATED\tTARGET\tREASONS")
+		q := store.LinkQuery{Status: model.LinkPendingReview, Ascending: true, Limit: 100}
+		for {
+			page, err := st.ListLinks(ctx, q)
+			if err != nil {
+				return err
+			}
+			for _, link := range page {
+				codes := make([]string, len(link.RiskReasons))
+				for i, reason := range link.RiskReasons {
+					codes[i] = reason.Code
+				}
+				fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\n", link.Code, link.RiskScore,
+					link.CreatedAt.Format("2006-01-02 15:04:05"), link.Target, strings.Join(codes, ","))
+			}
+			if len(page) < q.Limit {
+				break
+			}
+			q.After = &store.LinkCursor{ID: page[len(page)-1].ID}
+		}
+		return w.Flush()
+
+	case "approve", "reject":
+		if len(args) < 2 {
+			return errors.New(reviewUsage)
+		}
+		link, err := st.GetLinkByCode(ctx, args[1])
+		if err != nil {
+			return fmt.Errorf("link %s: %w", args[1], err)
+		}
+		if !link.Pending() {
+			return fmt.Errorf("link %s is not pending review", link.Code)
+		}
+
+		if args[0] == "reject" {
+			if err := st.DeleteLink(ctx, link.UserID, link.Code); err != nil {
+				return err
+			}
+			log.Printf("rejected and deleted %s", link.Code)
+			return nil
+		}
+		link.Status = model.LinkActive
+		if err := st.UpdateLink(ctx, link); err != nil {
+			return err
+		}
+		log.Printf("approved %s", link.Code)
+		return nil
+
+	default:
+		return errors.New(reviewUsage)
+	}
+}
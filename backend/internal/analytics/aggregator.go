@@ -0,0 +1,42 @@
+package analytics
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/ConstantineCTF/URLSecure/backend/internal/store"
+)
+
+// rollupBatch is the number of events rolled up per transaction.
+const rollupBatch = 1000
+
+// Aggregate rolls new click events up into the hourly counters every interval until ctx
+// is done. Several replicas may run it at once; each event is counted exactly once.
+func Aggregate(ctx context.Context, clicks store.ClickStore, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			rollup(ctx, clicks)
+		}
+	}
+}
+
+// rollup processes batches until the backlog is empty.
+func rollup(ctx context.Context, clicks store.ClickStore) {
+	for ctx.Err() == nil {
+		n, err := clicks.RollupClicks(ctx, rollupBatch)
+		if err != nil {
+			log.Printf("click rollup failed: %v", err)
+			return
+		}
+		if n < rollupBatch {
+			return
+		}
+	}
+}
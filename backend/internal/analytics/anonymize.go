@@ -0,0 +1,40 @@
+package analytics
+
+import "net/netip"
+
+// Default prefixes kept by an Anonymizer: the network, not the host.
+const (
+	DefaultIPv4Prefix = 24
+	DefaultIPv6Prefix = 48
+)
+
+// Anonymizer truncates client IPs to a network prefix before they are stored, logged or
+// looked up. Only the daily-salted unique visitor fingerprint sees the full address.
+// The zero value keeps IPs intact.
+type Anonymizer struct {
+	IPv4Prefix int // Bits kept of IPv4 addresses; 0 disables truncation
+	IPv6Prefix int // Bits kept of IPv6 addresses; 0 disables truncation
+}
+
+// IP returns ip with the host bits zeroed, e.g. 203.0.113.57 -> 203.0.113.0.
+// Unparseable input is returned unchanged.
+func (a Anonymizer) IP(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ip
+	}
+	addr = addr.Unmap()
+
+	bits := a.IPv6Prefix
+	if addr.Is4() {
+		bits = a.IPv4Prefix
+	}
+	if bits <= 0 || bits >= addr.BitLen() {
+		return addr.String()
+	}
+	prefix, err := addr.WithZone("").Prefix(bits)
+	if err != nil {
+		return ip
+	}
+	return prefix.Addr().String()
+}
@@ -0,0 +1,103 @@
+package analytics
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ConstantineCTF/URLSecure/backend/internal/model"
+	"github.com/ConstantineCTF/URLSecure/backend/internal/store"
+	"github.com/ConstantineCTF/URLSecure/backend/internal/uniques"
+)
+
+func TestAnonymizerIP(t *testing.T) {
+	defaults := Anonymizer{IPv4Prefix: DefaultIPv4Prefix, IPv6Prefix: DefaultIPv6Prefix}
+	tests := []struct {
+		anonymizer Anonymizer
+		ip, want   string
+	}{
+		{defaults, "203.0.113.57", "203.0.113.0"},
+		{defaults, "2001:db8:1234:5678::1", "2001:db8:1234::"},
+		{defaults, "::ffff:203.0.113.57", "203.0.113.0"}, // IPv4-mapped counts as IPv4
+		{defaults, "fe80::1%eth0", "fe80::"},
+		{defaults, "not an ip", "not an ip"},
+		{Anonymizer{IPv4Prefix: 16}, "203.0.113.57", "203.0.0.0"},
+		{Anonymizer{IPv4Prefix: 16}, "2001:db8::1", "2001:db8::1"}, // IPv6 untouched
+		{Anonymizer{IPv4Prefix: 32, IPv6Prefix: 128}, "203.0.113.57", "203.0.113.57"},
+		{Anonymizer{}, "203.0.113.57", "203.0.113.57"},
+	}
+	for _, tt := range tests {
+		if got := tt.anonymizer.IP(tt.ip); got != tt.want {
+			t.Errorf("%+v.IP(%q) = %q, want %q", tt.anonymizer, tt.ip, got, tt.want)
+		}
+	}
+}
+
+// fakeClicks records what a Recorder writes. Other ClickStore methods are not used.
+type fakeClicks struct {
+	store.ClickStore
+	mu     sync.Mutex
+	events []model.ClickEvent
+	counts []model.ClickCount
+}
+
+func (f *fakeClicks) RecordClicks(ctx context.Context, events []model.ClickEvent) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i := range events {
+		events[i].LinkID = 1 // As the store resolves codes
+	}
+	f.events = append(f.events, events...)
+	return nil
+}
+
+func (f *fakeClicks) CountClicks(ctx context.Context, counts []model.ClickCount) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.counts = append(f.counts, counts...)
+	return nil
+}
+
+func TestRecorderKeepsNothingAboutPrivateVisitors(t *testing.T) {
+	clicks := &fakeClicks{}
+	counter := uniques.NewMemoryCounter(24 * time.Hour)
+	r := NewRecorder(clicks, counter, nil, Options{})
+	at := time.Date(2026, 3, 2, 10, 15, 0, 0, time.UTC)
+
+	r.Record(Visit{Code: "abc", At: at, IP: "203.0.113.0", UserAgent: "Firefox", Referrer: "https://example.com/", Private: true})
+	r.Record(Visit{Code: "abc", At: at, IP: "203.0.113.0", UserAgent: "Firefox", Private: true})
+	r.Record(Visit{Code: "abc", At: at, IP: "198.51.100.0", UserAgent: "curl/8", Bot: true, Private: true})
+	r.Record(Visit{Code: "abc", At: at, IP: "192.0.2.0", VisitorIP: "192.0.2.1", UserAgent: "Firefox"})
+	r.Close()
+
+	// Only the tracked visit becomes an event; private humans are an hourly total
+	if len(clicks.events) != 1 {
+		t.Fatalf("%d click events, want 1", len(clicks.events))
+	}
+	want := []model.ClickCount{{Code: "abc", Hour: at.Truncate(time.Hour), Clicks: 2}}
+	if !slices.Equal(clicks.counts, want) {
+		t.Fatalf("counted %+v, want %+v", clicks.counts, want)
+	}
+	if n, _ := counter.Count(context.Background(), 1, at, at); n != 1 {
+		t.Fatalf("%d unique visitors, want only the tracked one", n)
+	}
+}
+
+func TestRecorderCountsVisitorsByFullIP(t *testing.T) {
+	clicks := &fakeClicks{}
+	counter := uniques.NewMemoryCounter(24 * time.Hour)
+	r := NewRecorder(clicks, counter, nil, Options{})
+	at := time.Date(2026, 3, 2, 10, 15, 0, 0, time.UTC)
+
+	// Neighbours on one /24 share the truncated IP but are different people
+	for _, ip := range []string{"203.0.113.7", "203.0.113.8", "203.0.113.8"} {
+		r.Record(Visit{Code: "abc", At: at, IP: "203.0.113.0", VisitorIP: ip, UserAgent: "Firefox"})
+	}
+	r.Close()
+
+	if n, _ := counter.Count(context.Background(), 1, at, at); n != 2 {
+		t.Fatalf("%d unique visitors, want 2", n)
+	}
+}
@@ -0,0 +1,31 @@
+package analytics
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/ConstantineCTF/URLSecure/backend/internal/store"
+)
+
+// Purge deletes raw click events past their owner's retention every interval until
+// ctx is done. It also runs once at start so a long outage cannot delay it.
+func Purge(ctx context.Context, clicks store.ClickStore, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		n, err := clicks.PurgeClickEvents(ctx, time.Now())
+		if err != nil {
+			log.Printf("click event purge failed: %v", err)
+		} else if n > 0 {
+			log.Printf("purged %d click events past retention", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,264 @@
+// Package analytics turns redirects into click events. The redirect path only queues
+// the raw request data; parsing, GeoIP lookups and database writes happen in batches
+// on a background goroutine so visitors are never kept waiting.
+package analytics
+
+import (
+	"context"
+	"log"
+	"net/netip"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ConstantineCTF/URLSecure/backend/internal/model"
+	"github.com/ConstantineCTF/URLSecure/backend/internal/store"
+	"github.com/ConstantineCTF/URLSecure/backend/internal/uniques" // Unique visitor counts
+	"github.com/ConstantineCTF/URLSecure/backend/pkg/geoip"        // Country lookups
+	"github.com/ConstantineCTF/URLSecure/backend/pkg/useragent"    // Browser/OS/device parsing
+	"golang.org/x/text/language"                                   // Accept-Language parsing
+)
+
+// Defaults for Options fields left at zero.
+const (
+	DefaultBatchSize     = 500
+	DefaultFlushInterval = 2 * time.Second
+	DefaultBuffer        = 10000
+)
+
+// writeTimeout bounds a single batch write.
+const writeTimeout = 10 * time.Second
+
+// Visit is the raw data of one redirect, captured on the request path.
+type Visit struct {
+	Code           string    // Short code visited
+	At             time.Time // Time of the redirect
+	IP             string    // Client IP truncated by the Anonymizer, used only for the country lookup
+	VisitorIP      string    // Full client IP, only hashed into the daily-salted unique visitor fingerprint
+	UserAgent      string
+	Referrer       string // Referer header
+	AcceptLanguage string
+	Bot            bool // Classified as a bot by the redirect handler
+	Private        bool // Sent DNT or Sec-GPC: count the click, record nothing about the visitor
+}
+
+// Options tunes the batching.
+type Options struct {
+	BatchSize     int           // Events per write; a full batch is written immediately
+	FlushInterval time.Duration // Longest a queued event waits before being written
+	Buffer        int           // Visits queued before new ones are dropped
+}
+
+// Recorder queues visits and writes them as click events in the background.
+// A nil *Recorder discards visits.
+type Recorder struct {
+	clicks  store.ClickStore
+	uniques uniques.Counter
+	geo     *geoip.DB
+	opts    Options
+	visits  chan Visit
+	done    chan struct{}
+	dropped atomic.Uint64
+
+	mu     sync.RWMutex // Guards closed against Record racing Close
+	closed bool
+}
+
+// NewRecorder starts a Recorder writing events to clicks and human visitors to counter.
+// geo may be nil, leaving countries empty.
+func NewRecorder(clicks store.ClickStore, counter uniques.Counter, geo *geoip.DB, opts Options) *Recorder {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = DefaultFlushInterval
+	}
+	if opts.Buffer <= 0 {
+		opts.Buffer = DefaultBuffer
+	}
+	r := &Recorder{
+		clicks:  clicks,
+		uniques: counter,
+		geo:     geo,
+		opts:    opts,
+		visits:  make(chan Visit, opts.Buffer),
+		done:    make(chan struct{}),
+	}
+	go r.run()
+	return r
+}
+
+// Record queues v without blocking. When the queue is full (the database cannot keep
+// up) the visit is dropped rather than slowing down the redirect.
+func (r *Recorder) Record(v Visit) {
+	if r == nil {
+		return
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.closed {
+		return
+	}
+	select {
+	case r.visits <- v:
+	default:
+		if n := r.dropped.Add(1); n == 1 || n%1000 == 0 {
+			log.Printf("click queue full: %d events dropped so far", n)
+		}
+	}
+}
+
+// Close stops accepting visits and waits until the queued ones are written.
+func (r *Recorder) Close() error {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	if !r.closed {
+		r.closed = true
+		close(r.visits)
+	}
+	r.mu.Unlock()
+	<-r.done
+	return nil
+}
+
+// run collects visits into batches and writes them when full or on every tick.
+func (r *Recorder) run() {
+	defer close(r.done)
+
+	ticker := time.NewTicker(r.opts.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]Visit, 0, r.opts.BatchSize)
+	for {
+		select {
+		case v, ok := <-r.visits:
+			if !ok {
+				r.write(batch)
+				return
+			}
+			batch = append(batch, v)
+			if len(batch) >= r.opts.BatchSize {
+				r.write(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			r.write(batch)
+			batch = batch[:0]
+		}
+	}
+}
+
+// write stores one batch as click events, then counts the human visitors among them.
+// Private visits only add to the hourly totals. A failed batch is logged and dropped
+// so memory stays bounded.
+func (r *Recorder) write(batch []Visit) {
+	if len(batch) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), writeTimeout)
+	defer cancel()
+
+	tracked := make([]Visit, 0, len(batch))
+	private := make(map[model.ClickCount]int)
+	for _, v := range batch {
+		switch {
+		case !v.Private:
+			tracked = append(tracked, v)
+		case !v.Bot: // Bots are never part of the totals
+			private[model.ClickCount{Code: v.Code, Hour: v.At.UTC().Truncate(time.Hour)}]++
+		}
+	}
+	if len(private) > 0 {
+		counts := make([]model.ClickCount, 0, len(private))
+		for count, clicks := range private {
+			count.Clicks = clicks
+			counts = append(counts, count)
+		}
+		if err := r.clicks.CountClicks(ctx, counts); err != nil {
+			log.Printf("failed to count %d private clicks: %v", len(counts), err)
+		}
+	}
+	if len(tracked) == 0 {
+		return
+	}
+
+	events := make([]model.ClickEvent, len(tracked))
+	for i, v := range tracked {
+		events[i] = r.event(v)
+	}
+	if err := r.clicks.RecordClicks(ctx, events); err != nil {
+		log.Printf("failed to write %d click events: %v", len(events), err)
+		return
+	}
+
+	// The store resolved codes to link IDs; deleted links have none. The fingerprint
+	// uses the full IP: the truncated one would merge everyone on a network into one
+	// visitor, and the salted hash reveals no more than the truncated address does.
+	visitors := make([]uniques.Visit, 0, len(tracked))
+	for i, v := range tracked {
+		if events[i].LinkID != 0 && !v.Bot {
+			visitors = append(visitors, uniques.Visit{LinkID: events[i].LinkID, At: v.At, IP: v.VisitorIP, UserAgent: v.UserAgent})
+		}
+	}
+	if err := r.uniques.Add(ctx, visitors); err != nil {
+		log.Printf("failed to count unique visitors: %v", err)
+	}
+}
+
+// event parses a visit into the stored click event.
+func (r *Recorder) event(v Visit) model.ClickEvent {
+	e := model.ClickEvent{
+		Code:         v.Code,
+		ClickedAt:    v.At.UTC(),
+		ReferrerHost: referrerHost(v.Referrer),
+		Language:     preferredLanguage(v.AcceptLanguage),
+		Bot:          v.Bot,
+	}
+	if !v.Bot {
+		agent := useragent.Parse(v.UserAgent)
+		e.Browser, e.OS, e.Device = agent.Browser, agent.OS, agent.Device
+	}
+	if addr, err := netip.ParseAddr(v.IP); err == nil {
+		e.Country = r.geo.Country(addr)
+	}
+	return e
+}
+
+// referrerHost reduces a Referer header to its host, without "www.". Only the host is
+// kept: full referrer URLs can carry search terms and session tokens.
+func referrerHost(referrer string) string {
+	if referrer == "" {
+		return ""
+	}
+	u, err := url.Parse(referrer)
+	if err != nil {
+		return ""
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	return truncate(host, 255)
+}
+
+// anyLanguage is the tag of the Accept-Language wildcard.
+var anyLanguage = language.MustParse("mul")
+
+// preferredLanguage returns the highest-weighted tag of an Accept-Language header, e.g. "en-US".
+func preferredLanguage(header string) string {
+	tags, _, err := language.ParseAcceptLanguage(header)
+	// "*" (any language) parses as "mul" and says nothing about the visitor
+	if err != nil || len(tags) == 0 || tags[0] == language.Und || tags[0] == anyLanguage {
+		return ""
+	}
+	return truncate(tags[0].String(), 35)
+}
+
+// truncate cuts s to at most n bytes to fit its column.
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
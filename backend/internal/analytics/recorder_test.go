@@ -0,0 +1,34 @@
+package analytics
+
+import "testing"
+
+func TestReferrerHost(t *testing.T) {
+	tests := []struct{ referrer, want string }{
+		{"", ""},
+		{"https://www.Google.com/search?q=secret+plans", "google.com"},
+		{"https://news.ycombinator.com/item?id=1", "news.ycombinator.com"},
+		{"android-app://com.slack/", "com.slack"},
+		{"http://[::1]:8080/x", "::1"},
+		{"%zz", ""},
+	}
+	for _, tt := range tests {
+		if got := referrerHost(tt.referrer); got != tt.want {
+			t.Errorf("referrerHost(%q) = %q, want %q", tt.referrer, got, tt.want)
+		}
+	}
+}
+
+func TestPreferredLanguage(t *testing.T) {
+	tests := []struct{ header, want string }{
+		{"", ""},
+		{"en-US,en;q=0.9", "en-US"},
+		{"fr;q=0.5, de-CH", "de-CH"},
+		{"*", ""},
+		{"not a language header;;;", ""},
+	}
+	for _, tt := range tests {
+		if got := preferredLanguage(tt.header); got != tt.want {
+			t.Errorf("preferredLanguage(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
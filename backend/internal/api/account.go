@@ -0,0 +1,290 @@
+package api
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ConstantineCTF/URLSecure/backend/internal/model"
+	"github.com/ConstantineCTF/URLSecure/backend/internal/store"
+	authpkg "github.com/ConstantineCTF/URLSecure/backend/pkg/auth" // Password hashing
+	"github.com/ConstantineCTF/URLSecure/backend/pkg/mailer"       // Outgoing email
+	"github.com/gin-gonic/gin"
+)
+
+// Account token purposes and lifetimes
+const (
+	purposeVerifyEmail   = "verify-email"
+	purposeResetPassword = "reset-password"
+	purposeMFAChallenge  = "mfa-challenge" // See mfaService
+	verifyEmailTTL       = 48 * time.Hour
+	resetPasswordTTL     = time.Hour
+	mailTimeout          = 15 * time.Second // Longest an email may take to send
+)
+
+// errAccountToken is returned for malformed, expired, forged or already used tokens.
+var errAccountToken = errors.New("invalid or expired token")
+
+// accountTokens issues and checks the signed tokens mailed for email verification and
+// password resets. Like link access cookies they need no storage: the signature covers
+// the state the token changes (the email address, the password hash), so each token
+// stops working once it has been used.
+type accountTokens struct {
+	key []byte
+}
+
+// newAccountTokens derives the token signing key from the application secret.
+func newAccountTokens(secret string) *accountTokens {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("urlsecure account tokens"))
+	return &accountTokens{key: mac.Sum(nil)}
+}
+
+// accountTokenState is what a token for purpose is bound to.
+func accountTokenState(purpose string, user *model.User) string {
+	if purpose == purposeResetPassword || purpose == purposeMFAChallenge {
+		return user.PasswordHash
+	}
+	return user.Email
+}
+
+// sign returns the MAC of a token for user expiring at expires (Unix seconds).
+func (t *accountTokens) sign(purpose string, user *model.User, expires int64) string {
+	mac := hmac.New(sha256.New, t.key)
+	fmt.Fprintf(mac, "%s|%d|%d|%s", purpose, user.ID, expires, accountTokenState(purpose, user))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// issue returns a token for user, formatted "<user ID>.<expires>.<MAC>".
+func (t *accountTokens) issue(purpose string, user *model.User, ttl time.Duration) string {
+	expires := time.Now().Add(ttl).Unix()
+	return fmt.Sprintf("%d.%d.%s", user.ID, expires, t.sign(purpose, user, expires))
+}
+
+// verify checks a token for purpose and returns the user it was issued to.
+func (t *accountTokens) verify(ctx context.Context, users store.UserStore, purpose, token string) (*model.User, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errAccountToken
+	}
+	userID, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return nil, errAccountToken
+	}
+	expires, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || time.Now().Unix() >= expires {
+		return nil, errAccountToken
+	}
+
+	user, err := users.GetUserByID(ctx, userID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, errAccountToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal([]byte(parts[2]), []byte(t.sign(purpose, user, expires))) {
+		return nil, errAccountToken
+	}
+	if purpose == purposeVerifyEmail && user.EmailVerifiedAt != nil {
+		return nil, errAccountToken // Already used
+	}
+	return user, nil
+}
+
+// accountService mails verification and password reset links.
+type accountService struct {
+	users    store.UserStore
+	tokens   store.TokenStore // Refresh tokens, revoked when the password changes
+	signer   *accountTokens
+	mail     mailer.Mailer
+	appURL   string // Public base URL the links in emails point to
+	required bool   // Whether creating links needs a verified address
+}
+
+// link returns the URL of a frontend page handling token.
+func (a *accountService) link(page, token string) string {
+	return strings.TrimSuffix(a.appURL, "/") + "/assets/" + page + "?token=" + url.QueryEscape(token)
+}
+
+// sendVerification mails user a link confirming their address.
+func (a *accountService) sendVerification(ctx context.Context, user *model.User) error {
+	ctx, cancel := context.WithTimeout(ctx, mailTimeout)
+	defer cancel()
+	return a.mail.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your URLSecure email address",
+		Body: fmt.Sprintf("Hi %s,\n\nplease confirm that this is your email address by opening this link:\n\n%s\n\n"+
+			"The link expires in %d hours. If you did not sign up for URLSecure, you can ignore this email.\n",
+			user.Username, a.link("verify-email.html", a.signer.issue(purposeVerifyEmail, user, verifyEmailTTL)), int(verifyEmailTTL.Hours())),
+	})
+}
+
+// sendPasswordReset mails user a link for choosing a new password.
+func (a *accountService) sendPasswordReset(ctx context.Context, user *model.User) error {
+	ctx, cancel := context.WithTimeout(ctx, mailTimeout)
+	defer cancel()
+	return a.mail.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your URLSecure password",
+		Body: fmt.Sprintf("Hi %s,\n\nsomeone asked to reset the password of your URLSecure account. To choose a new one, open this link:\n\n%s\n\n"+
+			"The link expires in %d minutes and works once. If you did not ask for this, you can ignore this email; your password stays the same.\n",
+			user.Username, a.link("reset-password.html", a.signer.issue(purposeResetPassword, user, resetPasswordTTL)), int(resetPasswordTTL.Minutes())),
+	})
+}
+
+// requestVerificationHandler mails the caller a new verification link.
+func requestVerificationHandler(accounts *accountService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, err := accounts.users.GetUserByID(c.Request.Context(), c.GetUint64("userID"))
+		if err != nil {
+			c.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+		if user.EmailVerifiedAt != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "email address already verified"})
+			return
+		}
+		if err := accounts.sendVerification(c.Request.Context(), user); err != nil {
+			c.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "could not send email"})
+			return
+		}
+		c.JSON(http.StatusAccepted, gin.H{"status": "verification email sent"})
+	}
+}
+
+// verifyEmailHandler confirms an address with the token from the verification email.
+func verifyEmailHandler(accounts *accountService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Token string `json:"token" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		ctx := c.Request.Context()
+		user, err := accounts.signer.verify(ctx, accounts.users, purposeVerifyEmail, req.Token)
+		if err == nil {
+			err = accounts.users.MarkEmailVerified(ctx, user.ID, user.Email, time.Now())
+		}
+		switch {
+		case errors.Is(err, errAccountToken), errors.Is(err, store.ErrNotFound):
+			c.JSON(http.StatusBadRequest, gin.H{"error": errAccountToken.Error()})
+			return
+		case err != nil:
+			c.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"emailVerified": true})
+	}
+}
+
+// requestPasswordResetHandler mails a reset link if the address belongs to an account.
+// The answer is the same either way, and the mail goes out in the background, so the
+// endpoint cannot be used to find out which addresses are registered.
+func requestPasswordResetHandler(accounts *accountService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Email string `json:"email" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		user, err := accounts.users.GetUserByLogin(c.Request.Context(), req.Email)
+		switch {
+		case err == nil && user.Email == req.Email: // Not a username that happens to match
+			go func() {
+				if err := accounts.sendPasswordReset(context.Background(), user); err != nil {
+					log.Printf("failed to send password reset email to user %d: %v", user.ID, err)
+				}
+			}()
+		case err != nil && !errors.Is(err, store.ErrNotFound):
+			c.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+		c.JSON(http.StatusAccepted, gin.H{"status": "if the address belongs to an account, a reset link is on its way"})
+	}
+}
+
+// resetPasswordHandler sets a new password with the token from the reset email and
+// logs the account out everywhere.
+func resetPasswordHandler(accounts *accountService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Token    string `json:"token" binding:"required"`
+			Password string `json:"password" binding:"required,max=72"` // bcrypt ignores anything longer
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		ctx := c.Request.Context()
+		user, err := accounts.signer.verify(ctx, accounts.users, purposeResetPassword, req.Token)
+		if errors.Is(err, errAccountToken) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+
+		hash, err := authpkg.HashPassword(req.Password)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "could not hash password"})
+			return
+		}
+		if err := accounts.users.SetUserPassword(ctx, user.ID, hash); err != nil {
+			c.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+
+		// Whoever knew the old password may still hold a session
+		if err := accounts.tokens.RevokeUserTokens(ctx, user.ID, time.Now()); err != nil {
+			c.Error(err)
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// requireVerifiedEmail refuses link creation to users who have not verified their
+// address, when the deployment requires it.
+func requireVerifiedEmail(accounts *accountService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !accounts.required {
+			c.Next()
+			return
+		}
+		user, err := accounts.users.GetUserByID(c.Request.Context(), c.GetUint64("userID"))
+		if err != nil {
+			c.Error(err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+		if user.EmailVerifiedAt == nil {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "verify your email address before creating links"})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,127 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ConstantineCTF/URLSecure/backend/internal/model"
+	"github.com/ConstantineCTF/URLSecure/backend/internal/store"
+	authpkg "github.com/ConstantineCTF/URLSecure/backend/pkg/auth"
+	"github.com/gin-gonic/gin"
+)
+
+// createUser stores a user with password "hunter22" and returns it.
+func createUser(t *testing.T, st *store.MemoryStore) *model.User {
+	t.Helper()
+	hash, err := authpkg.HashPassword("hunter22")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := &model.User{Username: "alice", Email: "alice@example.com", PasswordHash: hash, Plan: "free"}
+	if err := st.CreateUser(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func TestAccountTokenRejectsTampering(t *testing.T) {
+	ctx := context.Background()
+	st := store.NewMemoryStore()
+	user := createUser(t, st)
+	signer := newAccountTokens("test secret")
+	token := signer.issue(purposeResetPassword, user, time.Hour)
+	parts := strings.Split(token, ".")
+	expires, _ := strconv.ParseInt(parts[1], 10, 64)
+
+	if got, err := signer.verify(ctx, st, purposeResetPassword, token); err != nil || got.ID != user.ID {
+		t.Fatalf("genuine token: %v", err)
+	}
+	tests := []struct {
+		name    string
+		signer  *accountTokens
+		purpose string
+		token   string
+	}{
+		{"other purpose", signer, purposeVerifyEmail, token},
+		{"other secret", newAccountTokens("other secret"), purposeResetPassword, token},
+		{"other user", signer, purposeResetPassword, "2." + parts[1] + "." + parts[2]},
+		{"later expiry", signer, purposeResetPassword, parts[0] + "." + strconv.FormatInt(expires+3600, 10) + "." + parts[2]},
+		{"expired", signer, purposeResetPassword, signer.issue(purposeResetPassword, user, -time.Second)},
+		{"malformed", signer, purposeResetPassword, "not-a-token"},
+		{"empty", signer, purposeResetPassword, ""},
+	}
+	for _, tt := range tests {
+		if _, err := tt.signer.verify(ctx, st, tt.purpose, tt.token); !errors.Is(err, errAccountToken) {
+			t.Errorf("%s: %v, want errAccountToken", tt.name, err)
+		}
+	}
+}
+
+func TestVerifyEmailTokenIsSingleUse(t *testing.T) {
+	ctx := context.Background()
+	st := store.NewMemoryStore()
+	user := createUser(t, st)
+	signer := newAccountTokens("test secret")
+	token := signer.issue(purposeVerifyEmail, user, verifyEmailTTL)
+
+	if _, err := signer.verify(ctx, st, purposeVerifyEmail, token); err != nil {
+		t.Fatalf("first use: %v", err)
+	}
+	if err := st.MarkEmailVerified(ctx, user.ID, user.Email, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := signer.verify(ctx, st, purposeVerifyEmail, token); !errors.Is(err, errAccountToken) {
+		t.Fatalf("after verification: %v, want errAccountToken", err)
+	}
+}
+
+// postJSON sends body to path on r and returns the response.
+func postJSON(r http.Handler, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestResetPasswordTokenIsSingleUse(t *testing.T) {
+	ctx := context.Background()
+	st := store.NewMemoryStore()
+	user := createUser(t, st)
+	accounts := &accountService{users: st, tokens: st, signer: newAccountTokens("test secret")}
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/reset", resetPasswordHandler(accounts))
+
+	session := &model.RefreshToken{UserID: user.ID, FamilyID: "f", TokenHash: "h", ExpiresAt: time.Now().Add(time.Hour)}
+	if err := st.CreateRefreshToken(ctx, session); err != nil {
+		t.Fatal(err)
+	}
+	token := accounts.signer.issue(purposeResetPassword, user, resetPasswordTTL)
+	body := `{"token": "` + token + `", "password": "correct horse"}`
+
+	if w := postJSON(r, "/reset", body); w.Code != http.StatusNoContent {
+		t.Fatalf("first reset: status %d: %s", w.Code, w.Body)
+	}
+	updated, err := st.GetUserByID(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if authpkg.CheckPassword(updated.PasswordHash, "correct horse") != nil {
+		t.Fatal("password was not changed")
+	}
+	if session, err := st.GetRefreshToken(ctx, "h"); err != nil || session.RevokedAt == nil {
+		t.Fatalf("existing session not revoked: %v", err)
+	}
+
+	// The new password hash no longer matches the token's signature
+	if w := postJSON(r, "/reset", body); w.Code != http.StatusBadRequest {
+		t.Fatalf("second reset: status %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Alias length bounds; links.code is VARCHAR(16)
+const (
+	minAliasLen = 3
+	maxAliasLen = 16
+)
+
+// reservedAliases are words that collide with routes or could be used to impersonate the service.
+var reservedAliases = []string{
+	"api", "assets", "r", "admin", "administrator", "login", "logout", "signup", "register",
+	"dashboard", "account", "settings", "static", "public", "health", "status", "help",
+	"support", "about", "terms", "privacy", "security", "root", "www", "urlsecure",
+}
+
+// aliasValidator checks user-chosen short codes against the character set,
+// length limits, the reserved list and the configured blocklist.
+type aliasValidator struct {
+	blocked map[string]struct{} // Lower-cased reserved and blocklisted words
+}
+
+// newAliasValidator merges the built-in reserved words with extra blocklisted words from config.
+func newAliasValidator(blocklist []string) *aliasValidator {
+	v := &aliasValidator{blocked: make(map[string]struct{})}
+	for _, word := range reservedAliases {
+		v.blocked[word] = struct{}{}
+	}
+	for _, word := range blocklist {
+		v.blocked[strings.ToLower(word)] = struct{}{}
+	}
+	return v
+}
+
+// Validate returns a user-facing error if alias cannot be used as a short code.
+func (v *aliasValidator) Validate(alias string) error {
+	if len(alias) < minAliasLen || len(alias) > maxAliasLen {
+		return fmt.Errorf("alias must be between %d and %d characters", minAliasLen, maxAliasLen)
+	}
+
+	// Same URL-safe alphabet as generated codes
+	for _, r := range alias {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return fmt.Errorf("alias may only contain letters, digits, '-' and '_'")
+		}
+	}
+
+	// Reserved words are matched case-insensitively so "Admin" is blocked too
+	if _, blocked := v.blocked[strings.ToLower(alias)]; blocked {
+		return fmt.Errorf("alias %q is reserved", alias)
+	}
+	return nil
+}
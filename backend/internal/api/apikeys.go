@@ -0,0 +1,129 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ConstantineCTF/URLSecure/backend/internal/model"
+	"github.com/ConstantineCTF/URLSecure/backend/internal/store"
+	authpkg "github.com/ConstantineCTF/URLSecure/backend/pkg/auth" // API key generation and hashing
+	"github.com/gin-gonic/gin"
+)
+
+// maxAPIKeys caps how many live keys one user may hold.
+const maxAPIKeys = 50
+
+// apiKeyJSON is the API representation of a key; the key itself is never included.
+func apiKeyJSON(key *model.APIKey) gin.H {
+	return gin.H{
+		"id":         key.ID,
+		"name":       key.Name,
+		"prefix":     key.Prefix,
+		"scopes":     key.Scopes,
+		"createdAt":  key.CreatedAt,
+		"lastUsedAt": key.LastUsedAt,
+		"expiresAt":  key.ExpiresAt,
+	}
+}
+
+// listAPIKeysHandler returns the caller's live API keys.
+func listAPIKeysHandler(keys store.APIKeyStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		found, err := keys.ListAPIKeys(c.Request.Context(), c.GetUint64("userID"))
+		if err != nil {
+			c.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+		items := make([]gin.H, 0, len(found))
+		for _, key := range found {
+			items = append(items, apiKeyJSON(key))
+		}
+		c.JSON(http.StatusOK, gin.H{"keys": items})
+	}
+}
+
+// createAPIKeyHandler creates a named key with the requested scopes. The response is
+// the only time the key itself is shown.
+func createAPIKeyHandler(keys store.APIKeyStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Name      string     `json:"name" binding:"required,max=100"` // Label, e.g. "CI"
+			Scopes    []string   `json:"scopes" binding:"required,min=1"` // Subset of model.Scopes
+			ExpiresAt *time.Time `json:"expiresAt"`                       // Optional RFC 3339 expiry time
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		name := strings.TrimSpace(req.Name)
+		if name == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "name must not be blank"})
+			return
+		}
+		var scopes []string
+		for _, scope := range req.Scopes {
+			if !slices.Contains(model.Scopes, scope) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unknown scope %q; valid scopes are %s", scope, strings.Join(model.Scopes, ", "))})
+				return
+			}
+			if !slices.Contains(scopes, scope) {
+				scopes = append(scopes, scope)
+			}
+		}
+		if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "expiresAt must be in the future"})
+			return
+		}
+
+		userID := c.GetUint64("userID")
+		existing, err := keys.ListAPIKeys(c.Request.Context(), userID)
+		if err != nil {
+			c.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+		if len(existing) >= maxAPIKeys {
+			c.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("at most %d API keys; revoke one to create another", maxAPIKeys)})
+			return
+		}
+
+		secret, prefix, hash := authpkg.NewAPIKey()
+		key := &model.APIKey{UserID: userID, Name: name, Prefix: prefix, KeyHash: hash, Scopes: scopes, ExpiresAt: req.ExpiresAt}
+		if err := keys.CreateAPIKey(c.Request.Context(), key); err != nil {
+			c.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+
+		resp := apiKeyJSON(key)
+		resp["key"] = secret // Shown only in this response
+		c.JSON(http.StatusCreated, resp)
+	}
+}
+
+// revokeAPIKeyHandler revokes one of the caller's keys; it stops working immediately.
+func revokeAPIKeyHandler(keys store.APIKeyStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		if err := keys.RevokeAPIKey(c.Request.Context(), c.GetUint64("userID"), id, time.Now()); err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+				return
+			}
+			c.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
@@ -1,14 +1,15 @@
 package api
 
 import (
-	"database/sql"
+	"context"
+	"errors"
+	"log"
 	"net/http"
-	"strings"
 
+	"github.com/ConstantineCTF/URLSecure/backend/internal/model"
+	"github.com/ConstantineCTF/URLSecure/backend/internal/store"   // Storage interfaces and errors
+	authpkg "github.com/ConstantineCTF/URLSecure/backend/pkg/auth" // Auth utilities (hashing, JWT)
 	"github.com/gin-gonic/gin"
-	_ "github.com/go-redis/redis/v8"            // Redis client import for side effects
-	"github.com/go-sql-driver/mysql"             // MySQL driver specific errors
-	authpkg "github.com/ConstantineCTF/URLSecure/backend/pkg/auth"  // Auth utilities (hashing, JWT)
 )
 
 /* RegisterRoutes wires auth and protected endpoints.
@@ -28,7 +29,7 @@ func RegisterRoutes(r *gin.Engine, db *sql.DB, rdb *redis.Client) {
 }
 */
 
-func registerHandler(db *sql.DB) gin.HandlerFunc {
+func registerHandler(users store.UserStore, sessions *sessionService, accounts *accountService, mfa *mfaService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req struct {
 			Username string `json:"username"`
@@ -48,46 +49,49 @@ func registerHandler(db *sql.DB) gin.HandlerFunc {
 			return
 		}
 
-		// Insert new user into MySQL users table
-		_, err = db.Exec(
-			"INSERT INTO users (username, email, password_hash) VALUES (?, ?, ?)",
-			req.Username, req.Email, hash,
-		)
-		if err != nil {
+		// Insert new user; the store fills in the generated ID
+		user := &model.User{Username: req.Username, Email: req.Email, PasswordHash: hash}
+		if err := users.CreateUser(c.Request.Context(), user); err != nil {
 			// Handle duplicate username/email error
-			if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == 1062 {
-				msg := "username or email already in use"
-				if strings.Contains(mysqlErr.Message, "username") {
-					msg = "username already taken"
-				} else if strings.Contains(mysqlErr.Message, "email") {
-					msg = "email already registered"
-				}
-				c.JSON(http.StatusConflict, gin.H{"error": msg})
-			} else {
+			switch {
+			case errors.Is(err, store.ErrUsernameTaken):
+				c.JSON(http.StatusConflict, gin.H{"error": "username already taken"})
+			case errors.Is(err, store.ErrEmailTaken):
+				c.JSON(http.StatusConflict, gin.H{"error": "email already registered"})
+			default:
 				c.Error(err)
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "registration failed"})
 			}
 			return
 		}
 
-		// Query new user's ID for token creation
-		var userID uint64
-		db.QueryRow("SELECT id FROM users WHERE username = ?", req.Username).Scan(&userID)
+		// Mail the verification link in the background; it can be requested again later
+		go func() {
+			if err := accounts.sendVerification(context.Background(), user); err != nil {
+				log.Printf("failed to send verification email to user %d: %v", user.ID, err)
+			}
+		}()
+
+		// Where 2FA is required, new accounts enroll before they get a session
+		if mfa.requiredFor(user) {
+			c.JSON(http.StatusCreated, mfa.challenge(user))
+			return
+		}
 
-		// Issue JWT token for newly registered user
-		token, err := authpkg.CreateJWT(userID)
+		// Issue access and refresh tokens for newly registered user
+		tokens, err := sessions.issue(c.Request.Context(), user.ID, "")
 		if err != nil {
 			c.Error(err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "could not create token"})
 			return
 		}
 
-		// Return JWT token with 201 Created
-		c.JSON(http.StatusCreated, gin.H{"token": token})
+		// Return tokens with 201 Created
+		c.JSON(http.StatusCreated, tokens)
 	}
 }
 
-func loginHandler(db *sql.DB) gin.HandlerFunc {
+func loginHandler(users store.UserStore, sessions *sessionService, mfa *mfaService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req struct {
 			Identifier string `json:"identifier"` // Can be email or username
@@ -98,31 +102,34 @@ func loginHandler(db *sql.DB) gin.HandlerFunc {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
-		var id uint64
-		var hash string
-
-		// Query user by email or username to get ID and hashed password
-		query := "SELECT id, password_hash FROM users WHERE email = ? OR username = ?"
-		if err := db.QueryRow(query, req.Identifier, req.Identifier).Scan(&id, &hash); err != nil {
+		// Look user up by email or username to get ID and hashed password
+		user, err := users.GetUserByLogin(c.Request.Context(), req.Identifier)
+		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
 			return
 		}
 
 		// Check password correctness against stored hash
-		if err := authpkg.CheckPassword(hash, req.Password); err != nil {
+		if err := authpkg.CheckPassword(user.PasswordHash, req.Password); err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
 			return
 		}
 
-		// Create JWT token after successful auth
-		token, err := authpkg.CreateJWT(id)
+		// With 2FA the password only earns a challenge token for /api/login/mfa
+		if user.TwoFactorEnabled() || mfa.requiredFor(user) {
+			c.JSON(http.StatusOK, mfa.challenge(user))
+			return
+		}
+
+		// Start a new token family after successful auth
+		tokens, err := sessions.issue(c.Request.Context(), user.ID, "")
 		if err != nil {
 			c.Error(err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "could not create token"})
 			return
 		}
 
-		// Return JWT token on success
-		c.JSON(http.StatusOK, gin.H{"token": token})
+		// Return access and refresh tokens on success
+		c.JSON(http.StatusOK, tokens)
 	}
 }
@@ -2,26 +2,67 @@ package api
 
 import (
 	"context"
-	"database/sql"
+	"errors"
 	"log"
 	"net/http"
+	"strings"
 	"time"
 
-	"github.com/ConstantineCTF/URLSecure/backend/internal/middleware"  // Custom middleware (RateLimit, Auth)
+	"github.com/ConstantineCTF/URLSecure/backend/internal/analytics"  // Click event recording
+	"github.com/ConstantineCTF/URLSecure/backend/internal/middleware" // Custom middleware (RateLimit, Auth)
+	"github.com/ConstantineCTF/URLSecure/backend/internal/model"
+	"github.com/ConstantineCTF/URLSecure/backend/internal/ratelimit" // Rate limiter implementations
+	"github.com/ConstantineCTF/URLSecure/backend/internal/shortcode" // Short code strategies and allocation
+	"github.com/ConstantineCTF/URLSecure/backend/internal/store"     // Storage interfaces (MySQL, in-memory)
+	"github.com/ConstantineCTF/URLSecure/backend/internal/uniques"   // Unique visitor estimation
+	authpkg "github.com/ConstantineCTF/URLSecure/backend/pkg/auth"   // Access token signing keys
+	"github.com/ConstantineCTF/URLSecure/backend/pkg/blocklist"      // Threat-intel lists
+	"github.com/ConstantineCTF/URLSecure/backend/pkg/botdetect"      // User-Agent classification
 	"github.com/ConstantineCTF/URLSecure/backend/pkg/config"
+	"github.com/ConstantineCTF/URLSecure/backend/pkg/mailer"     // Outgoing email
+	"github.com/ConstantineCTF/URLSecure/backend/pkg/phishscore" // Phishing heuristics
+	"github.com/ConstantineCTF/URLSecure/backend/pkg/urlpolicy"  // Destination URL safety checks
 	"github.com/gin-gonic/gin"
-	"github.com/go-redis/redis/v8"
 )
 
-// NewRouter constructs the Gin engine and sets up routes and middleware
-func NewRouter(cfg *config.Config, db *sql.DB, rdb *redis.Client) *gin.Engine {
-	r := gin.Default()
+// Deps are the services the handlers depend on. They are created (and closed) by main.
+type Deps struct {
+	Links     store.LinkStore
+	Users     store.UserStore
+	Signer    *authpkg.KeyManager // Signs and verifies access tokens
+	Tokens    store.TokenStore    // Refresh tokens
+	APIKeys   store.APIKeyStore   // Personal API keys
+	MFA       store.MFAStore      // TOTP secrets and recovery codes
+	Mailer    mailer.Mailer       // Verification and password reset emails
+	Quotas    store.QuotaStore    // Plans and link creation counters
+	Analytics store.ClickStore    // Click events and their rollups
+	Uniques   uniques.Counter     // Unique visitors per link and day
+	Cache     store.Cache         // Redis or in-memory
+	Blocklist *blocklist.List     // Known-bad destinations, reloaded in the background
+	Limiter   ratelimit.Limiter   // Redis-backed when Redis is configured, in-memory otherwise
+	Clicks    *analytics.Recorder // Batches click events into the store
+}
+
+// NewRouter constructs the Gin engine and sets up routes and middleware.
+// Handlers only see the storage interfaces, so any LinkStore/UserStore backend and
+// any Cache (Redis or in-memory) can be plugged in.
+func NewRouter(cfg *config.Config, deps Deps) *gin.Engine {
+	links, users, cache := deps.Links, deps.Users, deps.Cache
+
+	// Client IPs are truncated to their network before they reach logs or analytics
+	anon := analytics.Anonymizer{IPv4Prefix: cfg.IPv4Prefix, IPv6Prefix: cfg.IPv6Prefix}
+
+	r := gin.New()
+	r.Use(middleware.Logger(anon.IP), gin.Recovery())
 
 	// Trust only localhost (loopback) for proxy IPs, enhancing security
 	if err := r.SetTrustedProxies([]string{"127.0.0.1", "::1"}); err != nil {
 		log.Fatalf("failed to set trusted proxies: %v", err)
 	}
 
+	// Server-rendered pages for the redirect path (410 Gone, etc.)
+	r.SetHTMLTemplate(loadTemplates())
+
 	// Serve static assets from ./public/assets
 	r.Static("/assets", "./public")
 
@@ -31,36 +72,163 @@ func NewRouter(cfg *config.Config, db *sql.DB, rdb *redis.Client) *gin.Engine {
 	// Health check endpoint (public)
 	r.GET("/api/health", healthHandler)
 
-	// Public authentication endpoints (register + login)
+	// Public keys verifying our access tokens, for other services
+	r.GET("/.well-known/jwks.json", jwksHandler(deps.Signer))
+
+	// Short code generation strategy (random, sequential or words) with collision retries
+	gen, err := shortcode.NewGenerator(shortcode.Options{Strategy: cfg.CodeStrategy, Salt: cfg.CodeSalt, Sequence: links})
+	if err != nil {
+		log.Fatalf("failed to set up code generator: %v", err)
+	}
+	codeLength := cfg.CodeLength
+	if codeLength == 0 {
+		codeLength = shortcode.DefaultLength(cfg.CodeStrategy)
+	}
+	codes := shortcode.NewAllocator(gen, codeLength, shortcode.MaxLength)
+
+	// Destination safety policy: scheme allowlist, no private networks, no loops back to us
+	policy := urlpolicy.New(urlpolicy.Config{
+		AllowedSchemes: cfg.AllowedSchemes,
+		SelfHosts:      cfg.SelfHosts,
+		AllowPrivate:   cfg.AllowPrivateTargets,
+		Blocklist:      deps.Blocklist,
+	})
+
+	// Phishing heuristics: suspicious destinations are held for review instead of going live
+	scorer := phishscore.New(phishscore.Config{
+		Threshold:      cfg.PhishThreshold,
+		Brands:         cfg.PhishBrands,
+		SuspiciousTLDs: cfg.PhishSuspiciousTLDs,
+	})
+
+	// Which links show the interstitial before redirecting, besides those that opt in
+	previews, err := parsePreviewPolicy(cfg.PreviewPolicy)
+	if err != nil {
+		log.Fatalf("invalid PREVIEW_POLICY: %v", err)
+	}
+
+	// Plan quotas and feature gates for link creation
+	quotas := &quotaService{users: users, quotas: deps.Quotas}
+
+	// Email verification and password resets by mailed, signed links
+	appURL := cfg.AppURL
+	if appURL == "" {
+		appURL = "http://localhost:" + cfg.HTTPPort
+	}
+	accounts := &accountService{
+		users:    users,
+		tokens:   deps.Tokens,
+		signer:   newAccountTokens(cfg.JWTSecret),
+		mail:     deps.Mailer,
+		appURL:   appURL,
+		required: cfg.RequireVerified,
+	}
+
+	// TOTP two-factor authentication between password and session
+	mfa := &mfaService{
+		users:    users,
+		mfa:      deps.MFA,
+		tokens:   accounts.signer,
+		limiter:  deps.Limiter,
+		issuer:   cfg.TOTPIssuer,
+		required: cfg.Require2FA,
+	}
+
+	// Short-lived access tokens plus rotating refresh tokens; logouts are denylisted in the cache
+	sessions := &sessionService{
+		signer:     deps.Signer,
+		tokens:     deps.Tokens,
+		users:      users,
+		mfa:        mfa,
+		revoked:    cache,
+		accessTTL:  time.Duration(cfg.AccessTokenSec) * time.Second,
+		refreshTTL: time.Duration(cfg.RefreshTokenDays) * 24 * time.Hour,
+	}
+
+	// Signed cookies remembering correct passwords of protected links
+	access := newLinkAccess(cfg.JWTSecret)
+
+	// Per-group request quotas; each group counts separately
+	authLimit := ratelimit.Limit{Requests: cfg.AuthRateLimitReqs, Window: time.Duration(cfg.AuthRateLimitWindow) * time.Second}
+	apiLimit := ratelimit.Limit{Requests: cfg.RateLimitRequests, Window: time.Duration(cfg.RateLimitWindowSec) * time.Second}
+	redirectLimit := ratelimit.Limit{Requests: cfg.RedirectLimitReqs, Window: time.Duration(cfg.RedirectLimitWindow) * time.Second}
+
+	// Crawlers, unfurlers and scripts are redirected like everyone else but counted apart
+	bots := botdetect.New(cfg.BotUserAgents)
+
+	// Public authentication endpoints (register + login), tightly limited against credential stuffing
 	public := r.Group("/api")
+	public.Use(middleware.RateLimitMiddleware(deps.Limiter, "auth", authLimit))
 	{
-		public.POST("/register", registerHandler(db))
-		public.POST("/login", loginHandler(db))
+		public.POST("/register", registerHandler(users, sessions, accounts, mfa))
+		public.POST("/login", loginHandler(users, sessions, mfa))
+		public.POST("/login/mfa", loginMFAHandler(mfa, sessions))                     // Second login step: challenge token and code
+		public.POST("/login/mfa/setup", loginMFASetupHandler(mfa))                    // Enroll during login when 2FA is required
+		public.POST("/token/refresh", refreshTokenHandler(sessions))                  // Rotate a refresh token
+		public.POST("/email/verify", verifyEmailHandler(accounts))                    // Confirm an address with a mailed token
+		public.POST("/password/reset/request", requestPasswordResetHandler(accounts)) // Mail a reset link
+		public.POST("/password/reset", resetPasswordHandler(accounts))                // Set a new password with a mailed token
 	}
 
-	// Protected endpoints - require rate limit and JWT auth middleware
+	// Protected endpoints - require rate limit and JWT or API key auth middleware.
+	// API keys only reach the routes their scopes allow.
 	protected := r.Group("/api")
 	protected.Use(
-		middleware.RateLimitMiddleware(),
-		middleware.AuthMiddleware(),
+		middleware.RateLimitMiddleware(deps.Limiter, "api", apiLimit),
+		middleware.AuthMiddleware(deps.Signer, cache, deps.APIKeys),
 	)
+	linksRead, linksWrite := middleware.RequireScope(model.ScopeLinksRead), middleware.RequireScope(model.ScopeLinksWrite)
 	{
-		protected.POST("/shorten", shortenHandler(db, rdb))        // Create short URL
-		protected.GET("/stats/:code", statsHandler(db))            // Get stats for code
-		protected.GET("/links", listLinksHandler(db))              // List all user links
+		protected.POST("/shorten", linksWrite, requireVerifiedEmail(accounts), shortenHandler(links, cache, codes, newAliasValidator(cfg.AliasBlocklist), policy, scorer, previews, quotas)) // Create short URL
+		protected.GET("/stats/:code", middleware.RequireScope(model.ScopeStatsRead), statsHandler(links, deps.Analytics, deps.Uniques))                                                      // Get stats for code
+		protected.GET("/links", linksRead, listLinksHandler(links))                                                                                                                          // List user links (paginated)
+		protected.GET("/links/:code", linksRead, getLinkHandler(links))                                                                                                                      // Get one owned link
+		protected.PATCH("/links/:code", linksWrite, updateLinkHandler(links, cache, policy, scorer, quotas))                                                                                 // Update target/expiry/limit
+		protected.DELETE("/links/:code", linksWrite, deleteLinkHandler(links, cache))                                                                                                        // Delete an owned link
+		protected.GET("/quota", linksRead, quotaHandler(quotas))                                                                                                                             // Plan, usage and remaining quota
 	}
 
-	// Redirect endpoint for short URLs (public)
-	r.GET("/r/:code", redirectHandler(db, rdb))
+	// Account management needs a logged-in user, never an API key
+	account := protected.Group("")
+	account.Use(middleware.SessionOnly())
+	{
+		account.GET("/settings", settingsHandler(quotas))                           // Account settings (analytics retention)
+		account.PATCH("/settings", updateSettingsHandler(quotas))                   // Change account settings
+		account.POST("/logout", logoutHandler(sessions))                            // Revoke the current tokens
+		account.GET("/keys", listAPIKeysHandler(deps.APIKeys))                      // List personal API keys
+		account.POST("/keys", createAPIKeyHandler(deps.APIKeys))                    // Create a key (shown once)
+		account.DELETE("/keys/:id", revokeAPIKeyHandler(deps.APIKeys))              // Revoke a key
+		account.POST("/email/verify/request", requestVerificationHandler(accounts)) // Mail a new verification link
+		account.GET("/2fa", twoFactorStatusHandler(mfa))                            // Whether 2FA is on and required
+		account.POST("/2fa/setup", twoFactorSetupHandler(mfa))                      // New TOTP secret and provisioning URI
+		account.POST("/2fa/enable", enableTwoFactorHandler(mfa, sessions))          // Confirm with a code; returns recovery codes and new tokens
+		account.POST("/2fa/disable", disableTwoFactorHandler(mfa))                  // Turn 2FA off with a code
+		account.POST("/2fa/recovery-codes", recoveryCodesHandler(mfa))              // Replace the recovery codes
+	}
+
+	// Redirect endpoint for short URLs (public), limited per IP against scraping and click inflation
+	redirects := r.Group("/r")
+	redirects.Use(middleware.RateLimitMiddleware(deps.Limiter, "redirect", redirectLimit))
+	{
+		redirects.GET("/:code", redirectHandler(links, users, cache, deps.Blocklist, previews, access, bots, deps.Clicks, anon))
+		redirects.POST("/:code", unlockLinkHandler(links, cache, access)) // Password form submissions
+	}
 
 	return r
 }
 
-// shortenHandler stores a new URL in DB and caches it in Redis asynchronously
-func shortenHandler(db *sql.DB, rdb *redis.Client) gin.HandlerFunc {
+// shortenHandler stores a new URL in DB and caches it asynchronously.
+// Links the phishing heuristics flag are stored as pending review and answered with 202.
+// The caller's plan limits how many links they may create and which features they may use.
+func shortenHandler(links store.LinkStore, cache store.Cache, codes *shortcode.Allocator, aliases *aliasValidator, policy *urlpolicy.Policy, scorer *phishscore.Scorer, previews previewPolicy, quotas *quotaService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req struct {
-			URL string `json:"url" binding:"required,url"` // URL must be valid
+			URL       string     `json:"url" binding:"required"`              // Checked against the URL policy below
+			ExpiresAt *time.Time `json:"expiresAt"`                           // Optional RFC 3339 expiry time
+			MaxClicks *int       `json:"maxClicks" binding:"omitempty,min=1"` // Optional click limit
+			Alias     string     `json:"alias"`                               // Optional custom short code
+			Preview   bool       `json:"preview"`                             // Always show the interstitial first
+			Password  string     `json:"password"`                            // Optional access password
 		}
 
 		// Validate JSON body
@@ -69,6 +237,24 @@ func shortenHandler(db *sql.DB, rdb *redis.Client) gin.HandlerFunc {
 			return
 		}
 
+		// Enforce the destination policy and store the normalized URL
+		target, err := policy.Check(c.Request.Context(), req.URL, c.Request.Host)
+		if err != nil {
+			respondPolicyRejection(c, err)
+			return
+		}
+		req.URL = target
+
+		// An expiry in the past would create a link that is dead on arrival
+		if req.ExpiresAt != nil {
+			if !req.ExpiresAt.After(time.Now()) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "expiresAt must be in the future"})
+				return
+			}
+			utc := req.ExpiresAt.UTC()
+			req.ExpiresAt = &utc
+		}
+
 		// Retrieve authenticated user ID from context
 		userIDVal, exists := c.Get("userID")
 		if !exists {
@@ -81,85 +267,229 @@ func shortenHandler(db *sql.DB, rdb *redis.Client) gin.HandlerFunc {
 			return
 		}
 
-		// Generate random 6-character short code for URL
-		code := generateCode(6)
-
-		// Insert link record into DB synchronously before responding
-		if _, err := db.Exec(
-			"INSERT INTO links (user_id, code, target) VALUES (?, ?, ?)",
-			userID, code, req.URL,
-		); err != nil {
+		// Enforce the plan before doing any work on the link
+		ctx := c.Request.Context()
+		now := time.Now()
+		plan, usage, err := quotas.load(ctx, userID, now)
+		if err != nil {
+			c.Error(err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
 			return
 		}
+		if !enforceQuota(c, plan, usage, now, req.Alias != "", req.Password != "") {
+			return
+		}
 
-		// Cache short URL target asynchronously; doesn't block response
-		go func() {
-			ctx := context.Background()
-			rdb.Set(ctx, "url:"+code, req.URL, 24*time.Hour)
-		}()
+		// Insert link record into DB synchronously before responding
+		link := &model.URL{UserID: userID, Target: req.URL, ExpiresAt: req.ExpiresAt, MaxClicks: req.MaxClicks, Preview: req.Preview}
+		assessRisk(link, scorer)
+		if req.Password != "" {
+			hash, err := hashLinkPassword(req.Password)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			link.PasswordHash = hash
+		}
+		if req.Alias != "" {
+			if err := aliases.Validate(req.Alias); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
 
-		// Return code of new shortened URL
-		c.JSON(http.StatusCreated, gin.H{"code": code})
-	}
-}
+		// Take the creation out of the allowance before creating the link: usage was read
+		// above, and concurrent requests may have used the rest of it since
+		if err := quotas.quotas.ReserveLinkCreation(ctx, userID, now, plan.DailyLinks, plan.MonthlyLinks); err != nil {
+			switch {
+			case errors.Is(err, store.ErrDailyLimit):
+				usage.Daily = *plan.DailyLinks
+			case errors.Is(err, store.ErrMonthlyLimit):
+				usage.Monthly = *plan.MonthlyLinks
+			default:
+				c.Error(err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+				return
+			}
+			enforceQuota(c, plan, usage, now, false, false)
+			return
+		}
+		// Give the reservation back if no link comes of it; even if the client has gone
+		release := func() {
+			if err := quotas.quotas.ReleaseLinkCreation(context.WithoutCancel(ctx), userID, now); err != nil {
+				c.Error(err)
+			}
+		}
 
-// statsHandler returns statistics (click count, creation date) for a short code
-func statsHandler(db *sql.DB) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		code := c.Param("code")
+		if req.Alias != "" {
+			// Use the requested alias as-is; a taken alias is the caller's problem, not retried
+			link.Code = req.Alias
+			if err := links.CreateLink(ctx, link); err != nil {
+				release()
+				if errors.Is(err, store.ErrCodeTaken) {
+					c.JSON(http.StatusConflict, gin.H{"error": "alias already taken"})
+					return
+				}
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+				return
+			}
+		} else {
+			// Generate codes until one is free; collisions are retried, not surfaced as errors
+			if _, err := codes.Allocate(ctx, func(code string) error {
+				link.Code = code
+				return links.CreateLink(ctx, link)
+			}, isCodeTaken); err != nil {
+				release()
+				c.Error(err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "could not allocate short code"})
+				return
+			}
+		}
+		code := link.Code
 
-		var clicks int
-		var created time.Time
+		usage.Daily++
+		usage.Monthly++
+		usage.Active++
 
-		// Query DB for click count and creation date of the short URL
-		if err := db.QueryRow(
-			"SELECT click_count, created_at FROM links WHERE code = ?", code,
-		).Scan(&clicks, &created); err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
-			return
+		// Cache short URL target asynchronously; doesn't block response
+		if ttl := linkCacheTTL(link, previews, time.Now()); ttl > 0 {
+			go func() {
+				ctx := context.Background()
+				cache.Set(ctx, linkCacheKey(code), req.URL, ttl)
+			}()
 		}
 
-		// Return stats as JSON
-		c.JSON(http.StatusOK, gin.H{"code": code, "clicks": clicks, "createdAt": created})
+		// Return code of new shortened URL along with its review state
+		status := http.StatusCreated
+		if link.Pending() {
+			status = http.StatusAccepted
+		}
+		c.JSON(status, gin.H{
+			"code":        code,
+			"status":      link.Status,
+			"riskScore":   link.RiskScore,
+			"riskReasons": link.RiskReasons,
+			"quota":       quotaJSON(plan, usage, now),
+		})
 	}
 }
 
-// redirectHandler resolves short URL from cache or DB, increments click, redirects user
-func redirectHandler(db *sql.DB, rdb *redis.Client) gin.HandlerFunc {
+// redirectHandler resolves short URL from cache or DB, increments click, redirects user.
+// Expired links and links that used up their clicks get 410 Gone instead, and links
+// whose target has since been blocklisted get a warning page rather than a redirect;
+// for click-limited links that page has no direct link, which would skip the count.
+// Links that need a preview (per link, by policy, or via the "+" suffix) show the
+// interstitial first; its continue button comes back with ?continue=1. Password-protected
+// links ask for the password before anything about them is shown. Visits from bots
+// are redirected too but counted in BotClicks. Bots never reach the target of a
+// click-limited link: the User-Agent is the client's word, so letting them through
+// without a click would make the limit optional. Every redirect is also queued as a
+// click event for analytics.
+func redirectHandler(links store.LinkStore, users store.UserStore, cache store.Cache, threats *blocklist.List, previews previewPolicy, access *linkAccess, bots *botdetect.Detector, clicks *analytics.Recorder, anon analytics.Anonymizer) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		code := c.Param("code")
 		ctx := context.Background()
+		visitor := bots.Classify(c.Request.UserAgent())
+
+		// "/r/abc123+" only previews the link; nothing is counted
+		previewOnly := strings.HasSuffix(code, previewSuffix)
+		code = strings.TrimSuffix(code, previewSuffix)
+		confirmed := c.Query("continue") == "1"
 
 		log.Printf("Redirect handler for code: %s", code)
 
-		// Try Redis cache first
-		target, err := rdb.Get(ctx, "url:"+code).Result()
-		if err == redis.Nil {
+		// Try cache first; links that need a preview are never cached
+		target, err := "", store.ErrCacheMiss
+		if !previewOnly {
+			target, err = cache.Get(ctx, linkCacheKey(code))
+		}
+		if errors.Is(err, store.ErrCacheMiss) {
 			log.Println("Cache miss—query DB")
 
 			// Cache miss, query DB for target URL
-			if err := db.QueryRow(
-				"SELECT target FROM links WHERE code = ?", code,
-			).Scan(&target); err != nil {
-				log.Printf("DB lookup failed for code %s: %v", code, err)
+			link, err := links.GetLinkByCode(ctx, code)
+			if err != nil {
+				if !errors.Is(err, store.ErrNotFound) {
+					log.Printf("DB lookup failed for code %s: %v", code, err)
+				}
 				c.String(http.StatusNotFound, "Not found")
 				return
 			}
+			now := time.Now()
+			if link.Expired(now) {
+				renderGone(c, "This link has expired.")
+				return
+			}
+			if link.Pending() {
+				renderUnavailable(c, http.StatusForbidden, "This link is awaiting a safety review.")
+				return
+			}
+			if link.Protected() && !access.allowed(c, link) {
+				renderPasswordForm(c, http.StatusUnauthorized, c.Param("code"), "")
+				return
+			}
+			if previewOnly || (!confirmed && previews.required(link)) {
+				renderLinkPreview(c, users, threats, link)
+				return
+			}
+
+			// Click-limited links are never cached; claim the click synchronously
+			// so concurrent visitors cannot push it past its limit
+			if link.MaxClicks != nil {
+				if link.Exhausted() {
+					renderGone(c, "This link has reached its click limit.")
+					return
+				}
+				// Unfurlers must not use up a one-time link, so bots get neither a
+				// click nor the target
+				if visitor.Bot {
+					renderUnavailable(c, http.StatusForbidden, "This link can only be opened a limited number of times. Open it in a web browser.")
+					return
+				}
+				// A direct link on the warning would open the target without using a click
+				if renderIfBlocklisted(c, threats, link.Target, "") {
+					return
+				}
+				counted, err := links.IncrementClicks(ctx, code)
+				if errors.Is(err, store.ErrNotFound) {
+					c.String(http.StatusNotFound, "Not found") // Deleted since it was read
+					return
+				}
+				if err != nil {
+					log.Printf("Click update failed for code %s: %v", code, err)
+					c.String(http.StatusInternalServerError, "Internal error")
+					return
+				}
+				if !counted {
+					renderGone(c, "This link has reached its click limit.")
+					return
+				}
+				clicks.Record(newVisit(c, code, visitor, anon))
+				c.Redirect(http.StatusFound, link.Target)
+				return
+			}
+			target = link.Target
 
-			// Cache result asynchronously
-			rdb.Set(ctx, "url:"+code, target, 24*time.Hour)
+			// Cache result until the link expires (at most 24h). A cache hit skips every
+			// check above, so links that need one must stay uncached; a zero TTL would
+			// even mean "never expires".
+			if ttl := linkCacheTTL(link, previews, now); ttl > 0 {
+				cache.Set(ctx, linkCacheKey(code), target, ttl)
+			}
 		} else if err != nil {
-			// Redis failure
-			log.Printf("Redis error for code %s: %v", code, err)
+			// Cache (Redis) failure
+			log.Printf("Cache error for code %s: %v", code, err)
 			c.String(http.StatusInternalServerError, "Internal error")
 			return
 		}
 
-		// Increment the click count asynchronously in DB, no need to await
-		go db.Exec(
-			"UPDATE links SET clicks = clicks + 1 WHERE code = ?", code,
-		)
+		// Lists are reloaded at runtime, so check on every visit, cache hit or not
+		if renderIfBlocklisted(c, threats, target, target) {
+			return
+		}
+
+		// Count the visit asynchronously in DB, no need to await
+		recordVisit(c, links, clicks, code, visitor, anon)
 
 		log.Printf("Redirecting code %s to target: %s", code, target)
 		// Redirect client to target URL
@@ -167,6 +497,111 @@ func redirectHandler(db *sql.DB, rdb *redis.Client) gin.HandlerFunc {
 	}
 }
 
+// respondPolicyRejection writes a 422 listing the policy reasons if err is a
+// *urlpolicy.Rejection, and reports whether it did so.
+func respondPolicyRejection(c *gin.Context, err error) bool {
+	var rejection *urlpolicy.Rejection
+	if !errors.As(err, &rejection) {
+		return false
+	}
+	c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "destination URL rejected", "reasons": rejection.Reasons})
+	return true
+}
+
+// renderIfBlocklisted shows the warning page instead of redirecting when target is on
+// the blocklist. The visit is not counted as a click. The page's continue button leads
+// to continueURL, or is left out when it is empty.
+func renderIfBlocklisted(c *gin.Context, threats *blocklist.List, target, continueURL string) bool {
+	if threats == nil {
+		return false
+	}
+	match, ok := threats.Match(target)
+	if !ok {
+		return false
+	}
+	log.Printf("Blocked redirect for code %s: %s matched %s", c.Param("code"), target, match.Source)
+	renderPreview(c, previewPage{
+		Title:       "Warning",
+		Target:      target,
+		Warnings:    []string{blocklistWarning(match)},
+		ContinueURL: continueURL,
+	})
+	return true
+}
+
+// recordVisit counts a redirect in the background: people as clicks, bots as bot clicks.
+// Either way the visit is queued as a click event.
+func recordVisit(c *gin.Context, links store.LinkStore, clicks *analytics.Recorder, code string, visitor botdetect.Result, anon analytics.Anonymizer) {
+	clicks.Record(newVisit(c, code, visitor, anon))
+	if !visitor.Bot {
+		go links.IncrementClicks(context.Background(), code)
+		return
+	}
+	go links.IncrementBotClicks(context.Background(), code)
+}
+
+// newVisit captures the request data the click event is built from. The IP is truncated
+// by anon except for the unique visitor fingerprint. Visitors who send DNT or Sec-GPC
+// are only counted.
+func newVisit(c *gin.Context, code string, visitor botdetect.Result, anon analytics.Anonymizer) analytics.Visit {
+	if c.GetHeader("DNT") == "1" || c.GetHeader("Sec-GPC") == "1" {
+		return analytics.Visit{Code: code, At: time.Now(), Bot: visitor.Bot, Private: true}
+	}
+	return analytics.Visit{
+		Code:           code,
+		At:             time.Now(),
+		IP:             anon.IP(c.ClientIP()),
+		VisitorIP:      c.ClientIP(),
+		UserAgent:      c.Request.UserAgent(),
+		Referrer:       c.Request.Referer(),
+		AcceptLanguage: c.GetHeader("Accept-Language"),
+		Bot:            visitor.Bot,
+	}
+}
+
+// linkCacheKey is the cache key holding the target URL for a short code.
+func linkCacheKey(code string) string {
+	return "url:" + code
+}
+
+// assessRisk scores the link's target with the phishing heuristics, records the score
+// and reasons on the link, and holds it for review when the score crosses the threshold.
+func assessRisk(link *model.URL, scorer *phishscore.Scorer) {
+	result := scorer.Score(link.Target)
+
+	link.RiskScore = result.Score
+	link.RiskReasons = nil
+	for _, signal := range result.Signals {
+		link.RiskReasons = append(link.RiskReasons, model.RiskReason{Code: signal.Code, Message: signal.Message})
+	}
+	link.Status = model.LinkActive
+	if result.Flagged {
+		link.Status = model.LinkPendingReview
+	}
+}
+
+// isCodeTaken reports whether a CreateLink error means the short code already exists.
+func isCodeTaken(err error) bool {
+	return errors.Is(err, store.ErrCodeTaken)
+}
+
+// linkCacheTTL returns how long a link's target may be cached: 24h, shortened so the
+// entry never outlives the link's expiry. Click-limited and pending links must always
+// be checked against the DB, as must password-protected ones and those that show the
+// interstitial under previews, so they get 0 (do not cache).
+func linkCacheTTL(link *model.URL, previews previewPolicy, now time.Time) time.Duration {
+	if link.MaxClicks != nil || link.Pending() || link.Protected() || previews.required(link) {
+		return 0
+	}
+	ttl := 24 * time.Hour
+	if link.ExpiresAt != nil {
+		if untilExpiry := link.ExpiresAt.Sub(now); untilExpiry < ttl {
+			ttl = untilExpiry
+		}
+	}
+	return ttl
+}
+
 // healthHandler returns basic health check JSON
 func healthHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"status": "ok"})
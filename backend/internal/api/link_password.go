@@ -0,0 +1,195 @@
+package api
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ConstantineCTF/URLSecure/backend/internal/model"
+	"github.com/ConstantineCTF/URLSecure/backend/internal/store"
+	authpkg "github.com/ConstantineCTF/URLSecure/backend/pkg/auth"
+	"github.com/gin-gonic/gin"
+)
+
+// Password-protected link settings
+const (
+	minLinkPasswordLen    = 4
+	maxLinkPasswordLen    = 72               // bcrypt ignores anything longer
+	linkAccessTTL         = time.Hour        // How long a correct password is remembered
+	passwordMaxFailures   = 5                // Attempts allowed per link and client IP per window
+	passwordLinkFailures  = 100              // Wrong passwords allowed per link per window, from all clients
+	passwordFailureWindow = 15 * time.Minute // Counted from the first attempt; a correct password clears the client's count
+)
+
+// hashLinkPassword validates and hashes a link access password.
+func hashLinkPassword(password string) (string, error) {
+	if len(password) < minLinkPasswordLen || len(password) > maxLinkPasswordLen {
+		return "", fmt.Errorf("password must be %d to %d characters", minLinkPasswordLen, maxLinkPasswordLen)
+	}
+	return authpkg.HashPassword(password)
+}
+
+// linkAccess issues and checks the signed cookies that remember a correct link password.
+// The signature covers the password hash, so changing the password revokes old cookies.
+type linkAccess struct {
+	key []byte
+}
+
+// newLinkAccess derives the cookie signing key from the application secret.
+func newLinkAccess(secret string) *linkAccess {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("urlsecure link access"))
+	return &linkAccess{key: mac.Sum(nil)}
+}
+
+// linkAccessCookie is the cookie name for a code; codes only use cookie-safe characters.
+func linkAccessCookie(code string) string {
+	return "link_" + code
+}
+
+// sign returns the MAC for link's access cookie expiring at expires (Unix seconds).
+func (a *linkAccess) sign(link *model.URL, expires int64) string {
+	mac := hmac.New(sha256.New, a.key)
+	fmt.Fprintf(mac, "%s|%d|%s", link.Code, expires, link.PasswordHash)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// grant sets the access cookie for link.
+func (a *linkAccess) grant(c *gin.Context, link *model.URL) {
+	expires := time.Now().Add(linkAccessTTL)
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     linkAccessCookie(link.Code),
+		Value:    strconv.FormatInt(expires.Unix(), 10) + "." + a.sign(link, expires.Unix()),
+		Path:     "/r/", // Also covers the /r/<code>+ preview
+		Expires:  expires,
+		MaxAge:   int(linkAccessTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https",
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// allowed reports whether the request carries a valid, unexpired access cookie for link.
+func (a *linkAccess) allowed(c *gin.Context, link *model.URL) bool {
+	value, err := c.Cookie(linkAccessCookie(link.Code))
+	if err != nil {
+		return false
+	}
+	exp, mac, ok := strings.Cut(value, ".")
+	if !ok {
+		return false
+	}
+	expires, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil || time.Now().Unix() >= expires {
+		return false
+	}
+	return hmac.Equal([]byte(mac), []byte(a.sign(link, expires)))
+}
+
+// client returns a keyed hash of a client IP, so the attempt counters in the cache do
+// not hold visitors' addresses.
+func (a *linkAccess) client(ip string) string {
+	mac := hmac.New(sha256.New, a.key)
+	fmt.Fprintf(mac, "client|%s", ip)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:12])
+}
+
+// passwordFailKey is the cache key counting one client's password attempts for a code.
+func passwordFailKey(code, client string) string {
+	return "pwfail:" + code + ":" + client
+}
+
+// passwordLinkFailKey is the cache key counting wrong passwords for a code from all clients.
+func passwordLinkFailKey(code string) string {
+	return "pwfail:" + code
+}
+
+// linkFailures returns the wrong passwords counted for code in the current window.
+func linkFailures(ctx context.Context, cache store.Cache, code string) (int64, error) {
+	value, err := cache.Get(ctx, passwordLinkFailKey(code))
+	if errors.Is(err, store.ErrCacheMiss) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(value, 10, 64)
+}
+
+// unlockLinkHandler checks a submitted link password. On success it sets the access
+// cookie and sends the visitor back to GET /r/:code. Attempts are throttled per link and
+// client IP, so one guesser cannot lock everyone else out; a much higher count of wrong
+// passwords per link stops guessers spread over many addresses.
+func unlockLinkHandler(links store.LinkStore, cache store.Cache, access *linkAccess) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		param := c.Param("code")
+		code := strings.TrimSuffix(param, previewSuffix)
+		ctx := c.Request.Context()
+
+		link, err := links.GetLinkByCode(ctx, code)
+		if err != nil {
+			if !errors.Is(err, store.ErrNotFound) {
+				log.Printf("DB lookup failed for code %s: %v", code, err)
+			}
+			c.String(http.StatusNotFound, "Not found")
+			return
+		}
+		if !link.Protected() {
+			c.Redirect(http.StatusSeeOther, "/r/"+param)
+			return
+		}
+
+		// Count the attempt before running bcrypt, so parallel guesses cannot all pass
+		// the check on the same count. The counts live in the shared cache, so every
+		// replica enforces the same limits; without it the form stays closed.
+		unavailable := func(err error) {
+			c.Error(err)
+			renderPasswordForm(c, http.StatusServiceUnavailable, param, "Cannot check passwords right now. Try again later.")
+		}
+		throttled := func() {
+			c.Header("Retry-After", strconv.Itoa(int(passwordFailureWindow.Seconds())))
+			renderPasswordForm(c, http.StatusTooManyRequests, param, "Too many wrong passwords. Try again later.")
+		}
+		clientKey := passwordFailKey(code, access.client(c.ClientIP()))
+		attempts, err := cache.Incr(ctx, clientKey, passwordFailureWindow)
+		if err != nil {
+			unavailable(err)
+			return
+		}
+		if attempts > passwordMaxFailures {
+			throttled()
+			return
+		}
+		failures, err := linkFailures(ctx, cache, code)
+		if err != nil {
+			unavailable(err)
+			return
+		}
+		if failures >= passwordLinkFailures {
+			throttled()
+			return
+		}
+
+		if err := authpkg.CheckPassword(link.PasswordHash, c.PostForm("password")); err != nil {
+			if _, err := cache.Incr(ctx, passwordLinkFailKey(code), passwordFailureWindow); err != nil {
+				c.Error(err)
+			}
+			renderPasswordForm(c, http.StatusUnauthorized, param, "Wrong password.")
+			return
+		}
+
+		if err := cache.Delete(ctx, clientKey); err != nil {
+			c.Error(err)
+		}
+		access.grant(c, link)
+		c.Redirect(http.StatusSeeOther, "/r/"+param)
+	}
+}
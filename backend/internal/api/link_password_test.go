@@ -0,0 +1,102 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ConstantineCTF/URLSecure/backend/internal/model"
+	"github.com/ConstantineCTF/URLSecure/backend/internal/store"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// brokenCache fails every call, like a Redis that is down.
+type brokenCache struct{}
+
+var errCacheDown = errors.New("cache down")
+
+func (brokenCache) Get(context.Context, string) (string, error)              { return "", errCacheDown }
+func (brokenCache) Set(context.Context, string, string, time.Duration) error { return errCacheDown }
+func (brokenCache) Delete(context.Context, ...string) error                  { return errCacheDown }
+func (brokenCache) Incr(context.Context, string, time.Duration) (int64, error) {
+	return 0, errCacheDown
+}
+
+// createProtectedLink stores a link with password at code. The hash uses the lowest
+// bcrypt cost, as the tests check many passwords.
+func createProtectedLink(t *testing.T, st *store.MemoryStore, code, password string) {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	createLink(t, st, &model.URL{Code: code, Target: "https://example.com/", PasswordHash: string(hash)})
+}
+
+func TestUnlockThrottleHoldsUnderConcurrency(t *testing.T) {
+	st := store.NewMemoryStore()
+	createProtectedLink(t, st, "locked", "hunter2")
+	r := newRedirectRouter(st, store.NewMemoryCache(), previewPerLink, nil)
+
+	const guesses = 20
+	codes := make(chan int, guesses)
+	var wg sync.WaitGroup
+	for range guesses {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes <- unlock(r, "locked", "wrong").Code
+		}()
+	}
+	wg.Wait()
+	close(codes)
+
+	counts := make(map[int]int)
+	for code := range codes {
+		counts[code]++
+	}
+	if counts[http.StatusUnauthorized] != passwordMaxFailures || counts[http.StatusTooManyRequests] != guesses-passwordMaxFailures {
+		t.Fatalf("responses %v, want %d x 401 and the rest 429", counts, passwordMaxFailures)
+	}
+
+	// The right password is refused too until the window passes
+	if w := unlock(r, "locked", "hunter2"); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("correct password while throttled: status %d, want %d", w.Code, http.StatusTooManyRequests)
+	}
+
+	// ...but only for the guesser; other visitors can still get in
+	if w := unlockFrom(r, "locked", "hunter2", "198.51.100.7"); w.Code != http.StatusSeeOther {
+		t.Fatalf("other client: status %d, want %d", w.Code, http.StatusSeeOther)
+	}
+}
+
+func TestUnlockThrottlesGuessesFromManyClients(t *testing.T) {
+	st := store.NewMemoryStore()
+	createProtectedLink(t, st, "locked", "hunter2")
+	r := newRedirectRouter(st, store.NewMemoryCache(), previewPerLink, nil)
+
+	// Spread over enough addresses, wrong passwords add up to the per-link ceiling
+	for i := range passwordLinkFailures {
+		ip := fmt.Sprintf("10.0.%d.%d", i/passwordMaxFailures, 1)
+		if w := unlockFrom(r, "locked", "wrong", ip); w.Code != http.StatusUnauthorized {
+			t.Fatalf("guess %d: status %d, want %d", i+1, w.Code, http.StatusUnauthorized)
+		}
+	}
+	if w := unlockFrom(r, "locked", "hunter2", "198.51.100.7"); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("after %d wrong passwords: status %d, want %d", passwordLinkFailures, w.Code, http.StatusTooManyRequests)
+	}
+}
+
+func TestUnlockFailsClosedWithoutCache(t *testing.T) {
+	st := store.NewMemoryStore()
+	createProtectedLink(t, st, "locked", "hunter2")
+	r := newRedirectRouter(st, brokenCache{}, previewPerLink, nil)
+
+	if w := unlock(r, "locked", "hunter2"); w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status %d, want %d", w.Code, http.StatusServiceUnavailable)
+	}
+}
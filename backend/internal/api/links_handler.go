@@ -1,20 +1,346 @@
 package api
 
 import (
-	"database/sql"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/ConstantineCTF/URLSecure/backend/internal/model"
+	"github.com/ConstantineCTF/URLSecure/backend/internal/store"
+	"github.com/ConstantineCTF/URLSecure/backend/pkg/phishscore"
+	"github.com/ConstantineCTF/URLSecure/backend/pkg/urlpolicy"
 	"github.com/gin-gonic/gin"
 )
 
-func listLinksHandler(db *sql.DB) gin.HandlerFunc {
+// Page size bounds for GET /api/links
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// linkJSON is the API representation of a link.
+func linkJSON(link *model.URL) gin.H {
+	return gin.H{
+		"code":              link.Code,
+		"target":            link.Target,
+		"clicks":            link.Clicks,
+		"botClicks":         link.BotClicks,
+		"createdAt":         link.CreatedAt,
+		"expiresAt":         link.ExpiresAt,
+		"maxClicks":         link.MaxClicks,
+		"status":            link.Status,
+		"riskScore":         link.RiskScore,
+		"riskReasons":       link.RiskReasons,
+		"preview":           link.Preview,
+		"passwordProtected": link.Protected(),
+	}
+}
+
+// listLinksHandler returns a page of the caller's links.
+// Query parameters: limit, cursor, sort (created|clicks), order (asc|desc) and q (search in target).
+func listLinksHandler(links store.LinkStore) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Get user ID from context (set by auth middleware)
-		userID := c.GetUint64("userId")
+		userID := c.GetUint64("userID")
+
+		q := store.LinkQuery{
+			UserID: userID,
+			Search: strings.TrimSpace(c.Query("q")),
+			SortBy: c.DefaultQuery("sort", store.SortByCreated),
+			Limit:  defaultPageSize,
+		}
+		if q.SortBy != store.SortByCreated && q.SortBy != store.SortByClicks {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "sort must be created or clicks"})
+			return
+		}
+		switch c.DefaultQuery("order", "desc") {
+		case "desc":
+		case "asc":
+			q.Ascending = true
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "order must be asc or desc"})
+			return
+		}
+		if raw := c.Query("limit"); raw != "" {
+			limit, err := strconv.Atoi(raw)
+			if err != nil || limit < 1 || limit > maxPageSize {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("limit must be between 1 and %d", maxPageSize)})
+				return
+			}
+			q.Limit = limit
+		}
+		if raw := c.Query("cursor"); raw != "" {
+			cursor, err := decodeCursor(raw)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor"})
+				return
+			}
+			q.After = cursor
+		}
+
+		// Fetch one extra row to learn whether another page exists
+		want := q.Limit
+		q.Limit++
+		page, err := links.ListLinks(c.Request.Context(), q)
+		if err != nil {
+			c.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+
+		var nextCursor *string
+		if len(page) > want {
+			page = page[:want]
+			last := page[len(page)-1]
+			next := encodeCursor(store.LinkCursor{Clicks: last.Clicks, ID: last.ID})
+			nextCursor = &next
+		}
+
+		// Build and return JSON array of links
+		out := make([]gin.H, 0, len(page))
+		for _, link := range page {
+			out = append(out, linkJSON(link))
+		}
+		c.JSON(http.StatusOK, gin.H{"links": out, "nextCursor": nextCursor})
+	}
+}
+
+// getLinkHandler returns a single link owned by the caller.
+func getLinkHandler(links store.LinkStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		link, ok := ownedLink(c, links)
+		if !ok {
+			return
+		}
+		c.JSON(http.StatusOK, linkJSON(link))
+	}
+}
+
+// updateLinkHandler changes the target, expiry, click limit, preview flag or password of a link.
+// Fields missing from the body are left alone; expiresAt, maxClicks and password may be set to null to clear them.
+// A new target is re-scored and may put the link (back) into review. A change that brings
+// an expired or used-up link back to life needs room in the plan's active-link allowance.
+func updateLinkHandler(links store.LinkStore, cache store.Cache, policy *urlpolicy.Policy, scorer *phishscore.Scorer, quotas *quotaService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		link, ok := ownedLink(c, links)
+		if !ok {
+			return
+		}
+		oldTarget := link.Target
+		now := time.Now()
+		wasLive := !link.Expired(now) && !link.Exhausted()
+
+		// Decode into raw fields first so "absent" and "null" can be told apart
+		var fields map[string]json.RawMessage
+		if err := c.ShouldBindJSON(&fields); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Setting a password is a plan feature, as it is at creation
+		if raw, ok := fields["password"]; ok && !bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+			plan, err := quotas.plan(c.Request.Context(), link.UserID)
+			if err != nil {
+				c.Error(err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+				return
+			}
+			if !plan.PasswordLinks {
+				c.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("password-protected links are not available on the %s plan", plan.Name)})
+				return
+			}
+		}
+
+		checkTarget := func(target string) (string, error) {
+			return policy.Check(c.Request.Context(), target, c.Request.Host)
+		}
+		if err := applyLinkPatch(link, fields, now, checkTarget); err != nil {
+			if respondPolicyRejection(c, err) {
+				return
+			}
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 
-		// Query all links for this user
-		rows, _ := db.Query("SELECT code,target,clicks,created_at FROM links WHERE user_id=?", userID)
-		defer rows.Close()
+		// The link is not among the active ones yet, so it needs a free slot
+		if !wasLive && !link.Expired(now) && !link.Exhausted() {
+			plan, usage, err := quotas.load(c.Request.Context(), link.UserID, now)
+			if err != nil {
+				c.Error(err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+				return
+			}
+			if reached(plan.MaxActiveLinks, usage.Active) {
+				c.JSON(http.StatusForbidden, gin.H{
+					"error": "active link limit reached; delete or expire links to reactivate this one",
+					"quota": quotaJSON(plan, usage, now),
+				})
+				return
+			}
+		}
+		if link.Target != oldTarget {
+			assessRisk(link, scorer)
+		}
 
-		// Build and return JSON array of links…
+		if err := links.UpdateLink(c.Request.Context(), link); err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+				return
+			}
+			c.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+
+		// Drop the cached target so redirects pick up the change immediately
+		if err := cache.Delete(c.Request.Context(), linkCacheKey(link.Code)); err != nil {
+			c.Error(err)
+		}
+
+		c.JSON(http.StatusOK, linkJSON(link))
+	}
+}
+
+// deleteLinkHandler removes a link owned by the caller.
+func deleteLinkHandler(links store.LinkStore, cache store.Cache) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.GetUint64("userID")
+		code := c.Param("code")
+
+		if err := links.DeleteLink(c.Request.Context(), userID, code); err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+				return
+			}
+			c.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+
+		// Stop serving the cached target for the deleted code
+		if err := cache.Delete(c.Request.Context(), linkCacheKey(code)); err != nil {
+			c.Error(err)
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// ownedLink loads the :code link and checks it belongs to the caller. Links owned by
+// someone else get the same 404 as missing ones so codes cannot be probed.
+func ownedLink(c *gin.Context, links store.LinkStore) (*model.URL, bool) {
+	link, err := links.GetLinkByCode(c.Request.Context(), c.Param("code"))
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return nil, false
+	}
+	if err != nil || link.UserID != c.GetUint64("userID") {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return nil, false
+	}
+	return link, true
+}
+
+// applyLinkPatch copies the fields present in a PATCH body onto link.
+// checkTarget validates a new target against the URL policy and returns its normalized form.
+func applyLinkPatch(link *model.URL, fields map[string]json.RawMessage, now time.Time, checkTarget func(string) (string, error)) error {
+	for name, raw := range fields {
+		isNull := bytes.Equal(bytes.TrimSpace(raw), []byte("null"))
+
+		switch name {
+		case "target":
+			var target string
+			if err := json.Unmarshal(raw, &target); err != nil || isNull {
+				return errors.New("target must be a string")
+			}
+			normalized, err := checkTarget(target)
+			if err != nil {
+				return err
+			}
+			link.Target = normalized
+
+		case "expiresAt":
+			if isNull {
+				link.ExpiresAt = nil
+				continue
+			}
+			var expiresAt time.Time
+			if err := json.Unmarshal(raw, &expiresAt); err != nil {
+				return errors.New("expiresAt must be an RFC 3339 time or null")
+			}
+			if !expiresAt.After(now) {
+				return errors.New("expiresAt must be in the future")
+			}
+			expiresAt = expiresAt.UTC()
+			link.ExpiresAt = &expiresAt
+
+		case "maxClicks":
+			if isNull {
+				link.MaxClicks = nil
+				continue
+			}
+			var maxClicks int
+			if err := json.Unmarshal(raw, &maxClicks); err != nil || maxClicks < 1 {
+				return errors.New("maxClicks must be a positive integer or null")
+			}
+			link.MaxClicks = &maxClicks
+
+		case "password":
+			if isNull {
+				link.PasswordHash = ""
+				continue
+			}
+			var password string
+			if err := json.Unmarshal(raw, &password); err != nil {
+				return errors.New("password must be a string or null")
+			}
+			hash, err := hashLinkPassword(password)
+			if err != nil {
+				return err
+			}
+			link.PasswordHash = hash
+
+		case "preview":
+			if err := json.Unmarshal(raw, &link.Preview); err != nil || isNull {
+				return errors.New("preview must be a boolean")
+			}
+
+		default:
+			return fmt.Errorf("field %q cannot be updated", name)
+		}
+	}
+	return nil
+}
+
+// encodeCursor serialises a page position as an opaque URL-safe token.
+func encodeCursor(cursor store.LinkCursor) string {
+	return base64.RawURLEncoding.EncodeToString(fmt.Appendf(nil, "%d:%d", cursor.Clicks, cursor.ID))
+}
+
+// decodeCursor parses a token produced by encodeCursor.
+func decodeCursor(token string) (*store.LinkCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, err
+	}
+	clicks, id, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return nil, errors.New("malformed cursor")
+	}
+
+	cursor := &store.LinkCursor{}
+	if cursor.Clicks, err = strconv.Atoi(clicks); err != nil {
+		return nil, err
+	}
+	if cursor.ID, err = strconv.ParseUint(id, 10, 64); err != nil {
+		return nil, err
 	}
+	return cursor, nil
 }
@@ -0,0 +1,156 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ConstantineCTF/URLSecure/backend/internal/model"
+	"github.com/ConstantineCTF/URLSecure/backend/internal/store"
+	"github.com/ConstantineCTF/URLSecure/backend/pkg/phishscore"
+	"github.com/ConstantineCTF/URLSecure/backend/pkg/urlpolicy"
+	"github.com/gin-gonic/gin"
+)
+
+// newLinksRouter serves PATCH /api/links/:code for user 1 of st, who is on plan.
+func newLinksRouter(st *store.MemoryStore, plan *model.Plan) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	quotas := &quotaService{users: st, quotas: fixedPlan{QuotaStore: st, plan: plan}}
+	r.PATCH("/api/links/:code", func(c *gin.Context) { c.Set("userID", uint64(1)) }, updateLinkHandler(st, store.NewMemoryCache(),
+		urlpolicy.New(urlpolicy.Config{}), phishscore.New(phishscore.Config{}), quotas))
+	return r
+}
+
+// patchLink sends body as a PATCH of code and returns the response.
+func patchLink(r http.Handler, code, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPatch, "/api/links/"+code, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestUpdateLinkReactivationNeedsActiveSlot(t *testing.T) {
+	ctx := context.Background()
+	st := store.NewMemoryStore()
+	createUser(t, st)
+	past := time.Now().Add(-time.Hour)
+	one := 1
+	createLink(t, st, &model.URL{Code: "live", Target: "https://93.184.216.34/", UserID: 1})
+	createLink(t, st, &model.URL{Code: "expired", Target: "https://93.184.216.34/", UserID: 1, ExpiresAt: &past})
+	createLink(t, st, &model.URL{Code: "usedup", Target: "https://93.184.216.34/", UserID: 1, MaxClicks: &one})
+	if _, err := st.IncrementClicks(ctx, "usedup"); err != nil {
+		t.Fatal(err)
+	}
+	active := 1
+	r := newLinksRouter(st, &model.Plan{Name: "test", MaxActiveLinks: &active})
+
+	// Every way of bringing a dead link back needs a free slot
+	for _, tt := range []struct{ code, body string }{
+		{"expired", `{"expiresAt": null}`},
+		{"usedup", `{"maxClicks": null}`},
+		{"usedup", `{"maxClicks": 5}`},
+	} {
+		if w := patchLink(r, tt.code, tt.body); w.Code != http.StatusForbidden {
+			t.Fatalf("%s %s: status %d, want %d", tt.code, tt.body, w.Code, http.StatusForbidden)
+		}
+	}
+	if link, _ := st.GetLinkByCode(ctx, "expired"); link.ExpiresAt == nil {
+		t.Fatal("refused patch was stored")
+	}
+
+	// Edits that leave a link as live or as dead as it was are not limited
+	if w := patchLink(r, "live", `{"maxClicks": 10}`); w.Code != http.StatusOK {
+		t.Fatalf("live link: status %d, want %d", w.Code, http.StatusOK)
+	}
+	if w := patchLink(r, "usedup", `{"preview": true}`); w.Code != http.StatusOK {
+		t.Fatalf("dead link: status %d, want %d", w.Code, http.StatusOK)
+	}
+
+	// With room in the plan the link comes back
+	if err := st.DeleteLink(ctx, 1, "live"); err != nil {
+		t.Fatal(err)
+	}
+	if w := patchLink(r, "expired", `{"expiresAt": null}`); w.Code != http.StatusOK {
+		t.Fatalf("with a free slot: status %d, want %d", w.Code, http.StatusOK)
+	}
+}
+
+func TestApplyLinkPatch(t *testing.T) {
+	now := time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)
+	expires := now.Add(time.Hour)
+	limit := 3
+
+	// base returns a link with every patchable field set
+	base := func() *model.URL {
+		exp, max := expires, limit
+		return &model.URL{Target: "https://example.com/", ExpiresAt: &exp, MaxClicks: &max, PasswordHash: "hash", Preview: true}
+	}
+	checkTarget := func(target string) (string, error) {
+		if strings.Contains(target, "blocked") {
+			return "", errors.New("target not allowed")
+		}
+		return strings.ToLower(target), nil
+	}
+
+	tests := []struct {
+		name  string
+		body  string
+		err   string
+		check func(link *model.URL) bool
+	}{
+		{"empty patch changes nothing", `{}`, "", func(l *model.URL) bool {
+			return l.Target == "https://example.com/" && l.ExpiresAt.Equal(expires) && *l.MaxClicks == limit && l.PasswordHash == "hash" && l.Preview
+		}},
+		{"target normalized", `{"target": "HTTPS://Example.org/"}`, "", func(l *model.URL) bool {
+			return l.Target == "https://example.org/" && l.ExpiresAt != nil && l.MaxClicks != nil
+		}},
+		{"target rejected by policy", `{"target": "https://blocked.example/"}`, "target not allowed", nil},
+		{"target null", `{"target": null}`, "target must be a string", nil},
+		{"expiresAt null clears", `{"expiresAt": null}`, "", func(l *model.URL) bool { return l.ExpiresAt == nil && l.MaxClicks != nil }},
+		{"expiresAt set in UTC", `{"expiresAt": "2030-01-02T01:00:00+01:00"}`, "", func(l *model.URL) bool {
+			return l.ExpiresAt.Equal(now.Add(12*time.Hour)) && l.ExpiresAt.Location() == time.UTC
+		}},
+		{"expiresAt in the past", `{"expiresAt": "2029-12-31T00:00:00Z"}`, "expiresAt must be in the future", nil},
+		{"expiresAt not a time", `{"expiresAt": 5}`, "expiresAt must be an RFC 3339 time or null", nil},
+		{"maxClicks null clears", `{"maxClicks": null}`, "", func(l *model.URL) bool { return l.MaxClicks == nil && l.ExpiresAt != nil }},
+		{"maxClicks set", `{"maxClicks": 7}`, "", func(l *model.URL) bool { return *l.MaxClicks == 7 }},
+		{"maxClicks zero", `{"maxClicks": 0}`, "maxClicks must be a positive integer or null", nil},
+		{"maxClicks not a number", `{"maxClicks": "7"}`, "maxClicks must be a positive integer or null", nil},
+		{"password null clears", `{"password": null}`, "", func(l *model.URL) bool { return l.PasswordHash == "" }},
+		{"password too short", `{"password": "ab"}`, fmt.Sprintf("password must be %d to %d characters", minLinkPasswordLen, maxLinkPasswordLen), nil},
+		{"password not a string", `{"password": 1234}`, "password must be a string or null", nil},
+		{"preview false", `{"preview": false}`, "", func(l *model.URL) bool { return !l.Preview }},
+		{"preview null", `{"preview": null}`, "preview must be a boolean", nil},
+		{"unknown field", `{"clicks": 0}`, `field "clicks" cannot be updated`, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fields map[string]json.RawMessage
+			if err := json.Unmarshal([]byte(tt.body), &fields); err != nil {
+				t.Fatal(err)
+			}
+			link := base()
+			err := applyLinkPatch(link, fields, now, checkTarget)
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !tt.check(link) {
+				t.Errorf("patched link = %+v", link)
+			}
+		})
+	}
+}
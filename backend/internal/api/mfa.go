@@ -0,0 +1,407 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ConstantineCTF/URLSecure/backend/internal/model"
+	"github.com/ConstantineCTF/URLSecure/backend/internal/ratelimit" // Caps code guesses per account
+	"github.com/ConstantineCTF/URLSecure/backend/internal/store"
+	authpkg "github.com/ConstantineCTF/URLSecure/backend/pkg/auth" // TOTP and recovery codes
+	"github.com/gin-gonic/gin"
+)
+
+// mfaChallengeTTL is how long the token between password and code step stays valid.
+const mfaChallengeTTL = 5 * time.Minute
+
+// mfaAttempts caps code guesses per account across all challenges and IPs. With the
+// accepted clock drift, each guess of a 6-digit code has a 3 in a million chance.
+var mfaAttempts = ratelimit.Limit{Requests: 10, Window: 15 * time.Minute}
+
+// Errors from checking codes; see mfaFailed.
+var (
+	errMFACode     = errors.New("invalid code")
+	errMFANotSetUp = errors.New("set up two-factor authentication first")
+	errMFAAttempts = errors.New("too many attempts, try again later")
+)
+
+// mfaCode is the second factor sent with a request: a TOTP code or, if the
+// authenticator is lost, one of the recovery codes.
+type mfaCode struct {
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recoveryCode"`
+}
+
+// mfaService runs TOTP two-factor authentication. Users with 2FA enabled, or required
+// to use it, only get a short-lived challenge token for their password; the token,
+// signed like the account tokens, is exchanged for a session together with a code.
+type mfaService struct {
+	users    store.UserStore
+	mfa      store.MFAStore
+	tokens   *accountTokens // Signs challenge tokens
+	limiter  ratelimit.Limiter
+	issuer   string // Account issuer shown by authenticator apps
+	required bool   // Every account must use 2FA
+}
+
+// requiredFor reports whether user must use 2FA.
+func (m *mfaService) requiredFor(user *model.User) bool {
+	return m.required || user.MFARequired
+}
+
+// challenge is the answer to a correct password when a code is needed as well.
+func (m *mfaService) challenge(user *model.User) gin.H {
+	return gin.H{
+		"mfaRequired":   true,
+		"mfaToken":      m.tokens.issue(purposeMFAChallenge, user, mfaChallengeTTL), // Send to /api/login/mfa with a code
+		"expiresIn":     int(mfaChallengeTTL.Seconds()),
+		"setupRequired": !user.TwoFactorEnabled(), // Enroll at /api/login/mfa/setup first
+	}
+}
+
+// attempt spends one of the user's code guesses. Limiter errors fail closed: an
+// unlimited guessing window would defeat the second factor.
+func (m *mfaService) attempt(c *gin.Context, userID uint64) error {
+	res, err := m.limiter.Allow(c.Request.Context(), fmt.Sprintf("mfa:%d", userID), mfaAttempts)
+	if err != nil {
+		return err
+	}
+	if !res.Allowed {
+		c.Header("Retry-After", strconv.Itoa(ratelimit.HeaderSeconds(res.RetryAfter)))
+		return errMFAAttempts
+	}
+	return nil
+}
+
+// setup starts enrollment with a new secret and returns it with its provisioning URI,
+// which the frontend shows as a QR code.
+func (m *mfaService) setup(c *gin.Context, user *model.User) (gin.H, error) {
+	secret := authpkg.NewTOTPSecret()
+	if err := m.mfa.SetTOTPSecret(c.Request.Context(), user.ID, secret); err != nil {
+		return nil, err
+	}
+	return gin.H{
+		"secret": secret,                                        // For typing into an app by hand
+		"uri":    authpkg.TOTPURI(m.issuer, user.Email, secret), // otpauth:// URI for the QR code
+	}, nil
+}
+
+// enable finishes enrollment with a code from the pending secret and returns the new
+// recovery codes.
+func (m *mfaService) enable(c *gin.Context, user *model.User, code string) ([]string, error) {
+	if user.TOTPSecret == "" {
+		return nil, errMFANotSetUp
+	}
+	if err := m.attempt(c, user.ID); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	step, ok := authpkg.ValidateTOTP(user.TOTPSecret, code, now)
+	if !ok {
+		return nil, errMFACode
+	}
+	codes, hashes := authpkg.NewRecoveryCodes()
+	err := m.mfa.EnableTOTP(c.Request.Context(), user.ID, user.TOTPSecret, step, hashes, now)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, errMFACode // Secret replaced by a new setup in the meantime
+	}
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// verify checks the second factor of a user with 2FA enabled. Each TOTP code and
+// recovery code is accepted only once.
+func (m *mfaService) verify(c *gin.Context, user *model.User, req mfaCode) error {
+	if req.Code == "" && req.RecoveryCode == "" {
+		return errMFACode
+	}
+	if err := m.attempt(c, user.ID); err != nil {
+		return err
+	}
+	ctx, now := c.Request.Context(), time.Now()
+
+	if req.Code != "" {
+		step, ok := authpkg.ValidateTOTP(user.TOTPSecret, req.Code, now)
+		if !ok {
+			return errMFACode
+		}
+		used, err := m.mfa.UseTOTPStep(ctx, user.ID, step)
+		if err != nil {
+			return err
+		}
+		if !used {
+			return errMFACode // Replayed
+		}
+		return nil
+	}
+
+	used, err := m.mfa.UseRecoveryCode(ctx, user.ID, authpkg.HashRecoveryCode(req.RecoveryCode), now)
+	if err != nil {
+		return err
+	}
+	if !used {
+		return errMFACode
+	}
+	log.Printf("User %d used a recovery code", user.ID)
+	return nil
+}
+
+// caller loads the logged-in user, writing an error response on failure.
+func (m *mfaService) caller(c *gin.Context) (*model.User, bool) {
+	user, err := m.users.GetUserByID(c.Request.Context(), c.GetUint64("userID"))
+	if err != nil {
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return nil, false
+	}
+	return user, true
+}
+
+// challengeUser resolves a challenge token, writing an error response on failure.
+func (m *mfaService) challengeUser(c *gin.Context, token string) (*model.User, bool) {
+	user, err := m.tokens.verify(c.Request.Context(), m.users, purposeMFAChallenge, token)
+	if errors.Is(err, errAccountToken) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	if err != nil {
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return nil, false
+	}
+	return user, true
+}
+
+// mfaFailed answers a failed code check; status is used for wrong codes.
+func mfaFailed(c *gin.Context, err error, status int) {
+	switch {
+	case errors.Is(err, errMFACode):
+		c.JSON(status, gin.H{"error": err.Error()})
+	case errors.Is(err, errMFANotSetUp):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, errMFAAttempts):
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+	default:
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not check code"})
+	}
+}
+
+// loginMFAHandler completes a login with the challenge token and a code. Users who
+// must use 2FA but have not enrolled yet confirm their new authenticator here and get
+// their recovery codes along with the session.
+func loginMFAHandler(mfa *mfaService, sessions *sessionService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			MFAToken string `json:"mfaToken" binding:"required"`
+			mfaCode
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		user, ok := mfa.challengeUser(c, req.MFAToken)
+		if !ok {
+			return
+		}
+
+		var err error
+		var recoveryCodes []string
+		if user.TwoFactorEnabled() {
+			err = mfa.verify(c, user, req.mfaCode)
+		} else {
+			recoveryCodes, err = mfa.enable(c, user, req.Code)
+		}
+		if err != nil {
+			mfaFailed(c, err, http.StatusUnauthorized)
+			return
+		}
+
+		tokens, err := sessions.issue(c.Request.Context(), user.ID, "")
+		if err != nil {
+			c.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "could not create token"})
+			return
+		}
+		if recoveryCodes != nil {
+			tokens["recoveryCodes"] = recoveryCodes // Shown once
+		}
+		c.JSON(http.StatusOK, tokens)
+	}
+}
+
+// loginMFASetupHandler starts enrollment for a user who has to set up 2FA to log in.
+func loginMFASetupHandler(mfa *mfaService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			MFAToken string `json:"mfaToken" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		user, ok := mfa.challengeUser(c, req.MFAToken)
+		if !ok {
+			return
+		}
+		twoFactorSetup(c, mfa, user)
+	}
+}
+
+// twoFactorSetup answers a setup request of user with a new secret.
+func twoFactorSetup(c *gin.Context, mfa *mfaService, user *model.User) {
+	res, err := mfa.setup(c, user)
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		c.JSON(http.StatusConflict, gin.H{"error": "two-factor authentication already enabled"})
+	case err != nil:
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+	default:
+		c.JSON(http.StatusOK, res)
+	}
+}
+
+// twoFactorStatusHandler reports whether the caller uses and must use 2FA.
+func twoFactorStatusHandler(mfa *mfaService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := mfa.caller(c)
+		if !ok {
+			return
+		}
+		codes, err := mfa.mfa.CountRecoveryCodes(c.Request.Context(), user.ID)
+		if err != nil {
+			c.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"enabled":           user.TwoFactorEnabled(),
+			"required":          mfa.requiredFor(user),
+			"recoveryCodesLeft": codes,
+		})
+	}
+}
+
+// twoFactorSetupHandler starts enrollment for the caller; confirm it at /api/2fa/enable.
+func twoFactorSetupHandler(mfa *mfaService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := mfa.caller(c)
+		if !ok {
+			return
+		}
+		twoFactorSetup(c, mfa, user)
+	}
+}
+
+// enableTwoFactorHandler turns 2FA on with a code from the new authenticator and
+// returns the recovery codes, which are not shown again. Sessions started with only a
+// password are revoked; the caller gets a new one, as it has just passed a code.
+func enableTwoFactorHandler(mfa *mfaService, sessions *sessionService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Code string `json:"code" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		user, ok := mfa.caller(c)
+		if !ok {
+			return
+		}
+		if user.TwoFactorEnabled() {
+			c.JSON(http.StatusConflict, gin.H{"error": "two-factor authentication already enabled"})
+			return
+		}
+		codes, err := mfa.enable(c, user, req.Code)
+		if err != nil {
+			mfaFailed(c, err, http.StatusBadRequest)
+			return
+		}
+
+		ctx := c.Request.Context()
+		if err := sessions.tokens.RevokeUserTokens(ctx, user.ID, time.Now()); err != nil {
+			c.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+		tokens, err := sessions.issue(ctx, user.ID, "")
+		if err != nil {
+			c.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "could not create token"})
+			return
+		}
+		tokens["recoveryCodes"] = codes // Shown once
+		c.JSON(http.StatusOK, tokens)
+	}
+}
+
+// disableTwoFactorHandler turns 2FA off after checking a code, unless it is required.
+func disableTwoFactorHandler(mfa *mfaService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req mfaCode
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		user, ok := mfa.caller(c)
+		if !ok {
+			return
+		}
+		if !user.TwoFactorEnabled() {
+			c.JSON(http.StatusConflict, gin.H{"error": "two-factor authentication not enabled"})
+			return
+		}
+		if mfa.requiredFor(user) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "two-factor authentication is required for this account"})
+			return
+		}
+		if err := mfa.verify(c, user, req); err != nil {
+			mfaFailed(c, err, http.StatusBadRequest)
+			return
+		}
+		if err := mfa.mfa.DisableTOTP(c.Request.Context(), user.ID); err != nil {
+			c.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// recoveryCodesHandler replaces the caller's recovery codes after checking a code.
+func recoveryCodesHandler(mfa *mfaService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req mfaCode
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		user, ok := mfa.caller(c)
+		if !ok {
+			return
+		}
+		if !user.TwoFactorEnabled() {
+			c.JSON(http.StatusConflict, gin.H{"error": "two-factor authentication not enabled"})
+			return
+		}
+		if err := mfa.verify(c, user, req); err != nil {
+			mfaFailed(c, err, http.StatusBadRequest)
+			return
+		}
+		codes, hashes := authpkg.NewRecoveryCodes()
+		if err := mfa.mfa.ReplaceRecoveryCodes(c.Request.Context(), user.ID, hashes); err != nil {
+			c.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"recoveryCodes": codes})
+	}
+}
@@ -0,0 +1,141 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ConstantineCTF/URLSecure/backend/internal/model"
+	"github.com/ConstantineCTF/URLSecure/backend/internal/ratelimit"
+	"github.com/ConstantineCTF/URLSecure/backend/internal/store"
+	authpkg "github.com/ConstantineCTF/URLSecure/backend/pkg/auth"
+	"github.com/gin-gonic/gin"
+)
+
+// testContext returns a gin context for a request that is not routed anywhere.
+func testContext() *gin.Context {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/", nil)
+	return c
+}
+
+// enrolledUser creates a user with 2FA enabled and returns it with its recovery codes.
+// No code has been accepted yet.
+func enrolledUser(t *testing.T, st *store.MemoryStore) (*model.User, []string) {
+	t.Helper()
+	ctx := context.Background()
+	user := &model.User{Username: "alice", Email: "alice@example.com", PasswordHash: "x", Plan: "free"}
+	if err := st.CreateUser(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	secret := authpkg.NewTOTPSecret()
+	codes, hashes := authpkg.NewRecoveryCodes()
+	if err := st.SetTOTPSecret(ctx, user.ID, secret); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.EnableTOTP(ctx, user.ID, secret, 0, hashes, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	user, err := st.GetUserByID(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user, codes
+}
+
+func TestMFAVerifyAcceptsCodesOnce(t *testing.T) {
+	st := store.NewMemoryStore()
+	m := &mfaService{users: st, mfa: st, limiter: ratelimit.NewMemoryLimiter()}
+	user, recovery := enrolledUser(t, st)
+
+	code, err := authpkg.TOTPCode(user.TOTPSecret, authpkg.TOTPStep(time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.verify(testContext(), user, mfaCode{Code: code}); err != nil {
+		t.Fatalf("first use of the code: %v", err)
+	}
+	if err := m.verify(testContext(), user, mfaCode{Code: code}); !errors.Is(err, errMFACode) {
+		t.Fatalf("replayed code: %v, want errMFACode", err)
+	}
+	// A code from before the accepted step is refused too, though still in the window
+	earlier, _ := authpkg.TOTPCode(user.TOTPSecret, authpkg.TOTPStep(time.Now())-1)
+	if err := m.verify(testContext(), user, mfaCode{Code: earlier}); !errors.Is(err, errMFACode) {
+		t.Fatalf("code of an earlier step: %v, want errMFACode", err)
+	}
+
+	// Recovery codes work once each, however they are typed
+	if err := m.verify(testContext(), user, mfaCode{RecoveryCode: " " + recovery[0] + " "}); err != nil {
+		t.Fatalf("recovery code: %v", err)
+	}
+	if err := m.verify(testContext(), user, mfaCode{RecoveryCode: recovery[0]}); !errors.Is(err, errMFACode) {
+		t.Fatalf("reused recovery code: %v, want errMFACode", err)
+	}
+	if left, _ := st.CountRecoveryCodes(context.Background(), user.ID); left != authpkg.RecoveryCodeCount-1 {
+		t.Fatalf("%d recovery codes left, want %d", left, authpkg.RecoveryCodeCount-1)
+	}
+}
+
+func TestMFAVerifyLimitsGuesses(t *testing.T) {
+	st := store.NewMemoryStore()
+	m := &mfaService{users: st, mfa: st, limiter: ratelimit.NewMemoryLimiter()}
+	user, recovery := enrolledUser(t, st)
+
+	for i := range mfaAttempts.Requests {
+		if err := m.verify(testContext(), user, mfaCode{Code: "000000"}); !errors.Is(err, errMFACode) {
+			t.Fatalf("guess %d: %v, want errMFACode", i+1, err)
+		}
+	}
+	// Once the guesses are used up even a valid code waits
+	c := testContext()
+	if err := m.verify(c, user, mfaCode{RecoveryCode: recovery[0]}); !errors.Is(err, errMFAAttempts) {
+		t.Fatalf("after %d guesses: %v, want errMFAAttempts", mfaAttempts.Requests, err)
+	}
+	if c.Writer.Header().Get("Retry-After") == "" {
+		t.Fatal("no Retry-After header")
+	}
+}
+
+func TestEnableTwoFactorSignsOutOtherSessions(t *testing.T) {
+	ctx := context.Background()
+	s := newTestSessions(t)
+	st := s.users.(*store.MemoryStore)
+	m := &mfaService{users: st, mfa: st, limiter: ratelimit.NewMemoryLimiter()}
+	old, err := s.issue(ctx, 1, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := authpkg.NewTOTPSecret()
+	if err := st.SetTOTPSecret(ctx, 1, secret); err != nil {
+		t.Fatal(err)
+	}
+	code, _ := authpkg.TOTPCode(secret, authpkg.TOTPStep(time.Now()))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/api/2fa/enable", func(c *gin.Context) { c.Set("userID", uint64(1)) }, enableTwoFactorHandler(m, s))
+	w := postJSON(r, "/api/2fa/enable", `{"code": "`+code+`"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("enable: status %d, body %s", w.Code, w.Body.String())
+	}
+	var res map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	if codes, _ := res["recoveryCodes"].([]any); len(codes) != authpkg.RecoveryCodeCount {
+		t.Fatalf("%d recovery codes, want %d", len(codes), authpkg.RecoveryCodeCount)
+	}
+
+	// The password-only session is over; the one that passed the code carries on
+	if _, err := s.refresh(ctx, refreshToken(t, old)); !errors.Is(err, errRefreshInvalid) {
+		t.Fatalf("old session: %v, want errRefreshInvalid", err)
+	}
+	if _, err := s.refresh(ctx, refreshToken(t, res)); err != nil {
+		t.Fatalf("new session: %v", err)
+	}
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"embed"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// templatesFS holds the server-rendered HTML pages shown on the redirect path.
+//
+//go:embed templates/*.html
+var templatesFS embed.FS
+
+// loadTemplates parses the embedded page templates for gin's c.HTML.
+func loadTemplates() *template.Template {
+	return template.Must(template.ParseFS(templatesFS, "templates/*.html"))
+}
+
+// wantsHTML reports whether the client prefers an HTML page (browsers) over plain text (curl, bots).
+func wantsHTML(c *gin.Context) bool {
+	return c.NegotiateFormat(gin.MIMEPlain, gin.MIMEHTML) == gin.MIMEHTML
+}
+
+// renderGone responds 410 Gone for links that expired or used up their clicks.
+func renderGone(c *gin.Context, message string) {
+	renderUnavailable(c, http.StatusGone, message)
+}
+
+// renderUnavailable shows the "link unavailable" page with the given status.
+func renderUnavailable(c *gin.Context, status int, message string) {
+	if wantsHTML(c) {
+		c.HTML(status, "gone.html", gin.H{"Title": "Link unavailable", "Message": message})
+		return
+	}
+	c.String(status, message)
+}
+
+// previewPage is the data for preview.html, the interstitial shown before leaving the site.
+type previewPage struct {
+	Title       string
+	Target      string   // Full destination URL
+	Owner       string   // Username of the link owner; empty when not shown
+	Warnings    []string // Safety warnings; the page is styled as a warning when non-empty
+	ContinueURL string   // Where the continue button leads; empty leaves the button out
+}
+
+// renderPreview shows the interstitial. Plain-text clients get the same facts as text.
+func renderPreview(c *gin.Context, page previewPage) {
+	if wantsHTML(c) {
+		c.HTML(http.StatusOK, "preview.html", page)
+		return
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Destination: %s\n", page.Target)
+	if page.Owner != "" {
+		fmt.Fprintf(&b, "Owner: %s\n", page.Owner)
+	}
+	for _, warning := range page.Warnings {
+		fmt.Fprintf(&b, "Warning: %s\n", warning)
+	}
+	if page.ContinueURL != "" {
+		fmt.Fprintf(&b, "Continue: %s\n", page.ContinueURL)
+	}
+	c.String(http.StatusOK, b.String())
+}
+
+// renderPasswordForm asks for a link's password; the form posts back to /r/<param>.
+func renderPasswordForm(c *gin.Context, status int, param, errMsg string) {
+	action := "/r/" + param
+	if wantsHTML(c) {
+		c.HTML(status, "password.html", gin.H{"Title": "Password required", "Action": action, "Error": errMsg})
+		return
+	}
+	if errMsg != "" {
+		errMsg += "\n"
+	}
+	c.String(status, "%sThis link is password protected. POST the form field \"password\" to %s.\n", errMsg, action)
+}
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/ConstantineCTF/URLSecure/backend/internal/model"
+	"github.com/ConstantineCTF/URLSecure/backend/internal/store"
+	"github.com/ConstantineCTF/URLSecure/backend/pkg/blocklist"
+	"github.com/gin-gonic/gin"
+)
+
+// previewSuffix appended to a short code (/r/abc123+) shows the preview instead of redirecting.
+const previewSuffix = "+"
+
+// previewPolicy decides which links show the interstitial before redirecting.
+type previewPolicy string
+
+// Preview policies, set with PREVIEW_POLICY.
+const (
+	previewPerLink previewPolicy = "link"  // Only links created with preview enabled
+	previewRisky   previewPolicy = "risky" // Also links the phishing heuristics had anything to say about
+	previewAll     previewPolicy = "all"   // Every link
+)
+
+// parsePreviewPolicy validates a PREVIEW_POLICY value; empty means previewPerLink.
+func parsePreviewPolicy(value string) (previewPolicy, error) {
+	switch p := previewPolicy(value); p {
+	case "":
+		return previewPerLink, nil
+	case previewPerLink, previewRisky, previewAll:
+		return p, nil
+	default:
+		return "", fmt.Errorf("unknown policy %q (want link, risky or all)", value)
+	}
+}
+
+// required reports whether link must show the interstitial before redirecting.
+func (p previewPolicy) required(link *model.URL) bool {
+	switch {
+	case link.Preview, p == previewAll:
+		return true
+	case p == previewRisky:
+		return link.RiskScore > 0
+	default:
+		return false
+	}
+}
+
+// renderLinkPreview shows the interstitial for link with its owner and safety warnings.
+// Continuing counts the click; a blocklisted target is linked directly so the visitor
+// is not sent back through the blocklist warning. That link would skip the count, so a
+// blocklisted click-limited link gets no continue button at all.
+func renderLinkPreview(c *gin.Context, users store.UserStore, threats *blocklist.List, link *model.URL) {
+	page := previewPage{
+		Title:       "Link preview",
+		Target:      link.Target,
+		ContinueURL: "/r/" + link.Code + "?continue=1",
+	}
+
+	if owner, err := users.GetUserByID(context.Background(), link.UserID); err == nil {
+		page.Owner = owner.Username
+	} else {
+		log.Printf("Owner lookup failed for code %s: %v", link.Code, err)
+	}
+
+	for _, reason := range link.RiskReasons {
+		page.Warnings = append(page.Warnings, reason.Message)
+	}
+	if threats != nil {
+		if match, ok := threats.Match(link.Target); ok {
+			page.Warnings = append(page.Warnings, blocklistWarning(match))
+			page.ContinueURL = link.Target
+			if link.MaxClicks != nil {
+				page.ContinueURL = ""
+			}
+		}
+	}
+	if len(page.Warnings) > 0 {
+		page.Title = "Warning"
+	}
+
+	renderPreview(c, page)
+}
+
+// blocklistWarning describes a blocklist match for the interstitial.
+func blocklistWarning(match blocklist.Match) string {
+	return "This destination is listed as malicious by " + match.Source + "."
+}
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ConstantineCTF/URLSecure/backend/internal/model"
+	"github.com/ConstantineCTF/URLSecure/backend/internal/ratelimit"
+	"github.com/ConstantineCTF/URLSecure/backend/internal/store"
+	"github.com/gin-gonic/gin"
+)
+
+// quotaService looks up a user's plan and usage.
+type quotaService struct {
+	users  store.UserStore
+	quotas store.QuotaStore
+}
+
+// plan returns the plan of the given user.
+func (q *quotaService) plan(ctx context.Context, userID uint64) (*model.Plan, error) {
+	user, err := q.users.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	plan, err := q.quotas.GetPlan(ctx, user.Plan)
+	if err != nil {
+		return nil, fmt.Errorf("plan %q of user %d: %w", user.Plan, userID, err)
+	}
+	return plan, nil
+}
+
+// load returns the user's plan together with their usage at now.
+func (q *quotaService) load(ctx context.Context, userID uint64, now time.Time) (*model.Plan, model.Usage, error) {
+	plan, err := q.plan(ctx, userID)
+	if err != nil {
+		return nil, model.Usage{}, err
+	}
+	usage, err := q.quotas.GetUsage(ctx, userID, now)
+	return plan, usage, err
+}
+
+// quotaCounter describes one limit; Limit and Remaining are null when unlimited.
+func quotaCounter(limit *int, used int, resetsAt *time.Time) gin.H {
+	counter := gin.H{"limit": limit, "used": used, "remaining": nil}
+	if limit != nil {
+		counter["remaining"] = max(*limit-used, 0)
+	}
+	if resetsAt != nil {
+		counter["resetsAt"] = resetsAt
+	}
+	return counter
+}
+
+// quotaJSON is the API representation of a plan and its usage.
+func quotaJSON(plan *model.Plan, usage model.Usage, now time.Time) gin.H {
+	nextDay, nextMonth := periodEnds(now)
+	return gin.H{
+		"plan":        plan.Name,
+		"daily":       quotaCounter(plan.DailyLinks, usage.Daily, &nextDay),
+		"monthly":     quotaCounter(plan.MonthlyLinks, usage.Monthly, &nextMonth),
+		"activeLinks": quotaCounter(plan.MaxActiveLinks, usage.Active, nil),
+		"features": gin.H{
+			"customAliases":          plan.CustomAliases,
+			"passwordLinks":          plan.PasswordLinks,
+			"analyticsRetentionDays": plan.AnalyticsRetentionDays,
+		},
+	}
+}
+
+// periodEnds returns the starts of the next UTC day and month, when the counters reset.
+func periodEnds(now time.Time) (nextDay, nextMonth time.Time) {
+	now = now.UTC()
+	nextDay = time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+	nextMonth = time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+	return nextDay, nextMonth
+}
+
+// reached reports whether used has hit an optional limit.
+func reached(limit *int, used int) bool {
+	return limit != nil && used >= *limit
+}
+
+// enforceQuota checks a link creation against the plan. Missing features and a full
+// active-link allowance are 403 (waiting does not help); exhausted daily or monthly
+// allowances are 429 with Retry-After. It writes the response and returns false on refusal.
+// The daily and monthly checks only fail early; ReserveLinkCreation enforces them.
+func enforceQuota(c *gin.Context, plan *model.Plan, usage model.Usage, now time.Time, alias, password bool) bool {
+	refuse := func(status int, msg string) bool {
+		c.JSON(status, gin.H{"error": msg, "quota": quotaJSON(plan, usage, now)})
+		return false
+	}
+	nextDay, nextMonth := periodEnds(now)
+
+	switch {
+	case alias && !plan.CustomAliases:
+		return refuse(http.StatusForbidden, fmt.Sprintf("custom aliases are not available on the %s plan", plan.Name))
+	case password && !plan.PasswordLinks:
+		return refuse(http.StatusForbidden, fmt.Sprintf("password-protected links are not available on the %s plan", plan.Name))
+	case reached(plan.MaxActiveLinks, usage.Active):
+		return refuse(http.StatusForbidden, "active link limit reached; delete or expire links to create more")
+	case reached(plan.DailyLinks, usage.Daily):
+		c.Header("Retry-After", strconv.Itoa(ratelimit.HeaderSeconds(nextDay.Sub(now))))
+		return refuse(http.StatusTooManyRequests, "daily link limit reached")
+	case reached(plan.MonthlyLinks, usage.Monthly):
+		c.Header("Retry-After", strconv.Itoa(ratelimit.HeaderSeconds(nextMonth.Sub(now))))
+		return refuse(http.StatusTooManyRequests, "monthly link limit reached")
+	}
+	return true
+}
+
+// quotaHandler returns the caller's plan, usage and remaining quota.
+func quotaHandler(quotas *quotaService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		now := time.Now()
+		plan, usage, err := quotas.load(c.Request.Context(), c.GetUint64("userID"), now)
+		if err != nil {
+			c.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+		c.JSON(http.StatusOK, quotaJSON(plan, usage, now))
+	}
+}
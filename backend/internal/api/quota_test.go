@@ -0,0 +1,97 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ConstantineCTF/URLSecure/backend/internal/model"
+	"github.com/ConstantineCTF/URLSecure/backend/internal/shortcode"
+	"github.com/ConstantineCTF/URLSecure/backend/internal/store"
+	"github.com/ConstantineCTF/URLSecure/backend/pkg/phishscore"
+	"github.com/ConstantineCTF/URLSecure/backend/pkg/urlpolicy"
+	"github.com/gin-gonic/gin"
+)
+
+// fixedPlan puts every user on plan.
+type fixedPlan struct {
+	store.QuotaStore
+	plan *model.Plan
+}
+
+func (f fixedPlan) GetPlan(context.Context, string) (*model.Plan, error) { return f.plan, nil }
+
+// newShortenRouter serves POST /shorten for user 1 of st, who is on plan.
+func newShortenRouter(st *store.MemoryStore, plan *model.Plan) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	quotas := &quotaService{users: st, quotas: fixedPlan{QuotaStore: st, plan: plan}}
+	r.POST("/shorten", func(c *gin.Context) { c.Set("userID", uint64(1)) }, shortenHandler(st, store.NewMemoryCache(),
+		shortcode.NewAllocator(shortcode.Random{}, 6, shortcode.MaxLength), newAliasValidator(nil),
+		urlpolicy.New(urlpolicy.Config{}), phishscore.New(phishscore.Config{}), previewPerLink, quotas))
+	return r
+}
+
+// shorten posts body to /shorten and returns the response.
+func shorten(r http.Handler, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/shorten", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestShortenQuotaHoldsUnderConcurrency(t *testing.T) {
+	st := store.NewMemoryStore()
+	createUser(t, st)
+	daily := 3
+	r := newShortenRouter(st, &model.Plan{Name: "test", DailyLinks: &daily})
+
+	const requests = 10
+	codes := make(chan int, requests)
+	var wg sync.WaitGroup
+	for range requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes <- shorten(r, `{"url": "https://93.184.216.34/"}`).Code
+		}()
+	}
+	wg.Wait()
+	close(codes)
+	counts := make(map[int]int)
+	for code := range codes {
+		counts[code]++
+	}
+	if counts[http.StatusCreated] != daily || counts[http.StatusTooManyRequests] != requests-daily {
+		t.Fatalf("responses %v, want %d x 201 and the rest 429", counts, daily)
+	}
+
+	w := shorten(r, `{"url": "https://93.184.216.34/"}`)
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Fatalf("over the limit: status %d, Retry-After %q", w.Code, w.Header().Get("Retry-After"))
+	}
+	if usage, _ := st.GetUsage(context.Background(), 1, time.Now()); usage.Daily != daily || usage.Active != daily {
+		t.Fatalf("usage %+v, want %d created and live", usage, daily)
+	}
+}
+
+func TestShortenReleasesQuotaOnFailure(t *testing.T) {
+	st := store.NewMemoryStore()
+	createUser(t, st)
+	createLink(t, st, &model.URL{Code: "taken", Target: "https://93.184.216.34/", UserID: 2})
+	daily := 1
+	r := newShortenRouter(st, &model.Plan{Name: "test", DailyLinks: &daily, CustomAliases: true})
+
+	// The conflict must not use up the only link of the day
+	if w := shorten(r, `{"url": "https://93.184.216.34/", "alias": "taken"}`); w.Code != http.StatusConflict {
+		t.Fatalf("taken alias: status %d, want %d", w.Code, http.StatusConflict)
+	}
+	if w := shorten(r, `{"url": "https://93.184.216.34/", "alias": "mine"}`); w.Code != http.StatusCreated {
+		t.Fatalf("after the conflict: status %d, want %d: %s", w.Code, http.StatusCreated, w.Body)
+	}
+}
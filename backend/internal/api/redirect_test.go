@@ -0,0 +1,264 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ConstantineCTF/URLSecure/backend/internal/analytics"
+	"github.com/ConstantineCTF/URLSecure/backend/internal/model"
+	"github.com/ConstantineCTF/URLSecure/backend/internal/store"
+	authpkg "github.com/ConstantineCTF/URLSecure/backend/pkg/auth"
+	"github.com/ConstantineCTF/URLSecure/backend/pkg/blocklist"
+	"github.com/ConstantineCTF/URLSecure/backend/pkg/botdetect"
+	"github.com/gin-gonic/gin"
+)
+
+// browserAgent is a User-Agent botdetect classifies as a person.
+const browserAgent = "Mozilla/5.0 (X11; Linux x86_64; rv:120.0) Gecko/20100101 Firefox/120.0"
+
+// newRedirectRouter serves /r/:code from st and cache the way NewRouter does, without
+// click recording. threats may be nil.
+func newRedirectRouter(st *store.MemoryStore, cache store.Cache, previews previewPolicy, threats *blocklist.List) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.SetHTMLTemplate(loadTemplates())
+	access := newLinkAccess("test secret")
+	r.GET("/r/:code", redirectHandler(st, st, cache, threats, previews, access, botdetect.New(nil), nil, analytics.Anonymizer{}))
+	r.POST("/r/:code", unlockLinkHandler(st, cache, access))
+	return r
+}
+
+// visit requests path with userAgent and returns the response.
+func visit(r http.Handler, path, userAgent string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("User-Agent", userAgent)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// createLink stores link in st, failing the test on error.
+func createLink(t *testing.T, st *store.MemoryStore, link *model.URL) {
+	t.Helper()
+	if err := st.CreateLink(context.Background(), link); err != nil {
+		t.Fatalf("CreateLink: %v", err)
+	}
+}
+
+func TestRedirectKeepsPreviewAfterContinue(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   previewPolicy
+		link     model.URL
+		previews bool // Whether a plain visit shows the interstitial
+	}{
+		{"per-link preview", previewPerLink, model.URL{Preview: true}, true},
+		{"risky policy", previewRisky, model.URL{RiskScore: 30}, true},
+		{"all policy", previewAll, model.URL{}, true},
+		{"no preview", previewPerLink, model.URL{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st, cache := store.NewMemoryStore(), store.NewMemoryCache()
+			link := tt.link
+			link.Code, link.Target = "abc123", "https://example.com/"
+			createLink(t, st, &link)
+			r := newRedirectRouter(st, cache, tt.policy, nil)
+
+			// Continuing from the interstitial redirects...
+			if w := visit(r, "/r/abc123?continue=1", browserAgent); w.Code != http.StatusFound {
+				t.Fatalf("confirmed visit: status %d, want %d", w.Code, http.StatusFound)
+			}
+			// ...but must not let the next visitor skip it through the cache
+			want := http.StatusFound
+			if tt.previews {
+				want = http.StatusOK
+			}
+			for i := 0; i < 2; i++ {
+				if w := visit(r, "/r/abc123", browserAgent); w.Code != want {
+					t.Fatalf("plain visit %d: status %d, want %d", i+1, w.Code, want)
+				}
+			}
+		})
+	}
+}
+
+// unlock posts password to the link's form and returns the response.
+func unlock(r http.Handler, code, password string) *httptest.ResponseRecorder {
+	return unlockFrom(r, code, password, "192.0.2.1")
+}
+
+// unlockFrom posts password to the link's form from client IP ip.
+func unlockFrom(r http.Handler, code, password, ip string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/r/"+code, strings.NewReader(url.Values{"password": {password}}.Encode()))
+	req.RemoteAddr = ip + ":41234"
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", browserAgent)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestRedirectKeepsPasswordAfterUnlock(t *testing.T) {
+	st, cache := store.NewMemoryStore(), store.NewMemoryCache()
+	hash, err := authpkg.HashPassword("hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	createLink(t, st, &model.URL{Code: "secret", Target: "https://example.com/", PasswordHash: hash})
+	r := newRedirectRouter(st, cache, previewPerLink, nil)
+
+	w := unlock(r, "secret", "hunter2")
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("unlock: status %d, want %d", w.Code, http.StatusSeeOther)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/r/secret", nil)
+	req.Header.Set("User-Agent", browserAgent)
+	for _, cookie := range w.Result().Cookies() {
+		req.AddCookie(cookie)
+	}
+	unlocked := httptest.NewRecorder()
+	r.ServeHTTP(unlocked, req)
+	if unlocked.Code != http.StatusFound {
+		t.Fatalf("visit with cookie: status %d, want %d", unlocked.Code, http.StatusFound)
+	}
+
+	// Someone without the cookie still has to enter the password
+	if w := visit(r, "/r/secret", browserAgent); w.Code != http.StatusUnauthorized {
+		t.Fatalf("visit without cookie: status %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestLinkCacheTTL(t *testing.T) {
+	now := time.Now()
+	soon := now.Add(time.Hour)
+	limit := 3
+	tests := []struct {
+		name   string
+		policy previewPolicy
+		link   model.URL
+		want   time.Duration
+	}{
+		{"plain", previewPerLink, model.URL{}, 24 * time.Hour},
+		{"expires sooner", previewPerLink, model.URL{ExpiresAt: &soon}, time.Hour},
+		{"click limited", previewPerLink, model.URL{MaxClicks: &limit}, 0},
+		{"pending review", previewPerLink, model.URL{Status: model.LinkPendingReview}, 0},
+		{"password", previewPerLink, model.URL{PasswordHash: "$2a$10$x"}, 0},
+		{"per-link preview", previewPerLink, model.URL{Preview: true}, 0},
+		{"risky under risky policy", previewRisky, model.URL{RiskScore: 10}, 0},
+		{"risky under link policy", previewPerLink, model.URL{RiskScore: 10}, 24 * time.Hour},
+		{"all policy", previewAll, model.URL{}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := linkCacheTTL(&tt.link, tt.policy, now); got != tt.want {
+				t.Errorf("linkCacheTTL = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedirectClickLimitIgnoresBotAgents(t *testing.T) {
+	st := store.NewMemoryStore()
+	limit := 1
+	createLink(t, st, &model.URL{Code: "once", Target: "https://example.com/secret", MaxClicks: &limit})
+	r := newRedirectRouter(st, store.NewMemoryCache(), previewPerLink, nil)
+
+	// Claiming to be a bot neither reveals the target nor uses the click
+	for _, agent := range []string{"curl/8.4.0", "Slackbot-LinkExpanding 1.0", "anybot", ""} {
+		w := visit(r, "/r/once", agent)
+		if w.Code != http.StatusForbidden {
+			t.Fatalf("bot %q: status %d, want %d", agent, w.Code, http.StatusForbidden)
+		}
+		if loc := w.Header().Get("Location"); loc != "" || strings.Contains(w.Body.String(), "example.com") {
+			t.Fatalf("bot %q was shown the target (Location %q)", agent, loc)
+		}
+	}
+
+	if w := visit(r, "/r/once", browserAgent); w.Code != http.StatusFound {
+		t.Fatalf("first click: status %d, want %d", w.Code, http.StatusFound)
+	}
+	for _, agent := range []string{browserAgent, "curl/8.4.0"} {
+		if w := visit(r, "/r/once", agent); w.Code != http.StatusGone {
+			t.Fatalf("after the limit, %q: status %d, want %d", agent, w.Code, http.StatusGone)
+		}
+	}
+}
+
+// blockDomains returns a blocklist of the given domains.
+func blockDomains(t *testing.T, domains ...string) *blocklist.List {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.txt")
+	if err := os.WriteFile(path, []byte(strings.Join(domains, "\n")+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	list, err := blocklist.New([]blocklist.Source{{Format: blocklist.FormatDomains, Path: path}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return list
+}
+
+func TestBlocklistWarningKeepsClickLimit(t *testing.T) {
+	st := store.NewMemoryStore()
+	limit := 1
+	createLink(t, st, &model.URL{Code: "once", Target: "https://evil.example/secret", MaxClicks: &limit})
+	createLink(t, st, &model.URL{Code: "plain", Target: "https://evil.example/open"})
+	r := newRedirectRouter(st, store.NewMemoryCache(), previewPerLink, blockDomains(t, "evil.example"))
+
+	// The warning of a click-limited link offers no way around the count
+	w := visit(r, "/r/once", browserAgent)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "malicious") {
+		t.Fatalf("click-limited: status %d, want the warning page", w.Code)
+	}
+	if strings.Contains(w.Body.String(), "Continue") {
+		t.Fatalf("click-limited warning links to the target:\n%s", w.Body.String())
+	}
+
+	// So does its preview
+	w = visit(r, "/r/once+", browserAgent)
+	if w.Code != http.StatusOK || strings.Contains(w.Body.String(), "Continue") {
+		t.Fatalf("click-limited preview: status %d, want a warning without a continue link:\n%s", w.Code, w.Body.String())
+	}
+
+	// Once the clicks are used up the link is gone, warning or not
+	if _, err := st.IncrementClicks(context.Background(), "once"); err != nil {
+		t.Fatal(err)
+	}
+	if w := visit(r, "/r/once", browserAgent); w.Code != http.StatusGone || strings.Contains(w.Body.String(), "evil.example") {
+		t.Fatalf("used up: status %d, want %d without the target", w.Code, http.StatusGone)
+	}
+
+	// Other links still let the visitor continue at their own risk
+	for _, path := range []string{"/r/plain", "/r/plain+"} {
+		if w := visit(r, path, browserAgent); !strings.Contains(w.Body.String(), "Continue: https://evil.example/open") {
+			t.Fatalf("%s: warning has no continue link:\n%s", path, w.Body.String())
+		}
+	}
+}
+
+func TestNewVisitTruncatesAllButFingerprint(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/r/abc123", nil)
+	c.Request.RemoteAddr = "203.0.113.57:51234"
+	anon := analytics.Anonymizer{IPv4Prefix: analytics.DefaultIPv4Prefix, IPv6Prefix: analytics.DefaultIPv6Prefix}
+
+	v := newVisit(c, "abc123", botdetect.Result{}, anon)
+	if v.IP != "203.0.113.0" || v.VisitorIP != "203.0.113.57" {
+		t.Fatalf("IP %q, VisitorIP %q; want 203.0.113.0 and 203.0.113.57", v.IP, v.VisitorIP)
+	}
+
+	// Private visitors leave no address at all
+	c.Request.Header.Set("DNT", "1")
+	if v := newVisit(c, "abc123", botdetect.Result{}, anon); v.IP != "" || v.VisitorIP != "" {
+		t.Fatalf("private visit kept IP %q, VisitorIP %q", v.IP, v.VisitorIP)
+	}
+}
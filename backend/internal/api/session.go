@@ -0,0 +1,186 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/ConstantineCTF/URLSecure/backend/internal/middleware" // Revoked token cache keys
+	"github.com/ConstantineCTF/URLSecure/backend/internal/model"
+	"github.com/ConstantineCTF/URLSecure/backend/internal/store"
+	authpkg "github.com/ConstantineCTF/URLSecure/backend/pkg/auth" // Token creation and hashing
+	"github.com/gin-gonic/gin"
+)
+
+// Errors from sessionService.refresh; all of them mean "log in again".
+var (
+	errRefreshInvalid = errors.New("invalid refresh token")
+	errRefreshReused  = errors.New("refresh token reused")
+	errRefreshMFA     = errors.New("two-factor authentication required; log in again")
+)
+
+// sessionService issues access tokens together with rotating refresh tokens.
+type sessionService struct {
+	signer     *authpkg.KeyManager // Signs access tokens
+	tokens     store.TokenStore
+	users      store.UserStore
+	mfa        *mfaService   // Refreshes are refused while a required 2FA is not set up
+	revoked    store.Cache   // Denylist of access token IDs, checked by AuthMiddleware
+	accessTTL  time.Duration // Lifetime of access tokens (JWTs)
+	refreshTTL time.Duration // Lifetime of each refresh token
+}
+
+// issue creates an access token and a refresh token in family; an empty family
+// starts a new one (a fresh login).
+func (s *sessionService) issue(ctx context.Context, userID uint64, family string) (gin.H, error) {
+	access, err := s.signer.CreateJWT(userID, s.accessTTL)
+	if err != nil {
+		return nil, err
+	}
+	if family == "" {
+		family = authpkg.RandomID()
+	}
+	refresh, hash := authpkg.NewRefreshToken()
+	token := &model.RefreshToken{UserID: userID, FamilyID: family, TokenHash: hash, ExpiresAt: time.Now().Add(s.refreshTTL)}
+	if err := s.tokens.CreateRefreshToken(ctx, token); err != nil {
+		return nil, err
+	}
+	return gin.H{
+		"token":        access,                     // Access token for the Authorization header
+		"expiresIn":    int(s.accessTTL.Seconds()), // Seconds until the access token expires
+		"refreshToken": refresh,                    // Single-use; exchange at /api/token/refresh
+	}, nil
+}
+
+// refresh exchanges a refresh token for a new pair in the same family. A token that
+// was already exchanged is proof that someone else holds a copy, so the whole family
+// is revoked and both parties have to log in again. So is a family of a user who must
+// use 2FA but has not set it up, as the session never passed a second factor.
+func (s *sessionService) refresh(ctx context.Context, raw string) (gin.H, error) {
+	now := time.Now()
+	token, err := s.tokens.GetRefreshToken(ctx, authpkg.HashRefreshToken(raw))
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, errRefreshInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	if token.RevokedAt != nil || now.After(token.ExpiresAt) {
+		return nil, errRefreshInvalid
+	}
+
+	used, err := s.tokens.UseRefreshToken(ctx, token.ID, now)
+	if err != nil {
+		return nil, err
+	}
+	if token.UsedAt != nil || !used {
+		log.Printf("Refresh token reuse for user %d; revoking token family %s", token.UserID, token.FamilyID)
+		if err := s.tokens.RevokeTokenFamily(ctx, token.FamilyID, now); err != nil {
+			return nil, err
+		}
+		return nil, errRefreshReused
+	}
+
+	user, err := s.users.GetUserByID(ctx, token.UserID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, errRefreshInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	if s.mfa.requiredFor(user) && !user.TwoFactorEnabled() {
+		if err := s.tokens.RevokeTokenFamily(ctx, token.FamilyID, now); err != nil {
+			return nil, err
+		}
+		return nil, errRefreshMFA
+	}
+	return s.issue(ctx, token.UserID, token.FamilyID)
+}
+
+// revokeAccess adds an access token to the denylist until it would have expired anyway.
+func (s *sessionService) revokeAccess(ctx context.Context, jti string, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if jti == "" || ttl <= 0 {
+		return nil
+	}
+	return s.revoked.Set(ctx, middleware.RevokedTokenKey(jti), "1", ttl)
+}
+
+// refreshTokenHandler exchanges a refresh token for a new access and refresh token.
+func refreshTokenHandler(sessions *sessionService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			RefreshToken string `json:"refreshToken" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		tokens, err := sessions.refresh(c.Request.Context(), req.RefreshToken)
+		switch {
+		case errors.Is(err, errRefreshInvalid), errors.Is(err, errRefreshReused), errors.Is(err, errRefreshMFA):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		case err != nil:
+			c.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "could not refresh token"})
+			return
+		}
+		c.JSON(http.StatusOK, tokens)
+	}
+}
+
+// logoutHandler revokes the caller's access token and, if given, the refresh token
+// family it was issued with.
+func logoutHandler(sessions *sessionService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			RefreshToken string `json:"refreshToken"` // Optional: ends the session on every tab, not just this token
+		}
+		if c.Request.ContentLength != 0 {
+			if err := c.ShouldBindJSON(&req); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+		ctx := c.Request.Context()
+		now := time.Now()
+
+		if req.RefreshToken != "" {
+			token, err := sessions.tokens.GetRefreshToken(ctx, authpkg.HashRefreshToken(req.RefreshToken))
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				// Unknown or already purged: nothing left to revoke
+			case err != nil:
+				c.Error(err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+				return
+			case token.UserID == c.GetUint64("userID"):
+				if err := sessions.tokens.RevokeTokenFamily(ctx, token.FamilyID, now); err != nil {
+					c.Error(err)
+					c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+					return
+				}
+			}
+		}
+
+		if err := sessions.revokeAccess(ctx, c.GetString("tokenID"), c.GetTime("tokenExpiresAt")); err != nil {
+			c.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "could not revoke token"})
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// jwksHandler publishes the public keys that verify access tokens (RFC 7517).
+func jwksHandler(signer *authpkg.KeyManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", int(authpkg.JWKSMaxAge.Seconds())))
+		c.JSON(http.StatusOK, gin.H{"keys": signer.JWKS()})
+	}
+}
@@ -0,0 +1,173 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ConstantineCTF/URLSecure/backend/internal/middleware"
+	"github.com/ConstantineCTF/URLSecure/backend/internal/store"
+	authpkg "github.com/ConstantineCTF/URLSecure/backend/pkg/auth"
+)
+
+// newTestSessions returns a sessionService with an HS256 signer on a MemoryStore holding
+// user 1, who does not have to use 2FA.
+func newTestSessions(t *testing.T) *sessionService {
+	t.Helper()
+	signer, err := authpkg.NewKeyManager(authpkg.KeyConfig{Secret: "test secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := store.NewMemoryStore()
+	createUser(t, st)
+	return &sessionService{signer: signer, tokens: st, users: st, mfa: &mfaService{users: st, mfa: st},
+		revoked: store.NewMemoryCache(), accessTTL: 15 * time.Minute, refreshTTL: time.Hour}
+}
+
+// refreshToken returns the refresh token of an issue or refresh response.
+func refreshToken(t *testing.T, tokens map[string]any) string {
+	t.Helper()
+	raw, ok := tokens["refreshToken"].(string)
+	if !ok || raw == "" {
+		t.Fatalf("no refresh token in %v", tokens)
+	}
+	return raw
+}
+
+func TestRefreshRotatesAndDetectsReuse(t *testing.T) {
+	ctx := context.Background()
+	s := newTestSessions(t)
+	login, err := s.issue(ctx, 1, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := refreshToken(t, login)
+
+	rotated, err := s.refresh(ctx, first)
+	if err != nil {
+		t.Fatalf("first refresh: %v", err)
+	}
+	if claims, err := s.signer.ParseJWT(rotated["token"].(string)); err != nil || claims.UserID != 1 {
+		t.Fatalf("access token from refresh: %v", err)
+	}
+	second := refreshToken(t, rotated)
+
+	// Presenting the exchanged token again means it was copied: end the whole family
+	if _, err := s.refresh(ctx, first); !errors.Is(err, errRefreshReused) {
+		t.Fatalf("reused token: %v, want errRefreshReused", err)
+	}
+	if _, err := s.refresh(ctx, second); !errors.Is(err, errRefreshInvalid) {
+		t.Fatalf("latest token after reuse: %v, want errRefreshInvalid", err)
+	}
+
+	// Other logins are separate families and keep working
+	other, err := s.issue(ctx, 1, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.refresh(ctx, refreshToken(t, other)); err != nil {
+		t.Fatalf("other session: %v", err)
+	}
+}
+
+func TestRefreshRejectsUnknownAndExpired(t *testing.T) {
+	ctx := context.Background()
+	s := newTestSessions(t)
+	if _, err := s.refresh(ctx, "made-up"); !errors.Is(err, errRefreshInvalid) {
+		t.Fatalf("unknown token: %v, want errRefreshInvalid", err)
+	}
+
+	s.refreshTTL = -time.Second
+	login, err := s.issue(ctx, 1, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.refresh(ctx, refreshToken(t, login)); !errors.Is(err, errRefreshInvalid) {
+		t.Fatalf("expired token: %v, want errRefreshInvalid", err)
+	}
+}
+
+func TestRefreshConcurrentUseSucceedsOnce(t *testing.T) {
+	ctx := context.Background()
+	s := newTestSessions(t)
+	login, err := s.issue(ctx, 1, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw := refreshToken(t, login)
+
+	const racers = 10
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded := 0
+	for range racers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := s.refresh(ctx, raw); err == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if succeeded != 1 {
+		t.Fatalf("%d concurrent refreshes succeeded, want 1", succeeded)
+	}
+}
+
+func TestRevokeAccess(t *testing.T) {
+	ctx := context.Background()
+	s := newTestSessions(t)
+	if err := s.revokeAccess(ctx, "jti-1", time.Now().Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.revoked.Get(ctx, middleware.RevokedTokenKey("jti-1")); err != nil {
+		t.Fatalf("revoked token not denylisted: %v", err)
+	}
+
+	// Already expired tokens need no entry
+	if err := s.revokeAccess(ctx, "jti-2", time.Now().Add(-time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.revoked.Get(ctx, middleware.RevokedTokenKey("jti-2")); !errors.Is(err, store.ErrCacheMiss) {
+		t.Fatalf("expired token denylisted: %v", err)
+	}
+}
+
+func TestRefreshRequiresEnrollmentWhen2FAIsRequired(t *testing.T) {
+	ctx := context.Background()
+	s := newTestSessions(t)
+	login, err := s.issue(ctx, 1, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	next, err := s.refresh(ctx, refreshToken(t, login))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A session from before 2FA became required cannot be carried on...
+	if err := s.users.(*store.MemoryStore).SetMFARequired(ctx, 1, true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.refresh(ctx, refreshToken(t, next)); !errors.Is(err, errRefreshMFA) {
+		t.Fatalf("refresh while 2FA is required: %v, want errRefreshMFA", err)
+	}
+
+	// ...and neither can any other session under REQUIRE_2FA
+	s.mfa.required = true
+	if err := s.users.(*store.MemoryStore).SetMFARequired(ctx, 1, false); err != nil {
+		t.Fatal(err)
+	}
+	other, err := s.issue(ctx, 1, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.refresh(ctx, refreshToken(t, other)); !errors.Is(err, errRefreshMFA) {
+		t.Fatalf("refresh under REQUIRE_2FA: %v, want errRefreshMFA", err)
+	}
+}
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/ConstantineCTF/URLSecure/backend/internal/model"
+	"github.com/ConstantineCTF/URLSecure/backend/internal/store"
+	"github.com/gin-gonic/gin"
+)
+
+// settingsJSON is the API representation of a user's account settings.
+func settingsJSON(user *model.User, plan *model.Plan) gin.H {
+	return gin.H{
+		"email":                  user.Email,
+		"emailVerified":          user.EmailVerifiedAt != nil,
+		"twoFactorEnabled":       user.TwoFactorEnabled(),
+		"analyticsRetentionDays": user.RetentionDays,                 // Chosen by the user; null follows the plan
+		"planRetentionDays":      plan.AnalyticsRetentionDays,        // Longest the plan allows; null is unlimited
+		"effectiveRetentionDays": plan.Retention(user.RetentionDays), // What the purge job applies
+	}
+}
+
+// loadSettings returns the caller with their plan, writing an error response on failure.
+func loadSettings(c *gin.Context, quotas *quotaService) (*model.User, *model.Plan, bool) {
+	userID := c.GetUint64("userID")
+	user, err := quotas.users.GetUserByID(c.Request.Context(), userID)
+	if err == nil {
+		var plan *model.Plan
+		if plan, err = quotas.quotas.GetPlan(c.Request.Context(), user.Plan); err == nil {
+			return user, plan, true
+		}
+	}
+	c.Error(err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+	return nil, nil, false
+}
+
+// settingsHandler returns the caller's account settings.
+func settingsHandler(quotas *quotaService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, plan, ok := loadSettings(c, quotas)
+		if !ok {
+			return
+		}
+		c.JSON(http.StatusOK, settingsJSON(user, plan))
+	}
+}
+
+// updateSettingsHandler changes the caller's account settings. A retention of null
+// falls back to the plan's; a number must be between 1 and the plan's limit.
+func updateSettingsHandler(quotas *quotaService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, plan, ok := loadSettings(c, quotas)
+		if !ok {
+			return
+		}
+
+		// Decode into raw fields first so "absent" and "null" can be told apart
+		var fields map[string]json.RawMessage
+		if err := c.ShouldBindJSON(&fields); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		raw, ok := fields["analyticsRetentionDays"]
+		if !ok {
+			c.JSON(http.StatusOK, settingsJSON(user, plan))
+			return
+		}
+		var days *int
+		if !bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+			var n int
+			if err := json.Unmarshal(raw, &n); err != nil || n < 1 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "analyticsRetentionDays must be a positive number of days or null"})
+				return
+			}
+			if limit := plan.AnalyticsRetentionDays; limit != nil && n > *limit {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("the %s plan keeps analytics for at most %d days", plan.Name, *limit)})
+				return
+			}
+			days = &n
+		}
+
+		if err := quotas.users.SetUserRetention(c.Request.Context(), user.ID, days); err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+				return
+			}
+			c.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+		user.RetentionDays = days
+		c.JSON(http.StatusOK, settingsJSON(user, plan))
+	}
+}
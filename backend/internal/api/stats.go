@@ -0,0 +1,125 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ConstantineCTF/URLSecure/backend/internal/model"
+	"github.com/ConstantineCTF/URLSecure/backend/internal/store"
+	"github.com/ConstantineCTF/URLSecure/backend/internal/uniques"
+	"github.com/gin-gonic/gin"
+)
+
+// Bounds for GET /api/stats/:code
+const (
+	defaultStatsRange = 30 * 24 * time.Hour
+	maxStatsBuckets   = 1000 // e.g. 41 days of hours or 2.7 years of days
+	defaultStatsTop   = 10
+	maxStatsTop       = 100
+)
+
+// statsHandler returns the analytics of one of the caller's links: lifetime counters
+// plus, for a time range, a click series and top referrers, countries, devices,
+// browsers and systems. Query parameters: from and to (RFC 3339 or YYYY-MM-DD, default
+// the last 30 days), bucket (hour|day|week, default day) and top (entries per breakdown).
+// Range figures come from the rollups, so they trail live traffic by the rollup interval.
+// Unique visitors are counted per UTC day over every day the range touches.
+func statsHandler(links store.LinkStore, clicks store.ClickStore, visitors uniques.Counter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		link, ok := ownedLink(c, links)
+		if !ok {
+			return
+		}
+
+		q, err := parseStatsQuery(c, time.Now())
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		q.LinkID = link.ID
+
+		stats, err := clicks.ClickStats(c.Request.Context(), q)
+		if err != nil {
+			c.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+		stats.UniqueVisitors, err = visitors.Count(c.Request.Context(), link.ID, q.From, q.To.Add(-time.Nanosecond))
+		if err != nil {
+			c.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "could not count unique visitors"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"code":      link.Code,
+			"clicks":    link.Clicks,
+			"botClicks": link.BotClicks,
+			"createdAt": link.CreatedAt,
+			"expiresAt": link.ExpiresAt,
+			"maxClicks": link.MaxClicks,
+			"status":    link.Status,
+			"from":      q.From,
+			"to":        q.To,
+			"bucket":    q.Bucket,
+			"period":    stats,
+		})
+	}
+}
+
+// parseStatsQuery reads and validates the range, bucket and top parameters.
+func parseStatsQuery(c *gin.Context, now time.Time) (store.StatsQuery, error) {
+	q := store.StatsQuery{
+		To:     now.UTC(),
+		Bucket: c.DefaultQuery("bucket", model.BucketDay),
+		Top:    defaultStatsTop,
+	}
+
+	var err error
+	if raw := c.Query("to"); raw != "" {
+		if q.To, err = parseStatsTime(raw); err != nil {
+			return q, errors.New("to must be an RFC 3339 time or YYYY-MM-DD date")
+		}
+	}
+	q.From = q.To.Add(-defaultStatsRange)
+	if raw := c.Query("from"); raw != "" {
+		if q.From, err = parseStatsTime(raw); err != nil {
+			return q, errors.New("from must be an RFC 3339 time or YYYY-MM-DD date")
+		}
+	}
+	if !q.From.Before(q.To) {
+		return q, errors.New("from must be before to")
+	}
+
+	var bucketSize time.Duration
+	switch q.Bucket {
+	case model.BucketHour:
+		bucketSize = time.Hour
+	case model.BucketDay:
+		bucketSize = 24 * time.Hour
+	case model.BucketWeek:
+		bucketSize = 7 * 24 * time.Hour
+	default:
+		return q, errors.New("bucket must be hour, day or week")
+	}
+	if q.To.Sub(q.From)/bucketSize >= maxStatsBuckets {
+		return q, errors.New("range has too many buckets; use a larger bucket or a shorter range")
+	}
+
+	if raw := c.Query("top"); raw != "" {
+		if q.Top, err = strconv.Atoi(raw); err != nil || q.Top < 1 || q.Top > maxStatsTop {
+			return q, errors.New("top must be between 1 and " + strconv.Itoa(maxStatsTop))
+		}
+	}
+	return q, nil
+}
+
+// parseStatsTime accepts a full RFC 3339 time or a bare date (midnight UTC).
+func parseStatsTime(raw string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t.UTC(), nil
+	}
+	return time.Parse(time.DateOnly, raw)
+}
@@ -1,17 +1,31 @@
 package middleware
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
 	"strings"
+	"time"
 
+	"github.com/ConstantineCTF/URLSecure/backend/internal/model"
+	"github.com/ConstantineCTF/URLSecure/backend/internal/store"   // Revoked token cache and API keys
 	authpkg "github.com/ConstantineCTF/URLSecure/backend/pkg/auth" // Auth utilities (JWT parsing)
 	"github.com/gin-gonic/gin"
 )
 
-// AuthMiddleware enforces JWT authentication on protected routes
-// It validates the Authorization header and extracts the user ID from the token,
-// then sets userID in Gin's context for handlers to use.
-func AuthMiddleware() gin.HandlerFunc {
+// RevokedTokenKey is the cache key marking the access token with ID jti as revoked.
+func RevokedTokenKey(jti string) string {
+	return "revoked:jti:" + jti
+}
+
+// AuthMiddleware enforces authentication on protected routes.
+// It accepts a Bearer JWT or a personal API key in the Authorization header and
+// sets userID in Gin's context for handlers to use. Requests made with an API key
+// also carry the key as "apiKey", for RequireScope. JWTs whose ID is in the revoked
+// cache (see RevokedTokenKey) are rejected.
+func AuthMiddleware(signer *authpkg.KeyManager, revoked store.Cache, keys store.APIKeyStore) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 
@@ -21,20 +35,100 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		// Extract the JWT token string after "Bearer "
+		// Extract the token string after "Bearer "
 		tokenStr := strings.TrimPrefix(authHeader, "Bearer ")
 
-		// Parse and validate the JWT token, obtain claims containing user ID
-		claims, err := authpkg.ParseJWT(tokenStr)
-		if err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+		if strings.HasPrefix(tokenStr, authpkg.APIKeyPrefix) {
+			authenticateAPIKey(c, keys, tokenStr)
+		} else {
+			authenticateJWT(c, signer, revoked, tokenStr)
+		}
+		if c.IsAborted() {
 			return
 		}
 
-		// Set the extracted user ID in the request context for downstream handlers
-		c.Set("userID", claims.UserID)
-
 		// Continue processing request
 		c.Next()
 	}
 }
+
+// authenticateJWT validates an access token and stores its user and ID in the context.
+func authenticateJWT(c *gin.Context, signer *authpkg.KeyManager, revoked store.Cache, tokenStr string) {
+	// Parse and validate the JWT token, obtain claims containing user ID
+	claims, err := signer.ParseJWT(tokenStr)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+		return
+	}
+
+	// Reject tokens revoked by logout. Unlike rate limiting this fails closed: if the
+	// denylist cannot be read, a revoked token must not get through.
+	if claims.ID != "" {
+		_, err := revoked.Get(c.Request.Context(), RevokedTokenKey(claims.ID))
+		switch {
+		case err == nil:
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "token revoked"})
+			return
+		case !errors.Is(err, store.ErrCacheMiss):
+			log.Printf("token denylist error: %v", err)
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "authentication unavailable"})
+			return
+		}
+	}
+
+	// Set the extracted user ID and token in the request context for downstream handlers
+	c.Set("userID", claims.UserID)
+	c.Set("tokenID", claims.ID)
+	if claims.ExpiresAt != nil {
+		c.Set("tokenExpiresAt", claims.ExpiresAt.Time)
+	}
+}
+
+// authenticateAPIKey looks up a personal API key and stores it and its owner in the context.
+func authenticateAPIKey(c *gin.Context, keys store.APIKeyStore, raw string) {
+	now := time.Now()
+	key, err := keys.GetAPIKeyByHash(c.Request.Context(), authpkg.HashAPIKey(raw))
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		log.Printf("API key lookup error: %v", err)
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "authentication unavailable"})
+		return
+	}
+	if err != nil || !key.Active(now) {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid API key"})
+		return
+	}
+
+	// Record the use in the background; a lost timestamp is not worth failing the request
+	go func() {
+		if err := keys.TouchAPIKey(context.Background(), key.ID, now); err != nil {
+			log.Printf("API key %d last-used update failed: %v", key.ID, err)
+		}
+	}()
+
+	c.Set("userID", key.UserID)
+	c.Set("apiKey", key)
+}
+
+// RequireScope lets requests made with an API key through only if the key carries
+// scope. Requests authenticated with a JWT act as the user and may do anything.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if key, ok := c.Get("apiKey"); ok && !key.(*model.APIKey).Allows(scope) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("API key lacks the %s scope", scope)})
+			return
+		}
+		c.Next()
+	}
+}
+
+// SessionOnly rejects requests made with an API key, for account management routes
+// (e.g. creating more keys) that need a logged-in user.
+func SessionOnly() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := c.Get("apiKey"); ok {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "not available to API keys; log in instead"})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,124 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ConstantineCTF/URLSecure/backend/internal/model"
+	"github.com/ConstantineCTF/URLSecure/backend/internal/store"
+	authpkg "github.com/ConstantineCTF/URLSecure/backend/pkg/auth"
+	"github.com/gin-gonic/gin"
+)
+
+// downCache fails every read, like an unreachable Redis.
+type downCache struct{ store.Cache }
+
+func (downCache) Get(context.Context, string) (string, error) { return "", errors.New("cache down") }
+
+// newAuthRouter protects /read, /write and /account the way the API routes do.
+func newAuthRouter(signer *authpkg.KeyManager, revoked store.Cache, keys store.APIKeyStore) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	ok := func(c *gin.Context) { c.Status(http.StatusNoContent) }
+	api := r.Group("/", AuthMiddleware(signer, revoked, keys))
+	api.GET("/read", RequireScope(model.ScopeLinksRead), ok)
+	api.GET("/write", RequireScope(model.ScopeLinksWrite), ok)
+	api.GET("/account", SessionOnly(), ok)
+	return r
+}
+
+// get requests path with the bearer credential and returns the status.
+func get(r http.Handler, path, credential string) int {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if credential != "" {
+		req.Header.Set("Authorization", "Bearer "+credential)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w.Code
+}
+
+// createKey stores an API key for user 1 and returns the raw key.
+func createKey(t *testing.T, st *store.MemoryStore, expiresAt *time.Time, scopes ...string) string {
+	t.Helper()
+	raw, prefix, hash := authpkg.NewAPIKey()
+	key := &model.APIKey{UserID: 1, Name: "test", Prefix: prefix, KeyHash: hash, Scopes: scopes, ExpiresAt: expiresAt}
+	if err := st.CreateAPIKey(context.Background(), key); err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func TestAuthMiddlewareScopes(t *testing.T) {
+	signer, err := authpkg.NewKeyManager(authpkg.KeyConfig{Secret: "test secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := store.NewMemoryStore()
+	cache := store.NewMemoryCache()
+	r := newAuthRouter(signer, cache, st)
+
+	jwt, err := signer.CreateJWT(1, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	revokedJWT, _ := signer.CreateJWT(1, time.Minute)
+	claims, _ := signer.ParseJWT(revokedJWT)
+	cache.Set(context.Background(), RevokedTokenKey(claims.ID), "1", time.Minute)
+
+	past := time.Now().Add(-time.Minute)
+	readKey := createKey(t, st, nil, model.ScopeLinksRead)
+	writeKey := createKey(t, st, nil, model.ScopeLinksWrite)
+	expiredKey := createKey(t, st, &past, model.ScopeLinksRead)
+	revokedKey := createKey(t, st, nil, model.ScopeLinksRead)
+	keys, _ := st.ListAPIKeys(context.Background(), 1)
+	for _, key := range keys {
+		if key.KeyHash == authpkg.HashAPIKey(revokedKey) {
+			st.RevokeAPIKey(context.Background(), 1, key.ID, time.Now())
+		}
+	}
+
+	tests := []struct {
+		name       string
+		credential string
+		read       int
+		write      int
+		account    int
+	}{
+		{"session", jwt, 204, 204, 204},
+		{"read key", readKey, 204, 403, 403},
+		{"write key", writeKey, 403, 204, 403},
+		{"expired key", expiredKey, 401, 401, 401},
+		{"revoked key", revokedKey, 401, 401, 401},
+		{"unknown key", authpkg.APIKeyPrefix + "0000", 401, 401, 401},
+		{"revoked session", revokedJWT, 401, 401, 401},
+		{"garbage", "not-a-token", 401, 401, 401},
+		{"none", "", 401, 401, 401},
+	}
+	for _, tt := range tests {
+		for path, want := range map[string]int{"/read": tt.read, "/write": tt.write, "/account": tt.account} {
+			if got := get(r, path, tt.credential); got != want {
+				t.Errorf("%s on %s: status %d, want %d", tt.name, path, got, want)
+			}
+		}
+	}
+}
+
+func TestAuthMiddlewareFailsClosedWithoutDenylist(t *testing.T) {
+	signer, err := authpkg.NewKeyManager(authpkg.KeyConfig{Secret: "test secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := newAuthRouter(signer, downCache{}, store.NewMemoryStore())
+	jwt, err := signer.CreateJWT(1, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := get(r, "/read", jwt); got != http.StatusServiceUnavailable {
+		t.Fatalf("status %d, want %d", got, http.StatusServiceUnavailable)
+	}
+}
@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Logger is gin's request logger with the client IP passed through anonymize first,
+// so access logs keep no more of an address than the analytics do.
+func Logger(anonymize func(ip string) string) gin.HandlerFunc {
+	return gin.LoggerWithFormatter(func(p gin.LogFormatterParams) string {
+		latency := p.Latency
+		if latency > time.Minute {
+			latency = latency.Truncate(time.Second)
+		}
+		return fmt.Sprintf("[GIN] %v | %3d | %13v | %15s | %-7s %#v\n%s",
+			p.TimeStamp.Format("2006/01/02 - 15:04:05"),
+			p.StatusCode,
+			latency,
+			anonymize(p.ClientIP),
+			p.Method,
+			p.Path,
+			p.ErrorMessage,
+		)
+	})
+}
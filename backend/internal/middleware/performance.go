@@ -1,52 +1,51 @@
 package middleware
 
 import (
-	"sync"
-	"time"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
 
+	"github.com/ConstantineCTF/URLSecure/backend/internal/ratelimit" // In-memory and Redis limiters
 	"github.com/gin-gonic/gin"
-	"golang.org/x/time/rate"
 )
 
-var (
-	// Map to hold rate limiters per IP address
-	visitors = make(map[string]*rate.Limiter)
-	// Mutex to synchronize access to the visitors map
-	mu sync.Mutex
-)
-
-// getVisitor returns the rate limiter for the given IP, creating one if not existing
-func getVisitor(ip string) *rate.Limiter {
-	mu.Lock()
-	defer mu.Unlock()
-
-	limiter, exists := visitors[ip]
-	if !exists {
-		// Create a new rate limiter allowing 20 requests per second with bursts allowed
-		limiter = rate.NewLimiter(rate.Every(time.Second), 20)
-		visitors[ip] = limiter
+// RateLimitMiddleware applies limit per client IP to the routes of one group. scope
+// keeps the quotas of different groups apart. Every response carries the RateLimit-*
+// headers; rejected requests get 429 with Retry-After. If the limiter itself fails
+// (e.g. Redis is down) the request is let through rather than taking the API down.
+func RateLimitMiddleware(limiter ratelimit.Limiter, scope string, limit ratelimit.Limit) gin.HandlerFunc {
+	if !limit.Enabled() {
+		return func(c *gin.Context) { c.Next() }
 	}
-	return limiter
-}
+	policy := fmt.Sprintf("%d;w=%d", limit.Requests, int(limit.Window.Seconds()))
 
-// RateLimitMiddleware applies rate limiting per IP address to incoming requests.
-// It aborts with HTTP 429 if the IP exceeds the allowed rate.
-func RateLimitMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		ip := c.ClientIP()
 
 		// Reject requests with invalid IP addresses
 		if ip == "" {
-			c.AbortWithStatusJSON(400, gin.H{"error": "invalid IP"})
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid IP"})
+			return
+		}
+
+		res, err := limiter.Allow(c.Request.Context(), scope+":"+ip, limit)
+		if err != nil {
+			log.Printf("rate limiter error for %s: %v", scope, err)
+			c.Next()
 			return
 		}
 
-		limiter := getVisitor(ip)
+		// Draft IETF RateLimit header fields
+		c.Header("RateLimit-Policy", policy)
+		c.Header("RateLimit-Limit", strconv.Itoa(res.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(ratelimit.HeaderSeconds(res.Reset)))
 
 		// If request exceeds limiter allowance, respond with rate limit error
-		if !limiter.Allow() {
-			c.JSON(429, gin.H{"error": "rate limit exceeded"})
-			c.Abort()
+		if !res.Allowed {
+			c.Header("Retry-After", strconv.Itoa(ratelimit.HeaderSeconds(res.RetryAfter)))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "rate limit exceeded"})
 			return
 		}
 
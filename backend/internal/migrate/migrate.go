@@ -0,0 +1,324 @@
+// Package migrate applies the embedded SQL migrations and records applied
+// versions in a schema_migrations table.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ConstantineCTF/URLSecure/backend/migrations" // Embedded migration files
+)
+
+// lockName identifies the advisory lock that keeps concurrent replicas from migrating at once.
+const lockName = "urlsecure_migrate"
+
+// Migration is a single numbered schema change.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string // SQL applied by "migrate up"
+	Down    string // SQL applied by "migrate down"; empty if irreversible
+}
+
+// Status describes whether a migration has been applied.
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Migrator applies migrations for one driver (mysql, postgres or sqlite).
+type Migrator struct {
+	db         *sql.DB
+	driver     string
+	migrations []Migration // Sorted by version
+}
+
+// New loads the embedded migrations for driver.
+func New(db *sql.DB, driver string) (*Migrator, error) {
+	list, err := load(migrations.FS, driver)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, fmt.Errorf("no migrations found for driver %q", driver)
+	}
+	return &Migrator{db: db, driver: driver, migrations: list}, nil
+}
+
+// load parses NNN_name.up.sql / NNN_name.down.sql pairs from dir in fsys.
+func load(fsys fs.FS, dir string) ([]Migration, error) {
+	files, err := fs.Glob(fsys, dir+"/*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, file := range files {
+		base := path.Base(file)
+
+		// Split "004_add_expiry.up.sql" into version, name and direction
+		var direction string
+		switch {
+		case strings.HasSuffix(base, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(base, ".down.sql"):
+			direction = "down"
+		default:
+			return nil, fmt.Errorf("%s: expected .up.sql or .down.sql suffix", file)
+		}
+		stem := strings.TrimSuffix(base, "."+direction+".sql")
+
+		prefix, name, ok := strings.Cut(stem, "_")
+		if !ok {
+			return nil, fmt.Errorf("%s: expected NNN_name prefix", file)
+		}
+		version, err := strconv.Atoi(prefix)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid version: %w", file, err)
+		}
+
+		body, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+
+		m, exists := byVersion[version]
+		if !exists {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		} else if m.Name != name {
+			return nil, fmt.Errorf("%s: version %d already used by %q", file, version, m.Name)
+		}
+		if direction == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	list := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %03d_%s has no up file", m.Version, m.Name)
+		}
+		list = append(list, *m)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+	return list, nil
+}
+
+// Up applies every pending migration in order and returns the ones applied.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		done, err := m.appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, mig := range m.migrations {
+			if _, ok := done[mig.Version]; ok {
+				continue
+			}
+			if err := m.run(ctx, conn, mig.Up,
+				"INSERT INTO schema_migrations (version, name) VALUES ("+m.placeholders(2)+")",
+				mig.Version, mig.Name,
+			); err != nil {
+				return fmt.Errorf("apply %03d_%s: %w", mig.Version, mig.Name, err)
+			}
+			applied = append(applied, mig)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down reverts the n most recently applied migrations and returns the ones reverted.
+func (m *Migrator) Down(ctx context.Context, n int) ([]Migration, error) {
+	var reverted []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		done, err := m.appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && len(reverted) < n; i-- {
+			mig := m.migrations[i]
+			if _, ok := done[mig.Version]; !ok {
+				continue
+			}
+			if mig.Down == "" {
+				return fmt.Errorf("migration %03d_%s has no down file", mig.Version, mig.Name)
+			}
+			if err := m.run(ctx, conn, mig.Down,
+				"DELETE FROM schema_migrations WHERE version = "+m.placeholders(1),
+				mig.Version,
+			); err != nil {
+				return fmt.Errorf("revert %03d_%s: %w", mig.Version, mig.Name, err)
+			}
+			reverted = append(reverted, mig)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Status lists every known migration and whether it has been applied.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	done, err := m.appliedVersions(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		appliedAt, ok := done[mig.Version]
+		statuses = append(statuses, Status{Migration: mig, Applied: ok, AppliedAt: appliedAt})
+	}
+	return statuses, nil
+}
+
+// run executes a migration script plus its bookkeeping statement in one transaction.
+// MySQL commits DDL implicitly, so there the transaction only covers the bookkeeping.
+func (m *Migrator) run(ctx context.Context, conn *sql.Conn, script, record string, args ...any) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, stmt := range splitStatements(script) {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// appliedVersions creates schema_migrations if needed and returns applied versions with their timestamps.
+func (m *Migrator) appliedVersions(ctx context.Context, conn *sql.Conn) (map[int]time.Time, error) {
+	if _, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+  version BIGINT NOT NULL PRIMARY KEY,
+  name VARCHAR(255) NOT NULL,
+  applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+)`); err != nil {
+		return nil, fmt.Errorf("create schema_migrations: %w", err)
+	}
+
+	rows, err := conn.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	done := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		done[version] = appliedAt
+	}
+	return done, rows.Err()
+}
+
+// withLock runs fn on a dedicated connection holding the driver's advisory lock,
+// so replicas started with auto-migrate do not race each other.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	switch m.driver {
+	case "mysql":
+		var got sql.NullInt64
+		if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, 60)", lockName).Scan(&got); err != nil {
+			return err
+		}
+		if got.Int64 != 1 {
+			return fmt.Errorf("timed out waiting for migration lock")
+		}
+		defer conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK(?)", lockName)
+	case "postgres":
+		if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock(hashtext($1))", lockName); err != nil {
+			return err
+		}
+		defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock(hashtext($1))", lockName)
+	}
+	// SQLite serialises writers itself and is only ever used by a single process
+
+	return fn(conn)
+}
+
+// placeholders returns n comma-separated bind parameters in the driver's syntax.
+func (m *Migrator) placeholders(n int) string {
+	params := make([]string, n)
+	for i := range params {
+		if m.driver == "postgres" {
+			params[i] = "$" + strconv.Itoa(i+1)
+		} else {
+			params[i] = "?"
+		}
+	}
+	return strings.Join(params, ", ")
+}
+
+// splitStatements breaks a script into statements at semicolons that end a line.
+// The MySQL driver rejects multi-statement Exec calls, and the migration files
+// never put a semicolon at the end of a line inside a string literal.
+func splitStatements(script string) []string {
+	var stmts []string
+	var current strings.Builder
+
+	flush := func() {
+		stmt := strings.TrimSpace(current.String())
+		current.Reset()
+		if stmt != "" && !onlyComments(stmt) {
+			stmts = append(stmts, stmt)
+		}
+	}
+
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasSuffix(trimmed, ";") {
+			current.WriteString(strings.TrimSuffix(trimmed, ";"))
+			flush()
+			continue
+		}
+		current.WriteString(line)
+		current.WriteByte('\n')
+	}
+	flush()
+	return stmts
+}
+
+// onlyComments reports whether every line of stmt is a -- comment.
+func onlyComments(stmt string) bool {
+	for _, line := range strings.Split(stmt, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "--") {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,182 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	_ "modernc.org/sqlite" // Registers the "sqlite" driver
+)
+
+// newSQLite opens an empty SQLite database in a temp dir.
+func newSQLite(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", "file:"+filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestLoad(t *testing.T) {
+	file := func(body string) *fstest.MapFile { return &fstest.MapFile{Data: []byte(body)} }
+
+	tests := []struct {
+		name     string
+		files    fstest.MapFS
+		versions []int
+		err      string
+	}{
+		{
+			name: "sorted by version",
+			files: fstest.MapFS{
+				"x/010_b.up.sql":   file("B"),
+				"x/002_a.up.sql":   file("A"),
+				"x/002_a.down.sql": file("-A"),
+			},
+			versions: []int{2, 10},
+		},
+		{
+			name:  "bad suffix",
+			files: fstest.MapFS{"x/001_a.sql": file("A")},
+			err:   "expected .up.sql or .down.sql suffix",
+		},
+		{
+			name:  "missing name",
+			files: fstest.MapFS{"x/001.up.sql": file("A")},
+			err:   "expected NNN_name prefix",
+		},
+		{
+			name:  "bad version",
+			files: fstest.MapFS{"x/one_a.up.sql": file("A")},
+			err:   "invalid version",
+		},
+		{
+			name: "version reused",
+			files: fstest.MapFS{
+				"x/001_a.up.sql": file("A"),
+				"x/001_b.up.sql": file("B"),
+			},
+			err: "version 1 already used",
+		},
+		{
+			name:  "down without up",
+			files: fstest.MapFS{"x/001_a.down.sql": file("-A")},
+			err:   "has no up file",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list, err := load(tt.files, "x")
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("err = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var versions []int
+			for _, m := range list {
+				versions = append(versions, m.Version)
+			}
+			if !reflect.DeepEqual(versions, tt.versions) {
+				t.Errorf("versions = %v, want %v", versions, tt.versions)
+			}
+		})
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   []string
+	}{
+		{"single", "CREATE TABLE a (id INT);", []string{"CREATE TABLE a (id INT)"}},
+		{"multi-line", "CREATE TABLE a (\n  id INT\n);\nDROP TABLE b;", []string{"CREATE TABLE a (\n  id INT\n)", "DROP TABLE b"}},
+		{"no trailing semicolon", "DROP TABLE a", []string{"DROP TABLE a"}},
+		{"comments dropped", "-- header\n;\nDROP TABLE a;\n-- trailer", []string{"DROP TABLE a"}},
+		{"inline semicolon kept", "INSERT INTO a VALUES ('x;y');", []string{"INSERT INTO a VALUES ('x;y')"}},
+		{"empty", "\n\n", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := splitStatements(tt.script); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitStatements(%q) = %q, want %q", tt.script, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpDownStatus(t *testing.T) {
+	ctx := context.Background()
+	db := newSQLite(t)
+	m, err := New(db, "sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	total := len(m.migrations)
+
+	applied, err := m.Up(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != total {
+		t.Fatalf("Up applied %d migrations, want %d", len(applied), total)
+	}
+	if again, err := m.Up(ctx); err != nil || len(again) != 0 {
+		t.Fatalf("second Up applied %d migrations (err %v), want none", len(again), err)
+	}
+
+	reverted, err := m.Down(ctx, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reverted) != 2 || reverted[0].Version != m.migrations[total-1].Version || reverted[1].Version != m.migrations[total-2].Version {
+		t.Fatalf("Down reverted %+v, want the last two migrations newest first", reverted)
+	}
+
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != total {
+		t.Fatalf("Status listed %d migrations, want %d", len(statuses), total)
+	}
+	for i, s := range statuses {
+		if want := i < total-2; s.Applied != want {
+			t.Errorf("migration %03d_%s applied = %v, want %v", s.Version, s.Name, s.Applied, want)
+		}
+	}
+
+	// Reverting everything leaves only the bookkeeping tables behind
+	if _, err := m.Down(ctx, total); err != nil {
+		t.Fatal(err)
+	}
+	var tables sql.NullString
+	if err := db.QueryRow("SELECT group_concat(name) FROM sqlite_master WHERE type = 'table' AND name NOT IN ('schema_migrations', 'sqlite_sequence')").Scan(&tables); err != nil {
+		t.Fatal(err)
+	}
+	if tables.Valid {
+		t.Errorf("tables left after reverting every migration: %s", tables.String)
+	}
+
+	// Re-applying proves the down scripts restore a clean slate
+	if applied, err := m.Up(ctx); err != nil || len(applied) != total {
+		t.Fatalf("Up after full Down applied %d migrations (err %v), want %d", len(applied), err, total)
+	}
+}
+
+func TestNewUnknownDriver(t *testing.T) {
+	if _, err := New(nil, "oracle"); err == nil {
+		t.Fatal("New accepted a driver with no migrations")
+	}
+}
@@ -0,0 +1,41 @@
+package model
+
+import (
+	"slices"
+	"time"
+)
+
+// API key scopes: what a key may do on its owner's behalf.
+const (
+	ScopeLinksRead  = "links:read"  // List and read links, see quota
+	ScopeLinksWrite = "links:write" // Create, update and delete links
+	ScopeStatsRead  = "stats:read"  // Read link analytics
+)
+
+// Scopes lists every valid scope.
+var Scopes = []string{ScopeLinksRead, ScopeLinksWrite, ScopeStatsRead}
+
+// APIKey is a personal key for programmatic access. Only its hash is stored; the
+// key itself is shown once when created.
+type APIKey struct {
+	ID         uint64     `db:"id"`           // Primary key
+	UserID     uint64     `db:"user_id"`      // Owner the key acts as
+	Name       string     `db:"name"`         // Label chosen by the owner, e.g. "CI"
+	Prefix     string     `db:"prefix"`       // First characters of the key, to tell keys apart
+	KeyHash    string     `db:"key_hash"`     // SHA-256 of the key, hex encoded
+	Scopes     []string   `db:"scopes"`       // Granted scopes, stored space-separated
+	CreatedAt  time.Time  `db:"created_at"`   // When the key was created
+	LastUsedAt *time.Time `db:"last_used_at"` // Last authenticated request (to the minute); nil if never used
+	ExpiresAt  *time.Time `db:"expires_at"`   // Optional expiry
+	RevokedAt  *time.Time `db:"revoked_at"`   // When the owner revoked it
+}
+
+// Active reports whether the key may still be used at now.
+func (k *APIKey) Active(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
+
+// Allows reports whether the key carries scope.
+func (k *APIKey) Allows(scope string) bool {
+	return slices.Contains(k.Scopes, scope)
+}
@@ -0,0 +1,26 @@
+package model
+
+import "time"
+
+// ClickEvent is one visit to a short link, as recorded for analytics.
+type ClickEvent struct {
+	ID           uint64    `db:"id"`            // Primary key
+	LinkID       uint64    `db:"link_id"`       // Link visited; filled in by the store from Code
+	Code         string    `db:"-"`             // Short code visited, resolved to LinkID when stored
+	ClickedAt    time.Time `db:"clicked_at"`    // Time of the visit (UTC)
+	ReferrerHost string    `db:"referrer_host"` // Host of the Referer header; empty for direct visits
+	Browser      string    `db:"browser"`       // e.g. Chrome, Safari; empty if no User-Agent
+	OS           string    `db:"os"`            // e.g. Windows, iOS
+	Device       string    `db:"device"`        // desktop, mobile or tablet
+	Language     string    `db:"language"`      // Preferred language from Accept-Language, e.g. en-US
+	Country      string    `db:"country"`       // ISO 3166-1 alpha-2 code from the GeoIP database; empty if unknown
+	Bot          bool      `db:"bot"`           // Visit came from a crawler, unfurler or script
+}
+
+// ClickCount is a number of clicks on a link within one hour, recorded without any
+// detail about the visitors (e.g. because they asked not to be tracked).
+type ClickCount struct {
+	Code   string    // Short code visited
+	Hour   time.Time // Start of the hour (UTC)
+	Clicks int
+}
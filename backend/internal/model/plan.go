@@ -0,0 +1,31 @@
+package model
+
+// DefaultPlan is the plan assigned to newly registered users.
+const DefaultPlan = "free"
+
+// Plan is a named set of quotas and feature gates. Nil limits are unlimited.
+type Plan struct {
+	Name                   string `db:"name"`                     // Primary key, e.g. "free" or "pro"
+	DailyLinks             *int   `db:"daily_links"`              // Links that may be created per UTC day
+	MonthlyLinks           *int   `db:"monthly_links"`            // Links that may be created per UTC month
+	MaxActiveLinks         *int   `db:"max_active_links"`         // Links that may be live (not expired or used up) at once
+	CustomAliases          bool   `db:"custom_aliases"`           // May choose their own short codes
+	PasswordLinks          bool   `db:"password_links"`           // May password-protect links
+	AnalyticsRetentionDays *int   `db:"analytics_retention_days"` // How long click analytics are kept
+}
+
+// Retention returns how many days raw click events are kept for a user of the plan who
+// chose userDays (nil if they did not): the shorter of the two, nil if both are unlimited.
+func (p *Plan) Retention(userDays *int) *int {
+	if userDays == nil || (p.AnalyticsRetentionDays != nil && *p.AnalyticsRetentionDays < *userDays) {
+		return p.AnalyticsRetentionDays
+	}
+	return userDays
+}
+
+// Usage is how much of its quotas a user has consumed.
+type Usage struct {
+	Daily   int // Links created today (UTC)
+	Monthly int // Links created this month (UTC)
+	Active  int // Links currently live
+}
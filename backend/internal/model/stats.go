@@ -0,0 +1,34 @@
+package model
+
+import "time"
+
+// Granularities of a click series.
+const (
+	BucketHour = "hour"
+	BucketDay  = "day"
+	BucketWeek = "week" // Starting Monday 00:00 UTC
+)
+
+// SeriesPoint is the number of clicks in one bucket of a series.
+type SeriesPoint struct {
+	Start  time.Time `json:"start"`
+	Clicks int       `json:"clicks"`
+}
+
+// Breakdown is the number of clicks with one value of a dimension, e.g. one country.
+type Breakdown struct {
+	Value  string `json:"value"` // Empty for direct visits, unknown countries and so on
+	Clicks int    `json:"clicks"`
+}
+
+// LinkStats is the click analytics of a link over a time range. Bot visits are excluded.
+type LinkStats struct {
+	Clicks         int           `json:"clicks"`         // Clicks in the range
+	UniqueVisitors int           `json:"uniqueVisitors"` // Distinct visitors per day, summed over the days of the range
+	Series         []SeriesPoint `json:"series"`         // One point per bucket, including empty ones
+	Referrers      []Breakdown   `json:"referrers"`      // Top referrer hosts
+	Countries      []Breakdown   `json:"countries"`      // Top countries (ISO codes)
+	Devices        []Breakdown   `json:"devices"`
+	Browsers       []Breakdown   `json:"browsers"`
+	OS             []Breakdown   `json:"os"`
+}
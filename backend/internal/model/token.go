@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// RefreshToken is one issued refresh token. Only its hash is stored. Every refresh
+// rotates the token into a new one of the same family, so a used token presented
+// again means it was stolen and the whole family is revoked.
+type RefreshToken struct {
+	ID        uint64     `db:"id"`         // Primary key
+	UserID    uint64     `db:"user_id"`    // Account the token logs in as
+	FamilyID  string     `db:"family_id"`  // Shared by all tokens rotated from one login
+	TokenHash string     `db:"token_hash"` // SHA-256 of the token, hex encoded
+	CreatedAt time.Time  `db:"created_at"` // When it was issued
+	ExpiresAt time.Time  `db:"expires_at"` // When it stops being accepted
+	UsedAt    *time.Time `db:"used_at"`    // When it was exchanged; nil if still unused
+	RevokedAt *time.Time `db:"revoked_at"` // When it was revoked by logout or reuse detection
+}
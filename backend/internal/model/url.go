@@ -2,12 +2,54 @@ package model
 
 import "time"
 
+// Link review states.
+const (
+	LinkActive        = "active"         // Redirects normally
+	LinkPendingReview = "pending_review" // Held by the phishing heuristics until an operator approves it
+)
+
+// RiskReason is one heuristic that contributed to a link's risk score.
+type RiskReason struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
 // URL represents a shortened URL entry stored in the database.
 type URL struct {
-	ID        int       `db:"id"`                 // Primary key
-	Code      string    `db:"code"`               // The unique short code
-	Target    string    `db:"target"`             // The original (target) URL
-	CreatedAt time.Time `db:"created_at"`         // Timestamp when shortened URL was created
-	ExpiresAt time.Time `db:"expires_at,omitempty"` // Optional expiration time
-	Clicks    int       `db:"clicks"`             // Number of times the link has been clicked
+	ID        uint64     `db:"id"`                   // Primary key
+	UserID    uint64     `db:"user_id"`              // Owner of the link
+	Code      string     `db:"code"`                 // The unique short code
+	Target    string     `db:"target"`               // The original (target) URL
+	CreatedAt time.Time  `db:"created_at"`           // Timestamp when shortened URL was created
+	ExpiresAt *time.Time `db:"expires_at,omitempty"` // Optional expiration time
+	MaxClicks *int       `db:"max_clicks,omitempty"` // Optional number of clicks after which the link stops working
+	Clicks    int        `db:"clicks"`               // Number of times the link has been clicked by people
+	BotClicks int        `db:"bot_clicks"`           // Visits from crawlers, unfurlers and other automated clients
+
+	Status      string       `db:"status"`       // LinkActive or LinkPendingReview
+	RiskScore   int          `db:"risk_score"`   // Phishing heuristics score of the target (0-100)
+	RiskReasons []RiskReason `db:"risk_reasons"` // Heuristics behind RiskScore, stored as JSON
+	Preview     bool         `db:"preview"`      // Always show the interstitial before redirecting
+
+	PasswordHash string `db:"password_hash"` // Bcrypt hash of the access password; empty if none
+}
+
+// Expired reports whether the link's expiry time has passed.
+func (u *URL) Expired(now time.Time) bool {
+	return u.ExpiresAt != nil && !now.Before(*u.ExpiresAt)
+}
+
+// Exhausted reports whether the link has used up its allowed clicks.
+func (u *URL) Exhausted() bool {
+	return u.MaxClicks != nil && u.Clicks >= *u.MaxClicks
+}
+
+// Pending reports whether the link is held for review and must not redirect.
+func (u *URL) Pending() bool {
+	return u.Status == LinkPendingReview
+}
+
+// Protected reports whether visitors must enter a password before being redirected.
+func (u *URL) Protected() bool {
+	return u.PasswordHash != ""
 }
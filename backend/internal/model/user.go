@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+// User represents a registered account stored in the database.
+type User struct {
+	ID              uint64     `db:"id"`                       // Primary key
+	Username        string     `db:"username"`                 // Unique login name
+	Email           string     `db:"email"`                    // Unique email address
+	EmailVerifiedAt *time.Time `db:"email_verified_at"`        // When the owner proved they receive mail there; nil if not yet
+	PasswordHash    string     `db:"password_hash"`            // Bcrypt hash of the password
+	Plan            string     `db:"plan"`                     // Name of the user's Plan
+	RetentionDays   *int       `db:"analytics_retention_days"` // Own, shorter click event retention; nil uses the plan's
+	TOTPSecret      string     `db:"totp_secret"`              // Base32 TOTP secret, set from enrollment on; empty without 2FA
+	TOTPEnabledAt   *time.Time `db:"totp_enabled_at"`          // When enrollment was confirmed with a code; nil while enrolling
+	TOTPLastStep    int64      `db:"totp_last_step"`           // Time step of the last accepted code, so none is accepted twice
+	MFARequired     bool       `db:"mfa_required"`             // Operator requires 2FA for this account
+	CreatedAt       time.Time  `db:"created_at"`               // Timestamp when the account was created
+}
+
+// TwoFactorEnabled reports whether logging in needs a TOTP or recovery code.
+func (u *User) TwoFactorEnabled() bool {
+	return u.TOTPEnabledAt != nil
+}
@@ -0,0 +1,66 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often MemoryLimiter drops idle keys.
+const sweepInterval = time.Minute
+
+// MemoryLimiter is a process-local Limiter. Keys whose quota has fully recovered
+// carry no state, so they are evicted periodically and memory stays bounded by the
+// number of recently active clients.
+type MemoryLimiter struct {
+	mu        sync.Mutex
+	tats      map[string]time.Time // Theoretical arrival time per key
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewMemoryLimiter returns an empty in-memory limiter.
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{tats: make(map[string]time.Time), now: time.Now}
+}
+
+// Allow implements Limiter.
+func (m *MemoryLimiter) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	if now.Sub(m.lastSweep) > sweepInterval {
+		m.sweep(now)
+	}
+
+	emission := limit.emission()
+	tat := m.tats[key]
+	if tat.Before(now) {
+		tat = now
+	}
+	newTat := tat.Add(emission)
+	allowAt := newTat.Add(-limit.Window)
+
+	if now.Before(allowAt) {
+		return Result{Limit: limit.Requests, Reset: tat.Sub(now), RetryAfter: allowAt.Sub(now)}, nil
+	}
+
+	m.tats[key] = newTat
+	return Result{
+		Allowed:   true,
+		Limit:     limit.Requests,
+		Remaining: int((limit.Window - newTat.Sub(now)) / emission),
+		Reset:     newTat.Sub(now),
+	}, nil
+}
+
+// sweep removes keys that have recovered their full quota; absent keys behave the same.
+func (m *MemoryLimiter) sweep(now time.Time) {
+	for key, tat := range m.tats {
+		if !tat.After(now) {
+			delete(m.tats, key)
+		}
+	}
+	m.lastSweep = now
+}
@@ -0,0 +1,47 @@
+// Package ratelimit implements request rate limiting with the generic cell rate
+// algorithm (GCRA): each key may make Limit.Requests requests per Limit.Window, with
+// capacity returning smoothly rather than in fixed-window steps. MemoryLimiter serves a
+// single process; RedisLimiter shares the limits between replicas.
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Limit is a request quota: Requests per Window, all of which may be used in a burst.
+type Limit struct {
+	Requests int
+	Window   time.Duration
+}
+
+// Enabled reports whether the limit restricts anything.
+func (l Limit) Enabled() bool {
+	return l.Requests > 0 && l.Window > 0
+}
+
+// emission is the time it takes for one request of capacity to come back.
+func (l Limit) emission() time.Duration {
+	return l.Window / time.Duration(l.Requests)
+}
+
+// Result is the outcome of one Allow call.
+type Result struct {
+	Allowed    bool
+	Limit      int           // Requests per window
+	Remaining  int           // Requests still available right now
+	Reset      time.Duration // Until the full quota is available again
+	RetryAfter time.Duration // Until the next request would be allowed; 0 when Allowed
+}
+
+// HeaderSeconds rounds d up to whole seconds for RateLimit-Reset and Retry-After.
+// Rounding down would tell clients to retry before they are allowed to.
+func HeaderSeconds(d time.Duration) int {
+	return int((d + time.Second - 1) / time.Second)
+}
+
+// Limiter decides whether the request identified by key fits within limit and,
+// if so, consumes one unit of it.
+type Limiter interface {
+	Allow(ctx context.Context, key string, limit Limit) (Result, error)
+}
@@ -0,0 +1,122 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestHeaderSeconds(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want int
+	}{
+		{0, 0},
+		{time.Nanosecond, 1},
+		{time.Second, 1},
+		{time.Second + time.Millisecond, 2},
+		{59*time.Second + 999*time.Millisecond, 60},
+	}
+	for _, tt := range tests {
+		if got := HeaderSeconds(tt.d); got != tt.want {
+			t.Errorf("HeaderSeconds(%v) = %d, want %d", tt.d, got, tt.want)
+		}
+	}
+}
+
+// fakeClock is a settable time source for MemoryLimiter.
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+// newTestLimiter returns a MemoryLimiter driven by the returned clock.
+func newTestLimiter() (*MemoryLimiter, *fakeClock) {
+	clock := &fakeClock{t: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	m := NewMemoryLimiter()
+	m.now = clock.now
+	return m, clock
+}
+
+// allow calls m.Allow for key, failing the test on error.
+func allow(t *testing.T, m *MemoryLimiter, key string, limit Limit) Result {
+	t.Helper()
+	res, err := m.Allow(context.Background(), key, limit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+func TestMemoryLimiterBurstThenRefill(t *testing.T) {
+	m, clock := newTestLimiter()
+	limit := Limit{Requests: 5, Window: 10 * time.Second} // One request back every 2s
+
+	// The whole quota may be used at once
+	for i := range 5 {
+		res := allow(t, m, "k", limit)
+		want := Result{Allowed: true, Limit: 5, Remaining: 4 - i, Reset: time.Duration(i+1) * 2 * time.Second}
+		if res != want {
+			t.Fatalf("request %d: %+v, want %+v", i+1, res, want)
+		}
+	}
+	if res := allow(t, m, "k", limit); res != (Result{Limit: 5, Reset: 10 * time.Second, RetryAfter: 2 * time.Second}) {
+		t.Fatalf("over the burst: %+v", res)
+	}
+
+	// Capacity comes back one request per emission interval, not all at once
+	clock.advance(time.Second)
+	if res := allow(t, m, "k", limit); res.Allowed || res.RetryAfter != time.Second {
+		t.Fatalf("after 1s: %+v, want refused with RetryAfter 1s", res)
+	}
+	clock.advance(time.Second)
+	if res := allow(t, m, "k", limit); !res.Allowed || res.Remaining != 0 || res.Reset != 10*time.Second {
+		t.Fatalf("after 2s: %+v, want one request allowed", res)
+	}
+	if res := allow(t, m, "k", limit); res.Allowed {
+		t.Fatalf("second request after 2s: %+v, want refused", res)
+	}
+
+	// After a full window the burst is available again
+	clock.advance(10 * time.Second)
+	if res := allow(t, m, "k", limit); !res.Allowed || res.Remaining != 4 {
+		t.Fatalf("after the window: %+v, want Remaining 4", res)
+	}
+}
+
+func TestMemoryLimiterKeysAreIndependent(t *testing.T) {
+	m, clock := newTestLimiter()
+	limit := Limit{Requests: 1, Window: time.Minute}
+
+	if !allow(t, m, "a", limit).Allowed || allow(t, m, "a", limit).Allowed {
+		t.Fatal("key a: want one request allowed, then refused")
+	}
+	if !allow(t, m, "b", limit).Allowed {
+		t.Fatal("key b was limited by key a")
+	}
+
+	// Recovered keys are swept; they behave like new ones
+	clock.advance(2 * time.Minute)
+	allow(t, m, "c", limit)
+	if _, ok := m.tats["a"]; ok {
+		t.Fatal("recovered key a was not swept")
+	}
+	if !allow(t, m, "a", limit).Allowed {
+		t.Fatal("key a still limited after the window")
+	}
+}
+
+func TestLimitEnabled(t *testing.T) {
+	for _, tt := range []struct {
+		limit Limit
+		want  bool
+	}{
+		{Limit{Requests: 10, Window: time.Minute}, true},
+		{Limit{Requests: 0, Window: time.Minute}, false},
+		{Limit{Requests: 10}, false},
+	} {
+		if got := tt.limit.Enabled(); got != tt.want {
+			t.Errorf("%+v.Enabled() = %t, want %t", tt.limit, got, tt.want)
+		}
+	}
+}
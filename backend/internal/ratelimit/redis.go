@@ -0,0 +1,64 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8" // Redis client library
+)
+
+// gcraScript runs GCRA atomically in Redis. Times are in milliseconds and taken from
+// the Redis server so replicas with skewed clocks still agree.
+// KEYS[1] = key, ARGV[1] = emission interval, ARGV[2] = window.
+// Returns {allowed, remaining, reset, retry_after}.
+var gcraScript = redis.NewScript(`
+if redis.replicate_commands then redis.replicate_commands() end
+local emission = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+
+local tat = tonumber(redis.call('GET', KEYS[1]) or now)
+if tat < now then tat = now end
+local new_tat = tat + emission
+local allow_at = new_tat - window
+
+if now < allow_at then
+  return {0, 0, tat - now, allow_at - now}
+end
+redis.call('SET', KEYS[1], new_tat, 'PX', new_tat - now)
+return {1, math.floor((window - (new_tat - now)) / emission), new_tat - now, 0}
+`)
+
+// RedisLimiter is a Limiter shared by every replica using the same Redis.
+// Keys expire on their own once their quota has recovered.
+type RedisLimiter struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisLimiter stores limiter state under "ratelimit:" keys on client.
+func NewRedisLimiter(client *redis.Client) *RedisLimiter {
+	return &RedisLimiter{client: client, prefix: "ratelimit:"}
+}
+
+// Allow implements Limiter.
+func (r *RedisLimiter) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	emission := max(limit.emission().Milliseconds(), 1)
+	raw, err := gcraScript.Run(ctx, r.client, []string{r.prefix + key}, emission, limit.Window.Milliseconds()).Int64Slice()
+	if err != nil {
+		return Result{}, err
+	}
+	if len(raw) != 4 {
+		return Result{}, fmt.Errorf("ratelimit: unexpected script reply %v", raw)
+	}
+
+	return Result{
+		Allowed:    raw[0] == 1,
+		Limit:      limit.Requests,
+		Remaining:  int(raw[1]),
+		Reset:      time.Duration(raw[2]) * time.Millisecond,
+		RetryAfter: time.Duration(raw[3]) * time.Millisecond,
+	}, nil
+}
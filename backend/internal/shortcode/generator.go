@@ -0,0 +1,115 @@
+// Package shortcode generates short codes for links and allocates them with
+// retries when a candidate is already taken.
+package shortcode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// MaxLength is the longest code that fits in links.code (VARCHAR(16)).
+const MaxLength = 16
+
+// Generator produces candidate short codes. Candidates are not guaranteed to be
+// unused; the Allocator checks them against the store.
+type Generator interface {
+	// Generate returns a candidate code. length is the generator-specific size
+	// (characters for random codes, minimum characters for sequential codes,
+	// number of digits for word codes) and grows as the keyspace fills up.
+	Generate(ctx context.Context, length int) (string, error)
+}
+
+// Sequence hands out increasing numbers shared by all replicas; LinkStore implements it.
+type Sequence interface {
+	NextSequence(ctx context.Context, name string) (uint64, error)
+}
+
+// Options configures NewGenerator.
+type Options struct {
+	Strategy string   // random, sequential or words
+	Salt     string   // Shuffles the sequential alphabet so codes are not guessable
+	Sequence Sequence // Counter source for the sequential strategy
+}
+
+// NewGenerator returns the generator for opts.Strategy.
+func NewGenerator(opts Options) (Generator, error) {
+	switch opts.Strategy {
+	case "", "random":
+		return Random{}, nil
+	case "sequential":
+		if opts.Sequence == nil {
+			return nil, errors.New("sequential code strategy needs a sequence source")
+		}
+		return NewSequential(opts.Sequence, opts.Salt), nil
+	case "words":
+		return Words{}, nil
+	default:
+		return nil, fmt.Errorf("unknown code strategy %q", opts.Strategy)
+	}
+}
+
+// DefaultLength is the starting length for a strategy when none is configured.
+func DefaultLength(strategy string) int {
+	switch strategy {
+	case "sequential":
+		return 5 // 62^5 ≈ 916M codes before growing to 6 characters
+	case "words":
+		return 2 // Two trailing digits: ~200k combinations
+	default:
+		return 6
+	}
+}
+
+// ErrExhausted is returned when no free code was found within the retry budget.
+var ErrExhausted = errors.New("shortcode: could not allocate a free code")
+
+// Allocator draws codes from a Generator until one is accepted by the store.
+// When allocations start needing retries the keyspace is getting crowded, so
+// the length used for future codes is increased.
+type Allocator struct {
+	gen         Generator
+	length      atomic.Int64 // Current length passed to the generator
+	maxLength   int
+	maxAttempts int // Attempts per Allocate call
+	growAfter   int // Collisions within one call that trigger a length increase
+}
+
+// NewAllocator wraps gen, starting at length and never exceeding maxLength.
+func NewAllocator(gen Generator, length, maxLength int) *Allocator {
+	a := &Allocator{gen: gen, maxLength: maxLength, maxAttempts: 8, growAfter: 2}
+	a.length.Store(int64(length))
+	return a
+}
+
+// Allocate generates candidates and passes each to create until create succeeds.
+// create must return isTaken(err) == true for codes that already exist; any
+// other error aborts the allocation.
+func (a *Allocator) Allocate(ctx context.Context, create func(code string) error, isTaken func(error) bool) (string, error) {
+	collisions := 0
+	for attempt := 0; attempt < a.maxAttempts; attempt++ {
+		length := int(a.length.Load())
+
+		code, err := a.gen.Generate(ctx, length)
+		if err != nil {
+			return "", err
+		}
+
+		err = create(code)
+		if err == nil {
+			return code, nil
+		}
+		if !isTaken(err) {
+			return "", err
+		}
+
+		// Repeated collisions mean the current length is crowded; grow it for everyone
+		collisions++
+		if collisions >= a.growAfter && length < a.maxLength {
+			a.length.CompareAndSwap(int64(length), int64(length+1))
+			collisions = 0
+		}
+	}
+	return "", ErrExhausted
+}
@@ -0,0 +1,35 @@
+package shortcode
+
+import (
+	"context"
+	"crypto/rand"
+)
+
+// base62 is the URL-safe alphabet used for random and sequential codes.
+const base62 = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// Random generates uniformly random base62 codes of the requested length.
+type Random struct{}
+
+// Generate returns length random base62 characters.
+func (Random) Generate(ctx context.Context, length int) (string, error) {
+	code := make([]byte, 0, length)
+	buf := make([]byte, length*2)
+
+	for len(code) < length {
+		if _, err := rand.Read(buf); err != nil {
+			return "", err
+		}
+		for _, b := range buf {
+			// Reject bytes >= 248 (4*62) so every character is equally likely
+			if b >= 248 {
+				continue
+			}
+			code = append(code, base62[b%62])
+			if len(code) == length {
+				break
+			}
+		}
+	}
+	return string(code), nil
+}
@@ -0,0 +1,82 @@
+package shortcode
+
+import (
+	"context"
+	"crypto/sha256"
+	"math/big"
+	"math/rand/v2"
+)
+
+// sequenceName is the counter used by the sequential strategy.
+const sequenceName = "link_code"
+
+// multiplier and offset scramble counter values within each length's keyspace.
+// The multiplier is odd and not divisible by 31, so it is coprime with 62^n and
+// x*multiplier+offset mod 62^n is a bijection.
+var (
+	multiplier = big.NewInt(6364136223846793005)
+	offset     = big.NewInt(1442695040888963407)
+)
+
+// Sequential turns a shared counter into short codes, Hashids-style: the counter is
+// scrambled and written with a salt-shuffled alphabet so neighbouring links do not get
+// neighbouring codes. Codes never repeat, and grow by one character whenever the
+// counter outgrows the keyspace of the current length.
+type Sequential struct {
+	seq      Sequence
+	alphabet string
+}
+
+// NewSequential returns a sequential generator whose alphabet is shuffled by salt.
+func NewSequential(seq Sequence, salt string) *Sequential {
+	return &Sequential{seq: seq, alphabet: shuffle(base62, salt)}
+}
+
+// Generate encodes the next counter value using at least length characters.
+func (s *Sequential) Generate(ctx context.Context, length int) (string, error) {
+	n, err := s.seq.NextSequence(ctx, sequenceName)
+	if err != nil {
+		return "", err
+	}
+	return s.encode(n, length), nil
+}
+
+// encode maps n to a code of at least minLength characters. Counter values are
+// split into consecutive blocks, one per length, so codes of different lengths never collide.
+func (s *Sequential) encode(n uint64, minLength int) string {
+	base := big.NewInt(int64(len(s.alphabet)))
+	value := new(big.Int).SetUint64(n)
+
+	// Find the length whose block contains n, skipping the blocks below minLength
+	length := minLength
+	space := new(big.Int).Exp(base, big.NewInt(int64(length)), nil)
+	for value.Cmp(space) >= 0 && length < MaxLength {
+		value.Sub(value, space)
+		length++
+		space.Exp(base, big.NewInt(int64(length)), nil)
+	}
+
+	// Scramble within the block: x -> x*multiplier+offset mod 62^length
+	value.Mul(value, multiplier).Add(value, offset).Mod(value, space)
+
+	// Write the scrambled value in the shuffled alphabet, left-padded to length
+	code := make([]byte, length)
+	rem := new(big.Int)
+	for i := length - 1; i >= 0; i-- {
+		value.DivMod(value, base, rem)
+		code[i] = s.alphabet[rem.Int64()]
+	}
+	return string(code)
+}
+
+// shuffle deterministically permutes alphabet using a ChaCha8 stream seeded from salt.
+func shuffle(alphabet, salt string) string {
+	if salt == "" {
+		return alphabet
+	}
+
+	chars := []byte(alphabet)
+	rng := rand.New(rand.NewChaCha8(sha256.Sum256([]byte(salt))))
+	rng.Shuffle(len(chars), func(i, j int) { chars[i], chars[j] = chars[j], chars[i] })
+	return string(chars)
+}
@@ -0,0 +1,189 @@
+package shortcode
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// counter is an in-memory Sequence.
+type counter struct{ n uint64 }
+
+func (c *counter) NextSequence(ctx context.Context, name string) (uint64, error) {
+	n := c.n
+	c.n++
+	return n, nil
+}
+
+func TestSequentialEncodeIsBijective(t *testing.T) {
+	s := NewSequential(&counter{}, "salt")
+	space := uint64(len(base62) * len(base62))
+
+	// Every value in the two-character block maps to a distinct two-character code
+	seen := make(map[string]uint64, space)
+	for n := uint64(0); n < space; n++ {
+		code := s.encode(n, 2)
+		if len(code) != 2 {
+			t.Fatalf("encode(%d, 2) = %q, want 2 characters", n, code)
+		}
+		if prev, dup := seen[code]; dup {
+			t.Fatalf("encode(%d, 2) = %q, same as encode(%d, 2)", n, code, prev)
+		}
+		seen[code] = n
+	}
+}
+
+func TestSequentialEncodeLength(t *testing.T) {
+	s := NewSequential(&counter{}, "")
+	block := func(n int) uint64 {
+		size := uint64(1)
+		for range n {
+			size *= uint64(len(base62))
+		}
+		return size
+	}
+
+	tests := []struct {
+		name      string
+		n         uint64
+		minLength int
+		want      int
+	}{
+		{"first value", 0, 2, 2},
+		{"end of first block", block(2) - 1, 2, 2},
+		{"grows past the block", block(2), 2, 3},
+		{"end of second block", block(2) + block(3) - 1, 2, 3},
+		{"third block", block(2) + block(3), 2, 4},
+		{"minimum length", 0, 5, 5},
+		{"largest counter", ^uint64(0), 10, 11},
+		{"MaxLength minimum", ^uint64(0), MaxLength, MaxLength},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.encode(tt.n, tt.minLength); len(got) != tt.want {
+				t.Errorf("encode(%d, %d) = %q, want %d characters", tt.n, tt.minLength, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSequentialGenerateNeverRepeats(t *testing.T) {
+	s := NewSequential(&counter{}, "salt")
+	seen := make(map[string]bool)
+	for range 10000 {
+		code, err := s.Generate(context.Background(), 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if seen[code] {
+			t.Fatalf("Generate repeated %q", code)
+		}
+		seen[code] = true
+	}
+}
+
+func TestShuffle(t *testing.T) {
+	if got := shuffle(base62, ""); got != base62 {
+		t.Errorf("shuffle with no salt = %q, want the alphabet unchanged", got)
+	}
+
+	a, b := shuffle(base62, "one"), shuffle(base62, "two")
+	if a != shuffle(base62, "one") {
+		t.Error("shuffle is not deterministic for the same salt")
+	}
+	if a == b {
+		t.Error("different salts gave the same alphabet")
+	}
+	sorted := []byte(a)
+	slices.Sort(sorted)
+	if string(sorted) != base62 {
+		t.Errorf("shuffle(%q) = %q, not a permutation of the alphabet", "one", a)
+	}
+}
+
+// lengthRecorder is a Generator that returns a fresh code each call and records the lengths it was asked for.
+type lengthRecorder struct {
+	lengths []int
+}
+
+func (g *lengthRecorder) Generate(ctx context.Context, length int) (string, error) {
+	g.lengths = append(g.lengths, length)
+	return strings.Repeat("x", length) + string(rune('a'+len(g.lengths))), nil
+}
+
+var errTaken = errors.New("taken")
+
+func TestAllocator(t *testing.T) {
+	errDB := errors.New("database down")
+
+	tests := []struct {
+		name       string
+		maxLength  int
+		taken      int   // Candidates rejected as taken before create succeeds
+		createErr  error // Returned after the taken candidates, nil for success
+		wantErr    error
+		wantLength []int // Lengths requested from the generator
+		wantNext   int   // Length used by the next Allocate call
+	}{
+		{"first try", 8, 0, nil, nil, []int{6}, 6},
+		{"one collision stays", 8, 1, nil, nil, []int{6, 6}, 6},
+		{"two collisions grow", 8, 2, nil, nil, []int{6, 6, 7}, 7},
+		{"keeps growing", 8, 4, nil, nil, []int{6, 6, 7, 7, 8}, 8},
+		{"capped at max", 7, 5, nil, nil, []int{6, 6, 7, 7, 7, 7}, 7},
+		{"exhausted", 8, 100, nil, ErrExhausted, []int{6, 6, 7, 7, 8, 8, 8, 8}, 8},
+		{"other error aborts", 8, 1, errDB, errDB, []int{6, 6}, 6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gen := &lengthRecorder{}
+			a := NewAllocator(gen, 6, tt.maxLength)
+
+			calls := 0
+			create := func(code string) error {
+				calls++
+				if calls <= tt.taken {
+					return errTaken
+				}
+				return tt.createErr
+			}
+			isTaken := func(err error) bool { return errors.Is(err, errTaken) }
+
+			code, err := a.Allocate(context.Background(), create, isTaken)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Allocate error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && len(code) != gen.lengths[len(gen.lengths)-1]+1 {
+				t.Errorf("Allocate returned %q, not the last candidate", code)
+			}
+			if !slices.Equal(gen.lengths, tt.wantLength) {
+				t.Errorf("generator lengths = %v, want %v", gen.lengths, tt.wantLength)
+			}
+			if got := int(a.length.Load()); got != tt.wantNext {
+				t.Errorf("next length = %d, want %d", got, tt.wantNext)
+			}
+		})
+	}
+}
+
+func TestNewGenerator(t *testing.T) {
+	tests := []struct {
+		strategy string
+		seq      Sequence
+		wantErr  bool
+	}{
+		{"", nil, false},
+		{"random", nil, false},
+		{"words", nil, false},
+		{"sequential", &counter{}, false},
+		{"sequential", nil, true},
+		{"uuid", nil, true},
+	}
+	for _, tt := range tests {
+		_, err := NewGenerator(Options{Strategy: tt.strategy, Sequence: tt.seq})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("NewGenerator(%q, sequence %v) error = %v, want error %v", tt.strategy, tt.seq != nil, err, tt.wantErr)
+		}
+	}
+}
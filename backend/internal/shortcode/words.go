@@ -0,0 +1,70 @@
+package shortcode
+
+import (
+	"context"
+	"crypto/rand"
+	"math/big"
+	"strings"
+)
+
+// Word lists are limited to five letters so "adjective-noun-digits" stays within MaxLength.
+var (
+	adjectives = []string{
+		"amber", "bold", "brave", "brisk", "calm", "clear", "cool", "crisp", "eager", "early",
+		"fair", "fancy", "fast", "fresh", "gold", "grand", "green", "happy", "jolly", "keen",
+		"kind", "lucky", "merry", "mild", "neat", "noble", "proud", "quick", "quiet", "rapid",
+		"ready", "royal", "shiny", "silky", "sleek", "smart", "solid", "sunny", "swift", "tidy",
+		"vivid", "warm", "wise", "witty", "young", "zesty",
+	}
+	nouns = []string{
+		"apple", "bear", "bird", "brook", "cedar", "cloud", "coral", "crane", "daisy", "eagle",
+		"ember", "fern", "finch", "fox", "grove", "hawk", "heron", "lake", "lark", "lemon",
+		"lion", "lotus", "maple", "moon", "moth", "oak", "otter", "owl", "panda", "peach",
+		"pine", "plum", "raven", "river", "robin", "sage", "seal", "stone", "storm", "swan",
+		"tiger", "tulip", "wave", "whale", "wolf", "wren",
+	}
+)
+
+// Words generates human-readable codes such as "brave-otter-42". length is the
+// number of trailing digits, which grows as the word combinations fill up.
+type Words struct{}
+
+// Generate returns a random adjective-noun pair followed by length digits.
+func (Words) Generate(ctx context.Context, length int) (string, error) {
+	adj, err := pick(adjectives)
+	if err != nil {
+		return "", err
+	}
+	noun, err := pick(nouns)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	b.WriteString(adj)
+	b.WriteByte('-')
+	b.WriteString(noun)
+
+	// Keep the whole code within links.code even after the digit count grows
+	digits := min(length, MaxLength-b.Len()-1)
+	if digits > 0 {
+		b.WriteByte('-')
+		for i := 0; i < digits; i++ {
+			d, err := rand.Int(rand.Reader, big.NewInt(10))
+			if err != nil {
+				return "", err
+			}
+			b.WriteByte(byte('0' + d.Int64()))
+		}
+	}
+	return b.String(), nil
+}
+
+// pick returns a uniformly random element of words.
+func pick(words []string) (string, error) {
+	i, err := rand.Int(rand.Reader, big.NewInt(int64(len(words))))
+	if err != nil {
+		return "", err
+	}
+	return words[i.Int64()], nil
+}
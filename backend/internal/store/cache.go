@@ -0,0 +1,134 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrCacheMiss is returned by Cache.Get when the key is absent or expired.
+var ErrCacheMiss = errors.New("store: cache miss")
+
+// Cache is a small string key/value cache with per-entry TTLs.
+// Redis backs it in production; MemoryCache is used when no Redis is configured.
+type Cache interface {
+	Get(ctx context.Context, key string) (string, error)
+	Set(ctx context.Context, key, value string, ttl time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+
+	// Incr atomically adds one to the counter at key and returns the new value. A
+	// missing or expired counter starts at 1 and expires after ttl; later increments
+	// keep that expiry, so the counter covers a fixed window.
+	Incr(ctx context.Context, key string, ttl time.Duration) (int64, error)
+}
+
+// cacheSweepInterval is how often MemoryCache drops expired entries.
+const cacheSweepInterval = time.Minute
+
+// cacheEntry is a value held by MemoryCache together with its expiry.
+type cacheEntry struct {
+	value     string
+	expiresAt time.Time // Zero means the entry never expires
+}
+
+// expired reports whether the entry has expired at now.
+func (e cacheEntry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && now.After(e.expiresAt)
+}
+
+// MemoryCache is a process-local Cache. Expired entries are dropped on access, and
+// writes periodically sweep out the ones nobody reads again (e.g. denylisted tokens
+// and attempt counters), so memory stays bounded by the live entries.
+type MemoryCache struct {
+	mu        sync.Mutex
+	entries   map[string]cacheEntry
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewMemoryCache returns an empty in-memory cache.
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{entries: make(map[string]cacheEntry), now: time.Now}
+}
+
+// Get returns the cached value for key or ErrCacheMiss.
+func (c *MemoryCache) Get(ctx context.Context, key string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return "", ErrCacheMiss
+	}
+	if entry.expired(c.now()) {
+		delete(c.entries, key)
+		return "", ErrCacheMiss
+	}
+	return entry.value, nil
+}
+
+// Set stores value under key; a ttl of zero keeps it until deleted.
+func (c *MemoryCache) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	c.maybeSweep(now)
+	entry := cacheEntry{value: value}
+	if ttl > 0 {
+		entry.expiresAt = now.Add(ttl)
+	}
+	c.entries[key] = entry
+	return nil
+}
+
+// Delete removes the given keys; missing keys are ignored.
+func (c *MemoryCache) Delete(ctx context.Context, keys ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, key := range keys {
+		delete(c.entries, key)
+	}
+	return nil
+}
+
+// Incr adds one to the counter at key under the lock.
+func (c *MemoryCache) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	c.maybeSweep(now)
+	entry, ok := c.entries[key]
+	if !ok || entry.expired(now) {
+		entry = cacheEntry{value: "0"}
+		if ttl > 0 {
+			entry.expiresAt = now.Add(ttl)
+		}
+	}
+	n, err := strconv.ParseInt(entry.value, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	n++
+	entry.value = strconv.FormatInt(n, 10)
+	c.entries[key] = entry
+	return n, nil
+}
+
+// maybeSweep removes expired entries if the last sweep is more than cacheSweepInterval
+// ago. Callers hold c.mu.
+func (c *MemoryCache) maybeSweep(now time.Time) {
+	if now.Sub(c.lastSweep) <= cacheSweepInterval {
+		return
+	}
+	for key, entry := range c.entries {
+		if entry.expired(now) {
+			delete(c.entries, key)
+		}
+	}
+	c.lastSweep = now
+}
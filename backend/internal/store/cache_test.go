@@ -0,0 +1,85 @@
+package store
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMemoryCacheIncrIsAtomic(t *testing.T) {
+	cache := NewMemoryCache()
+	ctx := context.Background()
+
+	const workers = 50
+	seen := make(chan int64, workers)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			n, err := cache.Incr(ctx, "k", time.Minute)
+			if err != nil {
+				t.Error(err)
+			}
+			seen <- n
+		}()
+	}
+	wg.Wait()
+	close(seen)
+
+	// Every increment sees a different value
+	got := make(map[int64]bool)
+	for n := range seen {
+		if got[n] {
+			t.Fatalf("value %d returned twice", n)
+		}
+		got[n] = true
+	}
+	if value, _ := cache.Get(ctx, "k"); value != "50" {
+		t.Fatalf("counter = %q, want 50", value)
+	}
+}
+
+func TestMemoryCacheIncrKeepsFirstExpiry(t *testing.T) {
+	cache := NewMemoryCache()
+	ctx := context.Background()
+
+	if n, _ := cache.Incr(ctx, "k", 50*time.Millisecond); n != 1 {
+		t.Fatalf("first Incr = %d, want 1", n)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if n, _ := cache.Incr(ctx, "k", 50*time.Millisecond); n != 2 {
+		t.Fatalf("second Incr = %d, want 2", n)
+	}
+	// The second increment must not have pushed the expiry back
+	time.Sleep(30 * time.Millisecond)
+	if n, _ := cache.Incr(ctx, "k", 50*time.Millisecond); n != 1 {
+		t.Fatalf("Incr after the window = %d, want a new counter at 1", n)
+	}
+}
+
+func TestMemoryCacheSweepsExpiredEntries(t *testing.T) {
+	cache := NewMemoryCache()
+	ctx := context.Background()
+	now := time.Date(2026, 3, 30, 12, 0, 0, 0, time.UTC)
+	cache.now = func() time.Time { return now }
+
+	// Entries nobody reads again, like a logged-out token or an attempt counter
+	cache.Set(ctx, "denied:a", "1", time.Minute)
+	cache.Incr(ctx, "pwfail:b", time.Minute)
+	cache.Set(ctx, "kept", "1", 0)
+	cache.Set(ctx, "later", "1", time.Hour)
+
+	now = now.Add(2 * time.Minute)
+	cache.Set(ctx, "new", "1", time.Minute)
+	cache.mu.Lock()
+	left := len(cache.entries)
+	cache.mu.Unlock()
+	if left != 3 {
+		t.Fatalf("%d entries after the sweep, want 3 (kept, later, new)", left)
+	}
+	if v, err := cache.Get(ctx, "later"); err != nil || v != "1" {
+		t.Fatalf("Get(later) = %q, %v; want the live entry", v, err)
+	}
+}
@@ -0,0 +1,198 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ConstantineCTF/URLSecure/backend/internal/model"
+)
+
+func TestLinkCRUD(t *testing.T) {
+	ctx := context.Background()
+	for name, st := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			limit := 5
+			link := &model.URL{Code: "abc123", Target: "https://example.com/", UserID: 1, MaxClicks: &limit}
+			if err := st.CreateLink(ctx, link); err != nil {
+				t.Fatal(err)
+			}
+			if link.ID == 0 || link.CreatedAt.IsZero() {
+				t.Fatalf("CreateLink left ID %d, CreatedAt %v", link.ID, link.CreatedAt)
+			}
+			if err := st.CreateLink(ctx, &model.URL{Code: "abc123", Target: "https://example.org/", UserID: 1}); !errors.Is(err, ErrCodeTaken) {
+				t.Fatalf("duplicate code: %v, want ErrCodeTaken", err)
+			}
+
+			got, err := st.GetLinkByCode(ctx, "abc123")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.ID != link.ID || got.Target != link.Target || got.MaxClicks == nil || *got.MaxClicks != limit || got.Status != model.LinkActive {
+				t.Fatalf("GetLinkByCode = %+v", got)
+			}
+			if _, err := st.GetLinkByCode(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("missing code: %v, want ErrNotFound", err)
+			}
+
+			// Updates are scoped to the owner
+			got.Target, got.MaxClicks = "https://example.org/", nil
+			if err := st.UpdateLink(ctx, got); err != nil {
+				t.Fatal(err)
+			}
+			if updated, _ := st.GetLinkByCode(ctx, "abc123"); updated.Target != "https://example.org/" || updated.MaxClicks != nil {
+				t.Fatalf("after UpdateLink: %+v", updated)
+			}
+			got.UserID = 2
+			if err := st.UpdateLink(ctx, got); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("update by another user: %v, want ErrNotFound", err)
+			}
+
+			// So are deletes
+			if err := st.DeleteLink(ctx, 2, "abc123"); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("delete by another user: %v, want ErrNotFound", err)
+			}
+			if err := st.DeleteLink(ctx, 1, "abc123"); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := st.GetLinkByCode(ctx, "abc123"); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("after DeleteLink: %v, want ErrNotFound", err)
+			}
+		})
+	}
+}
+
+func TestUserLookups(t *testing.T) {
+	ctx := context.Background()
+	for name, st := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			for _, login := range []string{"alice", "alice@example.com"} {
+				user, err := st.GetUserByLogin(ctx, login)
+				if err != nil || user.ID != 1 {
+					t.Fatalf("GetUserByLogin(%q) = %+v, %v", login, user, err)
+				}
+			}
+			if _, err := st.GetUserByLogin(ctx, "bob"); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("unknown login: %v, want ErrNotFound", err)
+			}
+			if _, err := st.GetUserByID(ctx, 99); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("unknown ID: %v, want ErrNotFound", err)
+			}
+
+			dupName := &model.User{Username: "alice", Email: "other@example.com", PasswordHash: "x", Plan: "free"}
+			if err := st.CreateUser(ctx, dupName); !errors.Is(err, ErrUsernameTaken) {
+				t.Fatalf("duplicate username: %v, want ErrUsernameTaken", err)
+			}
+			dupEmail := &model.User{Username: "bob", Email: "alice@example.com", PasswordHash: "x", Plan: "free"}
+			if err := st.CreateUser(ctx, dupEmail); !errors.Is(err, ErrEmailTaken) {
+				t.Fatalf("duplicate email: %v, want ErrEmailTaken", err)
+			}
+		})
+	}
+}
+
+func TestIncrementClicks(t *testing.T) {
+	ctx := context.Background()
+	for name, st := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			limit := 2
+			if err := st.CreateLink(ctx, &model.URL{Code: "twice", Target: "https://example.com/", UserID: 1, MaxClicks: &limit}); err != nil {
+				t.Fatal(err)
+			}
+			for i, want := range []bool{true, true, false} {
+				counted, err := st.IncrementClicks(ctx, "twice")
+				if err != nil || counted != want {
+					t.Fatalf("click %d: %t, %v; want %t", i+1, counted, err, want)
+				}
+			}
+			if link, _ := st.GetLinkByCode(ctx, "twice"); link.Clicks != limit {
+				t.Fatalf("clicks = %d, want %d", link.Clicks, limit)
+			}
+
+			// Both backends tell a missing link from a used-up one
+			if _, err := st.IncrementClicks(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("missing code: %v, want ErrNotFound", err)
+			}
+		})
+	}
+}
+
+func TestListLinks(t *testing.T) {
+	ctx := context.Background()
+	for name, st := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			bob := &model.User{Username: "bob", Email: "bob@example.com", PasswordHash: "x", Plan: "free"}
+			if err := st.CreateUser(ctx, bob); err != nil {
+				t.Fatal(err)
+			}
+
+			// Created in this order; a and c tie on clicks
+			seed := []struct {
+				code, target string
+				userID       uint64
+				clicks       int
+			}{
+				{"a", "https://a.example/one", 1, 2},
+				{"b", "https://b.example/100%-off", 1, 0},
+				{"c", "https://c.example/two", 1, 2},
+				{"d", "https://d.example/three", 1, 5},
+				{"e", "https://e.example/two", bob.ID, 9},
+			}
+			ids := make(map[string]uint64)
+			for _, s := range seed {
+				link := &model.URL{Code: s.code, Target: s.target, UserID: s.userID}
+				if err := st.CreateLink(ctx, link); err != nil {
+					t.Fatal(err)
+				}
+				ids[s.code] = link.ID
+				for range s.clicks {
+					if _, err := st.IncrementClicks(ctx, s.code); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+			after := func(code string, clicks int) *LinkCursor {
+				return &LinkCursor{ID: ids[code], Clicks: clicks}
+			}
+
+			tests := []struct {
+				name string
+				q    LinkQuery
+				want string
+			}{
+				{"newest first", LinkQuery{UserID: 1}, "dcba"},
+				{"oldest first", LinkQuery{UserID: 1, Ascending: true}, "abcd"},
+				{"most clicked, ties newest first", LinkQuery{UserID: 1, SortBy: SortByClicks}, "dcab"},
+				{"least clicked", LinkQuery{UserID: 1, SortBy: SortByClicks, Ascending: true}, "bacd"},
+				{"limit", LinkQuery{UserID: 1, Limit: 2}, "dc"},
+				{"after cursor", LinkQuery{UserID: 1, After: after("c", 0)}, "ba"},
+				{"after cursor ascending", LinkQuery{UserID: 1, Ascending: true, After: after("b", 0)}, "cd"},
+				{"after click tie", LinkQuery{UserID: 1, SortBy: SortByClicks, After: after("c", 2)}, "ab"},
+				{"after click tie ascending", LinkQuery{UserID: 1, SortBy: SortByClicks, Ascending: true, After: after("a", 2)}, "cd"},
+				{"cursor and limit", LinkQuery{UserID: 1, SortBy: SortByClicks, After: after("d", 5), Limit: 1}, "c"},
+				{"search ignores case", LinkQuery{UserID: 1, Search: "EXAMPLE/T"}, "dc"},
+				{"search is literal", LinkQuery{UserID: 1, Search: "%"}, "b"},
+				{"every owner", LinkQuery{}, "edcba"},
+				{"other owner", LinkQuery{UserID: bob.ID}, "e"},
+				{"no match", LinkQuery{UserID: 1, Search: "nowhere"}, ""},
+			}
+			for _, tt := range tests {
+				if tt.q.Limit == 0 {
+					tt.q.Limit = 10
+				}
+				links, err := st.ListLinks(ctx, tt.q)
+				if err != nil {
+					t.Fatalf("%s: %v", tt.name, err)
+				}
+				var got strings.Builder
+				for _, link := range links {
+					got.WriteString(link.Code)
+				}
+				if got.String() != tt.want {
+					t.Errorf("%s: got %q, want %q", tt.name, got.String(), tt.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,796 @@
+package store
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ConstantineCTF/URLSecure/backend/internal/model"
+)
+
+// MemoryStore implements LinkStore and UserStore entirely in process memory.
+// It is meant for tests and local development; all data is lost on exit.
+type MemoryStore struct {
+	mu         sync.RWMutex
+	links      map[string]*model.URL // Links keyed by short code
+	users      map[uint64]*model.User
+	sequences  map[string]uint64
+	plans      map[string]*model.Plan
+	usage      map[uint64]map[string]int      // User ID -> usage period -> links created
+	clicks     map[uint64][]model.ClickEvent  // Link ID -> click events, oldest first
+	unrolled   []model.ClickEvent             // Events not rolled up yet, oldest first
+	rolledUp   uint64                         // ID of the last event rolled up
+	rollups    map[uint64]map[rollupKey]int   // Link ID -> hourly counters, as in click_rollups
+	tokens     map[string]*model.RefreshToken // Refresh tokens keyed by hash
+	apiKeys    map[uint64]*model.APIKey       // API keys keyed by ID
+	recovery   map[uint64]map[string]bool     // User ID -> recovery code hash -> used
+	nextLinkID uint64
+	nextUserID uint64
+	nextClick  uint64
+	nextToken  uint64
+	nextAPIKey uint64
+}
+
+// NewMemoryStore returns an empty in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		links:     make(map[string]*model.URL),
+		users:     make(map[uint64]*model.User),
+		sequences: make(map[string]uint64),
+		plans:     defaultPlans(),
+		usage:     make(map[uint64]map[string]int),
+		clicks:    make(map[uint64][]model.ClickEvent),
+		rollups:   make(map[uint64]map[rollupKey]int),
+		tokens:    make(map[string]*model.RefreshToken),
+		apiKeys:   make(map[uint64]*model.APIKey),
+		recovery:  make(map[uint64]map[string]bool),
+	}
+}
+
+// defaultPlans mirrors the plans seeded by the SQL migrations.
+func defaultPlans() map[string]*model.Plan {
+	limit := func(n int) *int { return &n }
+	plans := []*model.Plan{
+		{Name: "free", DailyLinks: limit(50), MonthlyLinks: limit(500), MaxActiveLinks: limit(200), AnalyticsRetentionDays: limit(30)},
+		{Name: "pro", DailyLinks: limit(1000), MonthlyLinks: limit(20000), MaxActiveLinks: limit(10000), CustomAliases: true, PasswordLinks: true, AnalyticsRetentionDays: limit(365)},
+		{Name: "unlimited", CustomAliases: true, PasswordLinks: true},
+	}
+	byName := make(map[string]*model.Plan, len(plans))
+	for _, plan := range plans {
+		byName[plan.Name] = plan
+	}
+	return byName
+}
+
+// CreateLink stores a copy of link under its code.
+func (s *MemoryStore) CreateLink(ctx context.Context, link *model.URL) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.links[link.Code]; exists {
+		return ErrCodeTaken
+	}
+
+	if link.Status == "" {
+		link.Status = model.LinkActive
+	}
+	s.nextLinkID++
+	link.ID = s.nextLinkID
+	link.CreatedAt = time.Now()
+
+	stored := *link
+	s.links[link.Code] = &stored
+	return nil
+}
+
+// GetLinkByCode returns a copy of the link stored under code.
+func (s *MemoryStore) GetLinkByCode(ctx context.Context, code string) (*model.URL, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	link, ok := s.links[code]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	found := *link
+	return &found, nil
+}
+
+// ListLinks returns copies of one page of links, mirroring the SQL ordering.
+func (s *MemoryStore) ListLinks(ctx context.Context, q LinkQuery) ([]*model.URL, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	search := strings.ToLower(q.Search)
+	links := []*model.URL{}
+	for _, link := range s.links {
+		if q.UserID != 0 && link.UserID != q.UserID {
+			continue
+		}
+		if q.Status != "" && link.Status != q.Status {
+			continue
+		}
+		if search != "" && !strings.Contains(strings.ToLower(link.Target), search) {
+			continue
+		}
+		found := *link
+		links = append(links, &found)
+	}
+
+	// before reports whether a sorts ahead of b in descending order
+	before := func(a, b *model.URL) bool {
+		if q.SortBy == SortByClicks && a.Clicks != b.Clicks {
+			return a.Clicks > b.Clicks
+		}
+		return a.ID > b.ID
+	}
+	if q.Ascending {
+		desc := before
+		before = func(a, b *model.URL) bool { return desc(b, a) }
+	}
+	sort.Slice(links, func(i, j int) bool { return before(links[i], links[j]) })
+
+	// Skip everything up to and including the cursor position
+	if q.After != nil {
+		cursor := &model.URL{ID: q.After.ID, Clicks: q.After.Clicks}
+		start := sort.Search(len(links), func(i int) bool { return before(cursor, links[i]) })
+		links = links[start:]
+	}
+
+	if q.Limit > 0 && len(links) > q.Limit {
+		links = links[:q.Limit]
+	}
+	return links, nil
+}
+
+// UpdateLink saves the mutable fields of a link, scoped to its owner.
+func (s *MemoryStore) UpdateLink(ctx context.Context, link *model.URL) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.links[link.Code]
+	if !ok || stored.UserID != link.UserID {
+		return ErrNotFound
+	}
+	stored.Target = link.Target
+	stored.ExpiresAt = link.ExpiresAt
+	stored.MaxClicks = link.MaxClicks
+	stored.Status = link.Status
+	stored.RiskScore = link.RiskScore
+	stored.RiskReasons = link.RiskReasons
+	stored.Preview = link.Preview
+	stored.PasswordHash = link.PasswordHash
+	return nil
+}
+
+// DeleteLink removes a link, scoped to its owner.
+func (s *MemoryStore) DeleteLink(ctx context.Context, userID uint64, code string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.links[code]
+	if !ok || stored.UserID != userID {
+		return ErrNotFound
+	}
+	delete(s.links, code)
+	delete(s.clicks, stored.ID)
+	delete(s.rollups, stored.ID)
+	s.unrolled = slices.DeleteFunc(s.unrolled, func(e model.ClickEvent) bool { return e.LinkID == stored.ID })
+	return nil
+}
+
+// IncrementClicks adds one to the click counter of a link unless it has reached max clicks.
+// A missing code is ErrNotFound, as in SQLStore.
+func (s *MemoryStore) IncrementClicks(ctx context.Context, code string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	link, ok := s.links[code]
+	if !ok {
+		return false, ErrNotFound
+	}
+	if link.Exhausted() {
+		return false, nil
+	}
+	link.Clicks++
+	return true, nil
+}
+
+// IncrementBotClicks adds one to the bot visit counter of a link.
+func (s *MemoryStore) IncrementBotClicks(ctx context.Context, code string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	link, ok := s.links[code]
+	if !ok {
+		return ErrNotFound
+	}
+	link.BotClicks++
+	return nil
+}
+
+// NextSequence increments and returns the named counter.
+func (s *MemoryStore) NextSequence(ctx context.Context, name string) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sequences[name]++
+	return s.sequences[name], nil
+}
+
+// CreateUser stores a copy of user, enforcing unique usernames and emails.
+func (s *MemoryStore) CreateUser(ctx context.Context, user *model.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.users {
+		if existing.Username == user.Username {
+			return ErrUsernameTaken
+		}
+		if existing.Email == user.Email {
+			return ErrEmailTaken
+		}
+	}
+
+	if user.Plan == "" {
+		user.Plan = model.DefaultPlan
+	}
+	s.nextUserID++
+	user.ID = s.nextUserID
+	user.CreatedAt = time.Now()
+
+	stored := *user
+	s.users[user.ID] = &stored
+	return nil
+}
+
+// GetUserByLogin finds a user whose email or username equals identifier.
+func (s *MemoryStore) GetUserByLogin(ctx context.Context, identifier string) (*model.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, user := range s.users {
+		if user.Email == identifier || user.Username == identifier {
+			found := *user
+			return &found, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// GetUserByID returns a copy of the user with the given ID.
+func (s *MemoryStore) GetUserByID(ctx context.Context, id uint64) (*model.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	user, ok := s.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	found := *user
+	return &found, nil
+}
+
+// SetUserRetention updates the user's own retention setting.
+func (s *MemoryStore) SetUserRetention(ctx context.Context, userID uint64, days *int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[userID]
+	if !ok {
+		return ErrNotFound
+	}
+	user.RetentionDays = days
+	return nil
+}
+
+// MarkEmailVerified sets EmailVerifiedAt if the address still matches.
+func (s *MemoryStore) MarkEmailVerified(ctx context.Context, userID uint64, email string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[userID]
+	if !ok || user.Email != email || user.EmailVerifiedAt != nil {
+		return ErrNotFound
+	}
+	user.EmailVerifiedAt = &at
+	return nil
+}
+
+// SetUserPassword replaces the user's password hash.
+func (s *MemoryStore) SetUserPassword(ctx context.Context, userID uint64, hash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[userID]
+	if !ok {
+		return ErrNotFound
+	}
+	user.PasswordHash = hash
+	return nil
+}
+
+// GetPlan returns a copy of the named plan.
+func (s *MemoryStore) GetPlan(ctx context.Context, name string) (*model.Plan, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	plan, ok := s.plans[name]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	found := *plan
+	return &found, nil
+}
+
+// ListPlans returns copies of all plans ordered by name.
+func (s *MemoryStore) ListPlans(ctx context.Context) ([]*model.Plan, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	plans := make([]*model.Plan, 0, len(s.plans))
+	for _, plan := range s.plans {
+		found := *plan
+		plans = append(plans, &found)
+	}
+	sort.Slice(plans, func(i, j int) bool { return plans[i].Name < plans[j].Name })
+	return plans, nil
+}
+
+// SetUserPlan moves a user to an existing plan.
+func (s *MemoryStore) SetUserPlan(ctx context.Context, userID uint64, plan string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[userID]
+	if _, exists := s.plans[plan]; !ok || !exists {
+		return ErrNotFound
+	}
+	user.Plan = plan
+	return nil
+}
+
+// GetUsage returns the user's creation counters and live link count.
+func (s *MemoryStore) GetUsage(ctx context.Context, userID uint64, now time.Time) (model.Usage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	day, month := usagePeriods(now)
+	usage := model.Usage{Daily: s.usage[userID][day], Monthly: s.usage[userID][month]}
+	for _, link := range s.links {
+		if link.UserID == userID && !link.Expired(now) && !link.Exhausted() {
+			usage.Active++
+		}
+	}
+	return usage, nil
+}
+
+// ReserveLinkCreation bumps the user's day and month counters if both are under their limits.
+func (s *MemoryStore) ReserveLinkCreation(ctx context.Context, userID uint64, now time.Time, daily, monthly *int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.usage[userID] == nil {
+		s.usage[userID] = make(map[string]int)
+	}
+	day, month := usagePeriods(now)
+	switch {
+	case daily != nil && s.usage[userID][day] >= *daily:
+		return ErrDailyLimit
+	case monthly != nil && s.usage[userID][month] >= *monthly:
+		return ErrMonthlyLimit
+	}
+	s.usage[userID][day]++
+	s.usage[userID][month]++
+	return nil
+}
+
+// ReleaseLinkCreation takes back one reservation from the user's day and month counters.
+func (s *MemoryStore) ReleaseLinkCreation(ctx context.Context, userID uint64, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	day, month := usagePeriods(now)
+	for _, period := range []string{day, month} {
+		if s.usage[userID][period] > 0 {
+			s.usage[userID][period]--
+		}
+	}
+	return nil
+}
+
+// Close is a no-op; it exists to satisfy Store.
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+// RecordClicks appends events to their links' histories.
+func (s *MemoryStore) RecordClicks(ctx context.Context, events []model.ClickEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range events {
+		link, ok := s.links[events[i].Code]
+		if !ok {
+			continue
+		}
+		s.nextClick++
+		events[i].ID = s.nextClick
+		events[i].LinkID = link.ID
+		s.clicks[link.ID] = append(s.clicks[link.ID], events[i])
+		s.unrolled = append(s.unrolled, events[i])
+	}
+	return nil
+}
+
+// RollupClicks adds up to limit of the oldest events not rolled up yet to the hourly
+// counters.
+func (s *MemoryStore) RollupClicks(ctx context.Context, limit int) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := min(limit, len(s.unrolled))
+	if n == 0 {
+		return 0, nil
+	}
+	for i := range s.unrolled[:n] {
+		e := &s.unrolled[i]
+		addRollup(s.linkRollups(e.LinkID), e.LinkID, e)
+	}
+	s.rolledUp = s.unrolled[n-1].ID
+	s.unrolled = slices.Delete(s.unrolled, 0, n)
+	return n, nil
+}
+
+// linkRollups returns the link's hourly counters, creating them if needed. Callers
+// hold s.mu for writing.
+func (s *MemoryStore) linkRollups(linkID uint64) map[rollupKey]int {
+	if s.rollups[linkID] == nil {
+		s.rollups[linkID] = make(map[rollupKey]int)
+	}
+	return s.rollups[linkID]
+}
+
+// ClickStats reads clicks and breakdowns from the link's hourly counters.
+func (s *MemoryStore) ClickStats(ctx context.Context, q StatsQuery) (*model.LinkStats, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	from, to := statsRange(q)
+	hourly := make(map[time.Time]int)
+	breakdowns := make(map[string]map[string]int)
+	for key, clicks := range s.rollups[q.LinkID] {
+		if key.bucket.Before(from) || !key.bucket.Before(to) {
+			continue
+		}
+		if key.dimension == dimTotal {
+			hourly[key.bucket] += clicks
+			continue
+		}
+		if breakdowns[key.dimension] == nil {
+			breakdowns[key.dimension] = make(map[string]int)
+		}
+		breakdowns[key.dimension][key.value] += clicks
+	}
+	return buildStats(q, hourly, breakdowns), nil
+}
+
+// CountClicks adds anonymous counts straight to the link's hourly totals.
+func (s *MemoryStore) CountClicks(ctx context.Context, counts []model.ClickCount) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, count := range counts {
+		link, ok := s.links[count.Code]
+		if !ok {
+			continue
+		}
+		s.linkRollups(link.ID)[rollupKey{link.ID, count.Hour.UTC().Truncate(time.Hour), dimTotal, ""}] += count.Clicks
+	}
+	return nil
+}
+
+// PurgeClickEvents drops rolled-up events older than their owner's retention. Their
+// clicks stay in the hourly counters.
+func (s *MemoryStore) PurgeClickEvents(ctx context.Context, now time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var deleted int64
+	for _, link := range s.links {
+		user, ok := s.users[link.UserID]
+		if !ok {
+			continue
+		}
+		plan, ok := s.plans[user.Plan]
+		if !ok {
+			continue
+		}
+		days := plan.Retention(user.RetentionDays)
+		if days == nil {
+			continue
+		}
+
+		cutoff := now.AddDate(0, 0, -*days)
+		kept := s.clicks[link.ID][:0]
+		for _, event := range s.clicks[link.ID] {
+			if event.ID <= s.rolledUp && event.ClickedAt.Before(cutoff) {
+				deleted++
+				continue
+			}
+			kept = append(kept, event)
+		}
+		s.clicks[link.ID] = kept
+	}
+	return deleted, nil
+}
+
+// CreateRefreshToken stores a copy of token.
+func (s *MemoryStore) CreateRefreshToken(ctx context.Context, token *model.RefreshToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextToken++
+	token.ID = s.nextToken
+	token.CreatedAt = time.Now()
+
+	stored := *token
+	s.tokens[token.TokenHash] = &stored
+	return nil
+}
+
+// GetRefreshToken returns a copy of the token with the given hash.
+func (s *MemoryStore) GetRefreshToken(ctx context.Context, hash string) (*model.RefreshToken, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	token, ok := s.tokens[hash]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	found := *token
+	return &found, nil
+}
+
+// UseRefreshToken marks the token with id as used if it is still live.
+func (s *MemoryStore) UseRefreshToken(ctx context.Context, id uint64, now time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, token := range s.tokens {
+		if token.ID != id {
+			continue
+		}
+		if token.UsedAt != nil || token.RevokedAt != nil {
+			return false, nil
+		}
+		token.UsedAt = &now
+		return true, nil
+	}
+	return false, nil
+}
+
+// RevokeTokenFamily revokes all live tokens of a family.
+func (s *MemoryStore) RevokeTokenFamily(ctx context.Context, familyID string, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, token := range s.tokens {
+		if token.FamilyID == familyID && token.RevokedAt == nil {
+			token.RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+// RevokeUserTokens revokes all live tokens of a user.
+func (s *MemoryStore) RevokeUserTokens(ctx context.Context, userID uint64, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, token := range s.tokens {
+		if token.UserID == userID && token.RevokedAt == nil {
+			token.RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+// DeleteExpiredRefreshTokens drops tokens past their expiry.
+func (s *MemoryStore) DeleteExpiredRefreshTokens(ctx context.Context, now time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var deleted int64
+	for hash, token := range s.tokens {
+		if token.ExpiresAt.Before(now) {
+			delete(s.tokens, hash)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// CreateAPIKey stores a copy of key.
+func (s *MemoryStore) CreateAPIKey(ctx context.Context, key *model.APIKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextAPIKey++
+	key.ID = s.nextAPIKey
+	key.CreatedAt = time.Now()
+
+	stored := *key
+	s.apiKeys[key.ID] = &stored
+	return nil
+}
+
+// GetAPIKeyByHash returns a copy of the key with the given hash.
+func (s *MemoryStore) GetAPIKeyByHash(ctx context.Context, hash string) (*model.APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, key := range s.apiKeys {
+		if key.KeyHash == hash {
+			found := *key
+			return &found, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// ListAPIKeys returns copies of the user's live keys, newest first.
+func (s *MemoryStore) ListAPIKeys(ctx context.Context, userID uint64) ([]*model.APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var keys []*model.APIKey
+	for _, key := range s.apiKeys {
+		if key.UserID == userID && key.RevokedAt == nil {
+			found := *key
+			keys = append(keys, &found)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].ID > keys[j].ID })
+	return keys, nil
+}
+
+// RevokeAPIKey marks an owned key as revoked.
+func (s *MemoryStore) RevokeAPIKey(ctx context.Context, userID, id uint64, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, ok := s.apiKeys[id]
+	if !ok || key.UserID != userID || key.RevokedAt != nil {
+		return ErrNotFound
+	}
+	key.RevokedAt = &now
+	return nil
+}
+
+// TouchAPIKey updates LastUsedAt unless it was set within the last minute.
+func (s *MemoryStore) TouchAPIKey(ctx context.Context, id uint64, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if key, ok := s.apiKeys[id]; ok && (key.LastUsedAt == nil || key.LastUsedAt.Before(now.Add(-time.Minute))) {
+		key.LastUsedAt = &now
+	}
+	return nil
+}
+
+// SetTOTPSecret stores a pending secret unless 2FA is already enabled.
+func (s *MemoryStore) SetTOTPSecret(ctx context.Context, userID uint64, secret string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[userID]
+	if !ok || user.TOTPEnabledAt != nil {
+		return ErrNotFound
+	}
+	user.TOTPSecret = secret
+	return nil
+}
+
+// EnableTOTP confirms the pending secret and stores the recovery codes.
+func (s *MemoryStore) EnableTOTP(ctx context.Context, userID uint64, secret string, step int64, hashes []string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[userID]
+	if !ok || user.TOTPSecret != secret || user.TOTPEnabledAt != nil {
+		return ErrNotFound
+	}
+	user.TOTPEnabledAt = &at
+	user.TOTPLastStep = step
+	s.setRecoveryCodes(userID, hashes)
+	return nil
+}
+
+// DisableTOTP clears the user's TOTP state and recovery codes.
+func (s *MemoryStore) DisableTOTP(ctx context.Context, userID uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[userID]
+	if !ok {
+		return ErrNotFound
+	}
+	user.TOTPSecret, user.TOTPEnabledAt, user.TOTPLastStep = "", nil, 0
+	delete(s.recovery, userID)
+	return nil
+}
+
+// UseTOTPStep advances TOTPLastStep if step is newer.
+func (s *MemoryStore) UseTOTPStep(ctx context.Context, userID uint64, step int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[userID]
+	if !ok || user.TOTPLastStep >= step {
+		return false, nil
+	}
+	user.TOTPLastStep = step
+	return true, nil
+}
+
+// ReplaceRecoveryCodes swaps the user's recovery codes.
+func (s *MemoryStore) ReplaceRecoveryCodes(ctx context.Context, userID uint64, hashes []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.setRecoveryCodes(userID, hashes)
+	return nil
+}
+
+// setRecoveryCodes replaces the user's recovery codes; the caller holds the lock.
+func (s *MemoryStore) setRecoveryCodes(userID uint64, hashes []string) {
+	codes := make(map[string]bool, len(hashes))
+	for _, hash := range hashes {
+		codes[hash] = false
+	}
+	s.recovery[userID] = codes
+}
+
+// UseRecoveryCode marks an unused code as used.
+func (s *MemoryStore) UseRecoveryCode(ctx context.Context, userID uint64, hash string, now time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	used, ok := s.recovery[userID][hash]
+	if !ok || used {
+		return false, nil
+	}
+	s.recovery[userID][hash] = true
+	return true, nil
+}
+
+// CountRecoveryCodes counts the user's unused recovery codes.
+func (s *MemoryStore) CountRecoveryCodes(ctx context.Context, userID uint64) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	n := 0
+	for _, used := range s.recovery[userID] {
+		if !used {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// SetMFARequired updates the user's MFARequired flag.
+func (s *MemoryStore) SetMFARequired(ctx context.Context, userID uint64, required bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[userID]
+	if !ok {
+		return ErrNotFound
+	}
+	user.MFARequired = required
+	return nil
+}
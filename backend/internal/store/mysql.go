@@ -2,16 +2,19 @@ package store
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"time"
 
-	_ "github.com/go-sql-driver/mysql" // MySQL driver imported for side effects
+	"github.com/go-sql-driver/mysql" // MySQL driver, also registers itself with database/sql
 )
 
 // ConnectMySQL opens a pooled MySQL DB connection based on provided credentials.
 func ConnectMySQL(user, pass, host, port, dbname string) (*sql.DB, error) {
-	// Format MySQL DSN (data source name) with parseTime=true to handle time fields
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?parseTime=true", user, pass, host, port, dbname)
+	// Format MySQL DSN (data source name) with parseTime=true to handle time fields.
+	// clientFoundRows makes RowsAffected count matched rather than changed rows,
+	// so an UPDATE that rewrites identical values is not mistaken for a missing row.
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?parseTime=true&clientFoundRows=true", user, pass, host, port, dbname)
 
 	// Open connection to MySQL database
 	db, err := sql.Open("mysql", dsn)
@@ -20,9 +23,9 @@ func ConnectMySQL(user, pass, host, port, dbname string) (*sql.DB, error) {
 	}
 
 	// Configure connection pool settings for performance and resource management
-	db.SetMaxOpenConns(25)            // Max open connections
-	db.SetMaxIdleConns(25)            // Max idle connections for reuse
-	db.SetConnMaxLifetime(time.Hour) // Max lifetime before recycling connection
+	db.SetMaxOpenConns(25)                  // Max open connections
+	db.SetMaxIdleConns(25)                  // Max idle connections for reuse
+	db.SetConnMaxLifetime(time.Hour)        // Max lifetime before recycling connection
 	db.SetConnMaxIdleTime(10 * time.Minute) // Max idle time before recycling
 
 	// Ping DB to verify connectivity
@@ -32,3 +35,38 @@ func ConnectMySQL(user, pass, host, port, dbname string) (*sql.DB, error) {
 
 	return db, nil
 }
+
+// NewMySQLStore wraps an open MySQL connection pool in a SQLStore.
+func NewMySQLStore(db *sql.DB) *SQLStore {
+	return &SQLStore{db: db, dialect: mysqlDialect{}}
+}
+
+// mysqlDuplicateEntry is the MySQL error number for unique key violations (ER_DUP_ENTRY).
+const mysqlDuplicateEntry = 1062
+
+// mysqlDialect maps MySQL driver errors for SQLStore.
+type mysqlDialect struct{}
+
+// name identifies the driver's migration set.
+func (mysqlDialect) name() string { return "mysql" }
+
+// rebind is a no-op: the driver understands ? placeholders.
+func (mysqlDialect) rebind(query string) string { return query }
+
+// returningID is false: the driver implements LastInsertId.
+func (mysqlDialect) returningID() bool { return false }
+
+// duplicateKey detects ER_DUP_ENTRY, whose message reads "Duplicate entry 'x' for key 'users.username'".
+func (mysqlDialect) duplicateKey(err error) (string, bool) {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlDuplicateEntry {
+		return mysqlErr.Message, true
+	}
+	return "", false
+}
+
+// ignoreDuplicate uses ON DUPLICATE KEY UPDATE with a self-assignment, which changes
+// nothing. INSERT IGNORE would also swallow unrelated errors.
+func (mysqlDialect) ignoreDuplicate(column string) string {
+	return "ON DUPLICATE KEY UPDATE " + column + " = " + column
+}
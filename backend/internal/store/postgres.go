@@ -0,0 +1,95 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"   // PostgreSQL error details
+	_ "github.com/jackc/pgx/v5/stdlib" // Registers the "pgx" database/sql driver
+)
+
+// ConnectPostgres opens a pooled PostgreSQL DB connection based on provided credentials.
+func ConnectPostgres(user, pass, host, port, dbname, sslmode string) (*sql.DB, error) {
+	// Build a postgres:// URL so special characters in the password are escaped properly
+	dsn := (&url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(user, pass),
+		Host:     host + ":" + port,
+		Path:     dbname,
+		RawQuery: "sslmode=" + url.QueryEscape(sslmode),
+	}).String()
+
+	// Open connection to PostgreSQL database
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	// Configure connection pool settings for performance and resource management
+	db.SetMaxOpenConns(25)                  // Max open connections
+	db.SetMaxIdleConns(25)                  // Max idle connections for reuse
+	db.SetConnMaxLifetime(time.Hour)        // Max lifetime before recycling connection
+	db.SetConnMaxIdleTime(10 * time.Minute) // Max idle time before recycling
+
+	// Ping DB to verify connectivity
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("ping postgres: %w", err)
+	}
+
+	return db, nil
+}
+
+// NewPostgresStore wraps an open PostgreSQL connection pool in a SQLStore.
+func NewPostgresStore(db *sql.DB) *SQLStore {
+	return &SQLStore{db: db, dialect: postgresDialect{}}
+}
+
+// pgUniqueViolation is the SQLSTATE for unique constraint violations.
+const pgUniqueViolation = "23505"
+
+// postgresDialect adapts the shared queries to PostgreSQL.
+type postgresDialect struct{}
+
+// name identifies the driver's migration set.
+func (postgresDialect) name() string { return "postgres" }
+
+// rebind turns ? placeholders into $1, $2, ... The shared queries never
+// contain a literal question mark, so a plain scan is sufficient.
+func (postgresDialect) rebind(query string) string {
+	var b strings.Builder
+	b.Grow(len(query) + 8)
+
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// returningID is true: pgx does not implement LastInsertId.
+func (postgresDialect) returningID() bool { return true }
+
+// duplicateKey detects unique violations and returns the constraint name,
+// e.g. "users_username_key".
+func (postgresDialect) duplicateKey(err error) (string, bool) {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == pgUniqueViolation {
+		return pgErr.ConstraintName, true
+	}
+	return "", false
+}
+
+// ignoreDuplicate uses ON CONFLICT DO NOTHING.
+func (postgresDialect) ignoreDuplicate(string) string { return "ON CONFLICT DO NOTHING" }
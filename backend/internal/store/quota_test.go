@@ -0,0 +1,115 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ConstantineCTF/URLSecure/backend/internal/migrate"
+	"github.com/ConstantineCTF/URLSecure/backend/internal/model"
+)
+
+// testStores returns a MemoryStore and a migrated SQLite store, each with one user.
+func testStores(t *testing.T) map[string]Store {
+	t.Helper()
+	ctx := context.Background()
+
+	sqlite, err := OpenSQLite(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlite.Close() })
+	m, err := migrate.New(sqlite.DB(), sqlite.Driver())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	stores := map[string]Store{"memory": NewMemoryStore(), "sqlite": sqlite}
+	for name, st := range stores {
+		user := &model.User{Username: "alice", Email: "alice@example.com", PasswordHash: "x", Plan: "free"}
+		if err := st.CreateUser(ctx, user); err != nil {
+			t.Fatalf("%s: CreateUser: %v", name, err)
+		}
+		if user.ID != 1 {
+			t.Fatalf("%s: user ID %d, want 1", name, user.ID)
+		}
+	}
+	return stores
+}
+
+func TestReserveLinkCreationStopsAtLimit(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 3, 30, 12, 0, 0, 0, time.UTC)
+	daily, monthly := 5, 8
+
+	for name, st := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			// Parallel creations may take exactly the allowance, no more
+			var wg sync.WaitGroup
+			errs := make(chan error, 20)
+			for range 20 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					errs <- st.ReserveLinkCreation(ctx, 1, now, &daily, &monthly)
+				}()
+			}
+			wg.Wait()
+			close(errs)
+			reserved := 0
+			for err := range errs {
+				switch {
+				case err == nil:
+					reserved++
+				case !errors.Is(err, ErrDailyLimit):
+					t.Fatalf("ReserveLinkCreation: %v", err)
+				}
+			}
+			if reserved != daily {
+				t.Fatalf("reserved %d links, want %d", reserved, daily)
+			}
+
+			// A released reservation can be taken again
+			if err := st.ReleaseLinkCreation(ctx, 1, now); err != nil {
+				t.Fatal(err)
+			}
+			if err := st.ReserveLinkCreation(ctx, 1, now, &daily, &monthly); err != nil {
+				t.Fatalf("after release: %v", err)
+			}
+
+			// The next day has a fresh daily allowance but shares the month; a refusal
+			// by the monthly limit must not count against the day either
+			tomorrow := now.Add(24 * time.Hour)
+			for range 3 {
+				if err := st.ReserveLinkCreation(ctx, 1, tomorrow, &daily, &monthly); err != nil {
+					t.Fatalf("next day: %v", err)
+				}
+			}
+			if err := st.ReserveLinkCreation(ctx, 1, tomorrow, &daily, &monthly); !errors.Is(err, ErrMonthlyLimit) {
+				t.Fatalf("month full: got %v, want ErrMonthlyLimit", err)
+			}
+			usage, err := st.GetUsage(ctx, 1, tomorrow)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if usage.Daily != 3 || usage.Monthly != monthly {
+				t.Fatalf("usage %+v, want 3 today and %d this month", usage, monthly)
+			}
+
+			// Unlimited counters are still counted
+			nextMonth := now.Add(48 * time.Hour)
+			if err := st.ReserveLinkCreation(ctx, 1, nextMonth, nil, nil); err != nil {
+				t.Fatalf("new month: %v", err)
+			}
+			if usage, _ := st.GetUsage(ctx, 1, nextMonth); usage.Daily != 1 || usage.Monthly != 1 {
+				t.Fatalf("new month usage %+v, want 1 and 1", usage)
+			}
+		})
+	}
+}
@@ -31,3 +31,47 @@ func NewRedisClient(host, port string) *redis.Client {
 
 // Ctx is a globally available context for Redis commands
 var Ctx = context.Background()
+
+// RedisCache implements Cache on top of a Redis client.
+type RedisCache struct {
+	client *redis.Client
+}
+
+// NewRedisCache wraps an existing Redis client.
+func NewRedisCache(client *redis.Client) *RedisCache {
+	return &RedisCache{client: client}
+}
+
+// Get returns the cached value for key, translating redis.Nil to ErrCacheMiss.
+func (c *RedisCache) Get(ctx context.Context, key string) (string, error) {
+	value, err := c.client.Get(ctx, key).Result()
+	if err == redis.Nil {
+		return "", ErrCacheMiss
+	}
+	return value, err
+}
+
+// Set stores value under key with the given TTL.
+func (c *RedisCache) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	return c.client.Set(ctx, key, value, ttl).Err()
+}
+
+// Delete removes the given keys.
+func (c *RedisCache) Delete(ctx context.Context, keys ...string) error {
+	return c.client.Del(ctx, keys...).Err()
+}
+
+// Incr runs INCR and EXPIRE NX in one transaction, so the expiry is only set by the
+// increment that created the counter. EXPIRE NX needs Redis 7.
+func (c *RedisCache) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	var incr *redis.IntCmd
+	_, err := c.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		incr = pipe.Incr(ctx, key)
+		pipe.ExpireNX(ctx, key, ttl)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return incr.Val(), nil
+}
@@ -0,0 +1,1109 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/ConstantineCTF/URLSecure/backend/internal/model"
+)
+
+// dialect captures the differences between SQL backends that the shared queries care about.
+type dialect interface {
+	// name is the driver name, which is also the migrations directory for the dialect.
+	name() string
+
+	// rebind rewrites a query written with ? placeholders into the driver's syntax.
+	rebind(query string) string
+
+	// returningID reports whether inserts must use RETURNING id because the
+	// driver does not implement sql.Result.LastInsertId.
+	returningID() bool
+
+	// duplicateKey reports whether err is a unique constraint violation and,
+	// if so, returns the driver message naming the violated key or column.
+	duplicateKey(err error) (key string, ok bool)
+
+	// ignoreDuplicate returns the clause that turns an INSERT into a no-op when the row's
+	// key already exists. column is any column of the table, for MySQL, which has no
+	// DO NOTHING and assigns the column to itself instead.
+	ignoreDuplicate(column string) string
+}
+
+// SQLStore implements LinkStore and UserStore on top of a database/sql pool.
+// Queries are written once and the dialect handles driver-specific behaviour.
+type SQLStore struct {
+	db      *sql.DB
+	dialect dialect
+}
+
+// DB exposes the underlying connection pool, e.g. for tuning pool settings.
+func (s *SQLStore) DB() *sql.DB {
+	return s.db
+}
+
+// Driver returns the dialect name (mysql, postgres or sqlite).
+func (s *SQLStore) Driver() string {
+	return s.dialect.name()
+}
+
+// Close releases the underlying connection pool.
+func (s *SQLStore) Close() error {
+	return s.db.Close()
+}
+
+// exec runs a statement written with ? placeholders.
+func (s *SQLStore) exec(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return s.db.ExecContext(ctx, s.dialect.rebind(query), args...)
+}
+
+// query runs a query written with ? placeholders.
+func (s *SQLStore) query(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return s.db.QueryContext(ctx, s.dialect.rebind(query), args...)
+}
+
+// queryRow runs a single-row query written with ? placeholders.
+func (s *SQLStore) queryRow(ctx context.Context, query string, args ...any) *sql.Row {
+	return s.db.QueryRowContext(ctx, s.dialect.rebind(query), args...)
+}
+
+// insert runs an INSERT and returns the generated id column.
+func (s *SQLStore) insert(ctx context.Context, query string, args ...any) (uint64, error) {
+	if s.dialect.returningID() {
+		var id uint64
+		err := s.queryRow(ctx, query+" RETURNING id", args...).Scan(&id)
+		return id, err
+	}
+
+	res, err := s.exec(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	return uint64(id), err
+}
+
+// linkColumns is the column list matching scanLink.
+const linkColumns = "id, user_id, code, target, clicks, bot_clicks, created_at, expires_at, max_clicks, status, risk_score, risk_reasons, preview, password_hash"
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanLink reads one row selected with linkColumns.
+func scanLink(row rowScanner) (*model.URL, error) {
+	link := &model.URL{}
+	var reasons, passwordHash sql.NullString
+	err := row.Scan(&link.ID, &link.UserID, &link.Code, &link.Target, &link.Clicks, &link.BotClicks, &link.CreatedAt,
+		&link.ExpiresAt, &link.MaxClicks, &link.Status, &link.RiskScore, &reasons, &link.Preview, &passwordHash)
+	if err == nil && reasons.Valid {
+		err = json.Unmarshal([]byte(reasons.String), &link.RiskReasons)
+	}
+	link.PasswordHash = passwordHash.String
+	return link, err
+}
+
+// riskReasonsColumn encodes reasons for the risk_reasons column; none is stored as NULL.
+func riskReasonsColumn(reasons []model.RiskReason) (any, error) {
+	if len(reasons) == 0 {
+		return nil, nil
+	}
+	raw, err := json.Marshal(reasons)
+	return string(raw), err
+}
+
+// nullString stores an empty string as NULL.
+func nullString(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
+
+// CreateLink inserts a new link row. An empty Status is stored as active.
+func (s *SQLStore) CreateLink(ctx context.Context, link *model.URL) error {
+	if link.Status == "" {
+		link.Status = model.LinkActive
+	}
+	reasons, err := riskReasonsColumn(link.RiskReasons)
+	if err != nil {
+		return err
+	}
+
+	id, err := s.insert(ctx,
+		"INSERT INTO links (user_id, code, target, expires_at, max_clicks, status, risk_score, risk_reasons, preview, password_hash) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		link.UserID, link.Code, link.Target, link.ExpiresAt, link.MaxClicks, link.Status, link.RiskScore, reasons, link.Preview,
+		nullString(link.PasswordHash),
+	)
+	if err != nil {
+		if _, dup := s.dialect.duplicateKey(err); dup {
+			return ErrCodeTaken
+		}
+		return err
+	}
+
+	link.ID = id
+	link.CreatedAt = time.Now()
+	return nil
+}
+
+// GetLinkByCode fetches a single link by its short code.
+func (s *SQLStore) GetLinkByCode(ctx context.Context, code string) (*model.URL, error) {
+	link, err := scanLink(s.queryRow(ctx, "SELECT "+linkColumns+" FROM links WHERE code = ?", code))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return link, nil
+}
+
+// ListLinks returns one page of a user's links using keyset pagination on (sort key, id).
+func (s *SQLStore) ListLinks(ctx context.Context, q LinkQuery) ([]*model.URL, error) {
+	var where []string
+	var args []any
+
+	if q.UserID != 0 {
+		where = append(where, "user_id = ?")
+		args = append(args, q.UserID)
+	}
+	if q.Status != "" {
+		where = append(where, "status = ?")
+		args = append(args, q.Status)
+	}
+
+	if q.Search != "" {
+		// '!' is used as the LIKE escape character because backslash handling differs between drivers
+		where = append(where, "LOWER(target) LIKE ? ESCAPE '!'")
+		args = append(args, "%"+escapeLike(strings.ToLower(q.Search))+"%")
+	}
+
+	dir, cmp := "DESC", "<"
+	if q.Ascending {
+		dir, cmp = "ASC", ">"
+	}
+
+	// IDs increase with creation time, so "created" order is simply id order
+	order := "id " + dir
+	if q.SortBy == SortByClicks {
+		order = "clicks " + dir + ", id " + dir
+		if q.After != nil {
+			where = append(where, "(clicks "+cmp+" ? OR (clicks = ? AND id "+cmp+" ?))")
+			args = append(args, q.After.Clicks, q.After.Clicks, q.After.ID)
+		}
+	} else if q.After != nil {
+		where = append(where, "id "+cmp+" ?")
+		args = append(args, q.After.ID)
+	}
+	args = append(args, q.Limit)
+
+	query := "SELECT " + linkColumns + " FROM links"
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	rows, err := s.query(ctx, query+" ORDER BY "+order+" LIMIT ?", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	links := []*model.URL{}
+	for rows.Next() {
+		link, err := scanLink(rows)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+	return links, rows.Err()
+}
+
+// UpdateLink saves the mutable fields of a link, scoped to its owner.
+func (s *SQLStore) UpdateLink(ctx context.Context, link *model.URL) error {
+	reasons, err := riskReasonsColumn(link.RiskReasons)
+	if err != nil {
+		return err
+	}
+
+	res, err := s.exec(ctx,
+		"UPDATE links SET target = ?, expires_at = ?, max_clicks = ?, status = ?, risk_score = ?, risk_reasons = ?, preview = ?, password_hash = ? WHERE code = ? AND user_id = ?",
+		link.Target, link.ExpiresAt, link.MaxClicks, link.Status, link.RiskScore, reasons, link.Preview,
+		nullString(link.PasswordHash), link.Code, link.UserID,
+	)
+	if err != nil {
+		return err
+	}
+	return requireRow(res)
+}
+
+// DeleteLink removes a link, scoped to its owner.
+func (s *SQLStore) DeleteLink(ctx context.Context, userID uint64, code string) error {
+	res, err := s.exec(ctx, "DELETE FROM links WHERE code = ? AND user_id = ?", code, userID)
+	if err != nil {
+		return err
+	}
+	return requireRow(res)
+}
+
+// requireRow returns ErrNotFound when a statement matched no rows.
+func requireRow(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// escapeLike escapes LIKE wildcards in s using '!' as the escape character.
+func escapeLike(s string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(s)
+}
+
+// IncrementClicks adds one to the click counter of a link unless it has reached max_clicks.
+// The limit is checked in the UPDATE itself so concurrent redirects cannot overshoot it.
+// When no row changes, a second query tells a used-up link from a missing one.
+func (s *SQLStore) IncrementClicks(ctx context.Context, code string) (bool, error) {
+	res, err := s.exec(ctx,
+		"UPDATE links SET clicks = clicks + 1 WHERE code = ? AND (max_clicks IS NULL OR clicks < max_clicks)",
+		code,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil || n > 0 {
+		return n > 0, err
+	}
+	var one int
+	err = s.queryRow(ctx, "SELECT 1 FROM links WHERE code = ?", code).Scan(&one)
+	if err == sql.ErrNoRows {
+		return false, ErrNotFound
+	}
+	return false, err
+}
+
+// IncrementBotClicks adds one to the bot visit counter of a link.
+func (s *SQLStore) IncrementBotClicks(ctx context.Context, code string) error {
+	res, err := s.exec(ctx, "UPDATE links SET bot_clicks = bot_clicks + 1 WHERE code = ?", code)
+	if err != nil {
+		return err
+	}
+	return requireRow(res)
+}
+
+// NextSequence increments the named counter inside a transaction. The UPDATE takes a
+// row lock, so concurrent callers each see a distinct value.
+func (s *SQLStore) NextSequence(ctx context.Context, name string) (uint64, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx,
+		s.dialect.rebind("UPDATE sequences SET current_value = current_value + 1 WHERE name = ?"), name)
+	if err != nil {
+		return 0, err
+	}
+
+	// First use of this counter: create it. A concurrent first use fails with a
+	// duplicate key here, which the caller treats like any other failed attempt.
+	if n, err := res.RowsAffected(); err != nil {
+		return 0, err
+	} else if n == 0 {
+		if _, err := tx.ExecContext(ctx,
+			s.dialect.rebind("INSERT INTO sequences (name, current_value) VALUES (?, 1)"), name); err != nil {
+			return 0, err
+		}
+	}
+
+	var value uint64
+	if err := tx.QueryRowContext(ctx,
+		s.dialect.rebind("SELECT current_value FROM sequences WHERE name = ?"), name).Scan(&value); err != nil {
+		return 0, err
+	}
+	return value, tx.Commit()
+}
+
+// CreateUser inserts a new user row, mapping unique key violations to store errors.
+// An empty Plan is stored as model.DefaultPlan.
+func (s *SQLStore) CreateUser(ctx context.Context, user *model.User) error {
+	if user.Plan == "" {
+		user.Plan = model.DefaultPlan
+	}
+	id, err := s.insert(ctx,
+		"INSERT INTO users (username, email, password_hash, plan) VALUES (?, ?, ?, ?)",
+		user.Username, user.Email, user.PasswordHash, user.Plan,
+	)
+	if err != nil {
+		if key, dup := s.dialect.duplicateKey(err); dup {
+			// The driver message names the violated key, e.g. "users.username" or "users_username_key"
+			if strings.Contains(key, "username") {
+				return ErrUsernameTaken
+			}
+			return ErrEmailTaken
+		}
+		return err
+	}
+
+	user.ID = id
+	user.CreatedAt = time.Now()
+	return nil
+}
+
+// userColumns is the column list matching scanUser.
+const userColumns = "id, username, email, email_verified_at, password_hash, plan, analytics_retention_days, " +
+	"totp_secret, totp_enabled_at, totp_last_step, mfa_required, created_at"
+
+// scanUser reads one row selected with userColumns.
+func scanUser(row rowScanner) (*model.User, error) {
+	user := &model.User{}
+	err := row.Scan(&user.ID, &user.Username, &user.Email, &user.EmailVerifiedAt, &user.PasswordHash, &user.Plan, &user.RetentionDays,
+		&user.TOTPSecret, &user.TOTPEnabledAt, &user.TOTPLastStep, &user.MFARequired, &user.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// GetUserByLogin finds a user whose email or username equals identifier.
+func (s *SQLStore) GetUserByLogin(ctx context.Context, identifier string) (*model.User, error) {
+	return scanUser(s.queryRow(ctx,
+		"SELECT "+userColumns+" FROM users WHERE email = ? OR username = ?", identifier, identifier))
+}
+
+// GetUserByID fetches a single user by primary key.
+func (s *SQLStore) GetUserByID(ctx context.Context, id uint64) (*model.User, error) {
+	return scanUser(s.queryRow(ctx, "SELECT "+userColumns+" FROM users WHERE id = ?", id))
+}
+
+// SetUserRetention updates the user's own retention setting.
+func (s *SQLStore) SetUserRetention(ctx context.Context, userID uint64, days *int) error {
+	res, err := s.exec(ctx, "UPDATE users SET analytics_retention_days = ? WHERE id = ?", days, userID)
+	if err != nil {
+		return err
+	}
+	return requireRow(res)
+}
+
+// MarkEmailVerified sets email_verified_at if the address still matches.
+func (s *SQLStore) MarkEmailVerified(ctx context.Context, userID uint64, email string, at time.Time) error {
+	res, err := s.exec(ctx,
+		"UPDATE users SET email_verified_at = ? WHERE id = ? AND email = ? AND email_verified_at IS NULL", at.UTC(), userID, email)
+	if err != nil {
+		return err
+	}
+	return requireRow(res)
+}
+
+// SetUserPassword updates the user's password hash.
+func (s *SQLStore) SetUserPassword(ctx context.Context, userID uint64, hash string) error {
+	res, err := s.exec(ctx, "UPDATE users SET password_hash = ? WHERE id = ?", hash, userID)
+	if err != nil {
+		return err
+	}
+	return requireRow(res)
+}
+
+// planColumns is the column list matching scanPlan.
+const planColumns = "name, daily_links, monthly_links, max_active_links, custom_aliases, password_links, analytics_retention_days"
+
+// scanPlan reads one row selected with planColumns.
+func scanPlan(row rowScanner) (*model.Plan, error) {
+	plan := &model.Plan{}
+	err := row.Scan(&plan.Name, &plan.DailyLinks, &plan.MonthlyLinks, &plan.MaxActiveLinks,
+		&plan.CustomAliases, &plan.PasswordLinks, &plan.AnalyticsRetentionDays)
+	return plan, err
+}
+
+// GetPlan fetches a plan by name.
+func (s *SQLStore) GetPlan(ctx context.Context, name string) (*model.Plan, error) {
+	plan, err := scanPlan(s.queryRow(ctx, "SELECT "+planColumns+" FROM plans WHERE name = ?", name))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// ListPlans returns all plans ordered by name.
+func (s *SQLStore) ListPlans(ctx context.Context) ([]*model.Plan, error) {
+	rows, err := s.query(ctx, "SELECT "+planColumns+" FROM plans ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	plans := []*model.Plan{}
+	for rows.Next() {
+		plan, err := scanPlan(rows)
+		if err != nil {
+			return nil, err
+		}
+		plans = append(plans, plan)
+	}
+	return plans, rows.Err()
+}
+
+// SetUserPlan moves a user to an existing plan.
+func (s *SQLStore) SetUserPlan(ctx context.Context, userID uint64, plan string) error {
+	if _, err := s.GetPlan(ctx, plan); err != nil {
+		return err
+	}
+	res, err := s.exec(ctx, "UPDATE users SET plan = ? WHERE id = ?", plan, userID)
+	if err != nil {
+		return err
+	}
+	return requireRow(res)
+}
+
+// GetUsage reads the creation counters for the current day and month and counts live links.
+func (s *SQLStore) GetUsage(ctx context.Context, userID uint64, now time.Time) (model.Usage, error) {
+	var usage model.Usage
+	day, month := usagePeriods(now)
+
+	rows, err := s.query(ctx, "SELECT period, links FROM link_usage WHERE user_id = ? AND period IN (?, ?)", userID, day, month)
+	if err != nil {
+		return usage, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var period string
+		var links int
+		if err := rows.Scan(&period, &links); err != nil {
+			return usage, err
+		}
+		if period == day {
+			usage.Daily = links
+		} else {
+			usage.Monthly = links
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return usage, err
+	}
+
+	err = s.queryRow(ctx,
+		"SELECT COUNT(*) FROM links WHERE user_id = ? AND (expires_at IS NULL OR expires_at > ?) AND (max_clicks IS NULL OR clicks < max_clicks)",
+		userID, now.UTC(),
+	).Scan(&usage.Active)
+	return usage, err
+}
+
+// ReserveLinkCreation bumps the day and month counters inside one transaction. Each
+// counter row is created first if missing, then incremented only while under its limit;
+// the conditional UPDATE locks the row, so concurrent reservations cannot both take the
+// last link of an allowance.
+func (s *SQLStore) ReserveLinkCreation(ctx context.Context, userID uint64, now time.Time, daily, monthly *int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	day, month := usagePeriods(now)
+	counters := []struct {
+		period string
+		limit  *int
+		full   error
+	}{
+		{day, daily, ErrDailyLimit},
+		{month, monthly, ErrMonthlyLimit},
+	}
+	for _, counter := range counters {
+		if _, err := tx.ExecContext(ctx,
+			s.dialect.rebind("INSERT INTO link_usage (user_id, period, links) VALUES (?, ?, 0) "+s.dialect.ignoreDuplicate("links")),
+			userID, counter.period); err != nil {
+			return err
+		}
+
+		query, args := "UPDATE link_usage SET links = links + 1 WHERE user_id = ? AND period = ?", []any{userID, counter.period}
+		if counter.limit != nil {
+			query += " AND links < ?"
+			args = append(args, *counter.limit)
+		}
+		res, err := tx.ExecContext(ctx, s.dialect.rebind(query), args...)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			return counter.full // Rolls back the day counter if the month is full
+		}
+	}
+	return tx.Commit()
+}
+
+// ReleaseLinkCreation decrements the day and month counters of a reservation.
+func (s *SQLStore) ReleaseLinkCreation(ctx context.Context, userID uint64, now time.Time) error {
+	day, month := usagePeriods(now)
+	_, err := s.exec(ctx,
+		"UPDATE link_usage SET links = links - 1 WHERE user_id = ? AND period IN (?, ?) AND links > 0",
+		userID, day, month)
+	return err
+}
+
+// clickBatchRows caps the rows per INSERT so batches stay under placeholder limits.
+const clickBatchRows = 200
+
+// RecordClicks looks up the link IDs of the batch's codes in one query, then writes the
+// events with multi-row INSERTs.
+func (s *SQLStore) RecordClicks(ctx context.Context, events []model.ClickEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	codes := make([]string, len(events))
+	for i, e := range events {
+		codes[i] = e.Code
+	}
+	ids, err := s.linkIDs(ctx, codes)
+	if err != nil {
+		return err
+	}
+
+	rows := make([]string, 0, clickBatchRows)
+	args := make([]any, 0, clickBatchRows*9)
+	flush := func() error {
+		if len(rows) == 0 {
+			return nil
+		}
+		_, err := s.exec(ctx, "INSERT INTO click_events (link_id, clicked_at, referrer_host, browser, os, device, language, country, bot) VALUES "+
+			strings.Join(rows, ", "), args...)
+		rows, args = rows[:0], args[:0]
+		return err
+	}
+	for i, e := range events {
+		id, ok := ids[e.Code]
+		if !ok {
+			continue // Link deleted since the visit
+		}
+		events[i].LinkID = id
+		rows = append(rows, "(?, ?, ?, ?, ?, ?, ?, ?, ?)")
+		args = append(args, id, e.ClickedAt.UTC(), e.ReferrerHost, e.Browser, e.OS, e.Device, e.Language, e.Country, e.Bot)
+		if len(rows) == clickBatchRows {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	return flush()
+}
+
+// linkIDs maps the distinct codes to link IDs; unknown codes are left out.
+func (s *SQLStore) linkIDs(ctx context.Context, codes []string) (map[string]uint64, error) {
+	seen := make(map[string]bool)
+	var args []any
+	for _, code := range codes {
+		if !seen[code] {
+			seen[code] = true
+			args = append(args, code)
+		}
+	}
+	ids := make(map[string]uint64, len(args))
+
+	rows, err := s.query(ctx, "SELECT id, code FROM links WHERE code IN ("+placeholders(len(args))+")", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id uint64
+		var code string
+		if err := rows.Scan(&id, &code); err != nil {
+			return nil, err
+		}
+		ids[code] = id
+	}
+	return ids, rows.Err()
+}
+
+// RollupClicks claims a batch of unprocessed events by flagging them rolled_up and adds
+// them to the hourly counters in the same transaction. If another process flagged any of
+// them first the whole batch is left to it.
+func (s *SQLStore) RollupClicks(ctx context.Context, limit int) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, s.dialect.rebind(
+		"SELECT id, link_id, clicked_at, referrer_host, browser, os, device, country, bot FROM click_events WHERE rolled_up = ? ORDER BY id LIMIT ?"),
+		false, limit)
+	if err != nil {
+		return 0, err
+	}
+	counts := make(map[rollupKey]int)
+	var ids []any
+	for rows.Next() {
+		var id, linkID uint64
+		var e model.ClickEvent
+		if err := rows.Scan(&id, &linkID, &e.ClickedAt, &e.ReferrerHost, &e.Browser, &e.OS, &e.Device, &e.Country, &e.Bot); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+		addRollup(counts, linkID, &e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil || len(ids) == 0 {
+		return 0, err
+	}
+
+	// Claim the batch; fewer rows than selected means a concurrent run got there first
+	res, err := tx.ExecContext(ctx, s.dialect.rebind(
+		"UPDATE click_events SET rolled_up = ? WHERE rolled_up = ? AND id IN ("+placeholders(len(ids))+")"),
+		append([]any{true, false}, ids...)...)
+	if err != nil {
+		return 0, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n != int64(len(ids)) {
+		return 0, err
+	}
+
+	if err := s.addRollups(ctx, tx, counts); err != nil {
+		return 0, err
+	}
+	return len(ids), tx.Commit()
+}
+
+// addRollups adds counts to the hourly counters inside tx.
+func (s *SQLStore) addRollups(ctx context.Context, tx *sql.Tx, counts map[rollupKey]int) error {
+	for key, clicks := range counts {
+		res, err := tx.ExecContext(ctx, s.dialect.rebind(
+			"UPDATE click_rollups SET clicks = clicks + ? WHERE link_id = ? AND bucket = ? AND dimension = ? AND value = ?"),
+			clicks, key.linkID, key.bucket, key.dimension, key.value)
+		if err != nil {
+			return err
+		}
+		// First click of this hour and value: create its counter
+		if n, err := res.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			if _, err := tx.ExecContext(ctx, s.dialect.rebind(
+				"INSERT INTO click_rollups (link_id, bucket, dimension, value, clicks) VALUES (?, ?, ?, ?, ?)"),
+				key.linkID, key.bucket, key.dimension, key.value, clicks); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// CountClicks adds anonymous counts straight to the hourly totals.
+func (s *SQLStore) CountClicks(ctx context.Context, counts []model.ClickCount) error {
+	if len(counts) == 0 {
+		return nil
+	}
+	codes := make([]string, len(counts))
+	for i, count := range counts {
+		codes[i] = count.Code
+	}
+	ids, err := s.linkIDs(ctx, codes)
+	if err != nil {
+		return err
+	}
+
+	totals := make(map[rollupKey]int)
+	for _, count := range counts {
+		if id, ok := ids[count.Code]; ok {
+			totals[rollupKey{id, count.Hour.UTC().Truncate(time.Hour), dimTotal, ""}] += count.Clicks
+		}
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := s.addRollups(ctx, tx, totals); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// purgeUsersPerQuery caps the user IDs in one DELETE.
+const purgeUsersPerQuery = 500
+
+// PurgeClickEvents groups users by their effective retention and deletes each group's
+// expired events.
+func (s *SQLStore) PurgeClickEvents(ctx context.Context, now time.Time) (int64, error) {
+	rows, err := s.query(ctx,
+		"SELECT u.id, u.analytics_retention_days, p.analytics_retention_days FROM users u JOIN plans p ON p.name = u.plan")
+	if err != nil {
+		return 0, err
+	}
+	byRetention := make(map[int][]any)
+	for rows.Next() {
+		var userID uint64
+		var userDays, planDays *int
+		if err := rows.Scan(&userID, &userDays, &planDays); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		plan := model.Plan{AnalyticsRetentionDays: planDays}
+		if days := plan.Retention(userDays); days != nil {
+			byRetention[*days] = append(byRetention[*days], userID)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	var deleted int64
+	for days, users := range byRetention {
+		cutoff := now.UTC().AddDate(0, 0, -days)
+		for len(users) > 0 {
+			chunk := users[:min(len(users), purgeUsersPerQuery)]
+			users = users[len(chunk):]
+
+			res, err := s.exec(ctx,
+				"DELETE FROM click_events WHERE rolled_up = ? AND clicked_at < ? AND link_id IN (SELECT id FROM links WHERE user_id IN ("+placeholders(len(chunk))+"))",
+				append([]any{true, cutoff}, chunk...)...)
+			if err != nil {
+				return deleted, err
+			}
+			n, err := res.RowsAffected()
+			if err != nil {
+				return deleted, err
+			}
+			deleted += n
+		}
+	}
+	return deleted, nil
+}
+
+// placeholders returns n comma-separated ? placeholders for an IN list.
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// ClickStats reads clicks and breakdowns from the hourly rollups.
+func (s *SQLStore) ClickStats(ctx context.Context, q StatsQuery) (*model.LinkStats, error) {
+	from, to := statsRange(q)
+
+	hourly := make(map[time.Time]int)
+	rows, err := s.query(ctx,
+		"SELECT bucket, clicks FROM click_rollups WHERE link_id = ? AND dimension = ? AND bucket >= ? AND bucket < ?",
+		q.LinkID, dimTotal, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var bucket time.Time
+		var clicks int
+		if err := rows.Scan(&bucket, &clicks); err != nil {
+			return nil, err
+		}
+		hourly[bucket.UTC()] += clicks
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	breakdowns := make(map[string]map[string]int)
+	rows, err = s.query(ctx,
+		"SELECT dimension, value, SUM(clicks) FROM click_rollups WHERE link_id = ? AND dimension <> ? AND bucket >= ? AND bucket < ? GROUP BY dimension, value",
+		q.LinkID, dimTotal, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var dimension, value string
+		var clicks int
+		if err := rows.Scan(&dimension, &value, &clicks); err != nil {
+			return nil, err
+		}
+		if breakdowns[dimension] == nil {
+			breakdowns[dimension] = make(map[string]int)
+		}
+		breakdowns[dimension][value] = clicks
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return buildStats(q, hourly, breakdowns), nil
+}
+
+// refreshTokenColumns is the column list matching scanRefreshToken.
+const refreshTokenColumns = "id, user_id, family_id, token_hash, created_at, expires_at, used_at, revoked_at"
+
+// scanRefreshToken reads one row selected with refreshTokenColumns.
+func scanRefreshToken(row rowScanner) (*model.RefreshToken, error) {
+	token := &model.RefreshToken{}
+	err := row.Scan(&token.ID, &token.UserID, &token.FamilyID, &token.TokenHash, &token.CreatedAt, &token.ExpiresAt, &token.UsedAt, &token.RevokedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// CreateRefreshToken inserts a new refresh token row.
+func (s *SQLStore) CreateRefreshToken(ctx context.Context, token *model.RefreshToken) error {
+	token.CreatedAt = time.Now().UTC()
+	id, err := s.insert(ctx,
+		"INSERT INTO refresh_tokens (user_id, family_id, token_hash, created_at, expires_at) VALUES (?, ?, ?, ?, ?)",
+		token.UserID, token.FamilyID, token.TokenHash, token.CreatedAt, token.ExpiresAt.UTC(),
+	)
+	if err != nil {
+		return err
+	}
+	token.ID = id
+	return nil
+}
+
+// GetRefreshToken looks a refresh token up by its hash.
+func (s *SQLStore) GetRefreshToken(ctx context.Context, hash string) (*model.RefreshToken, error) {
+	return scanRefreshToken(s.queryRow(ctx, "SELECT "+refreshTokenColumns+" FROM refresh_tokens WHERE token_hash = ?", hash))
+}
+
+// UseRefreshToken marks a token used in a single conditional UPDATE.
+func (s *SQLStore) UseRefreshToken(ctx context.Context, id uint64, now time.Time) (bool, error) {
+	res, err := s.exec(ctx,
+		"UPDATE refresh_tokens SET used_at = ? WHERE id = ? AND used_at IS NULL AND revoked_at IS NULL", now.UTC(), id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// RevokeTokenFamily revokes all live tokens of a family.
+func (s *SQLStore) RevokeTokenFamily(ctx context.Context, familyID string, now time.Time) error {
+	_, err := s.exec(ctx,
+		"UPDATE refresh_tokens SET revoked_at = ? WHERE family_id = ? AND revoked_at IS NULL", now.UTC(), familyID)
+	return err
+}
+
+// RevokeUserTokens revokes all live refresh tokens of a user.
+func (s *SQLStore) RevokeUserTokens(ctx context.Context, userID uint64, now time.Time) error {
+	_, err := s.exec(ctx,
+		"UPDATE refresh_tokens SET revoked_at = ? WHERE user_id = ? AND revoked_at IS NULL", now.UTC(), userID)
+	return err
+}
+
+// DeleteExpiredRefreshTokens removes refresh tokens past their expiry.
+func (s *SQLStore) DeleteExpiredRefreshTokens(ctx context.Context, now time.Time) (int64, error) {
+	res, err := s.exec(ctx, "DELETE FROM refresh_tokens WHERE expires_at < ?", now.UTC())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// apiKeyColumns is the column list matching scanAPIKey.
+const apiKeyColumns = "id, user_id, name, prefix, key_hash, scopes, created_at, last_used_at, expires_at, revoked_at"
+
+// scanAPIKey reads one row selected with apiKeyColumns.
+func scanAPIKey(row rowScanner) (*model.APIKey, error) {
+	key := &model.APIKey{}
+	var scopes string
+	err := row.Scan(&key.ID, &key.UserID, &key.Name, &key.Prefix, &key.KeyHash, &scopes, &key.CreatedAt, &key.LastUsedAt, &key.ExpiresAt, &key.RevokedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	key.Scopes = strings.Fields(scopes)
+	return key, nil
+}
+
+// CreateAPIKey inserts a new API key row.
+func (s *SQLStore) CreateAPIKey(ctx context.Context, key *model.APIKey) error {
+	key.CreatedAt = time.Now().UTC()
+	id, err := s.insert(ctx,
+		"INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes, created_at, expires_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		key.UserID, key.Name, key.Prefix, key.KeyHash, strings.Join(key.Scopes, " "), key.CreatedAt, key.ExpiresAt,
+	)
+	if err != nil {
+		return err
+	}
+	key.ID = id
+	return nil
+}
+
+// GetAPIKeyByHash looks an API key up by its hash.
+func (s *SQLStore) GetAPIKeyByHash(ctx context.Context, hash string) (*model.APIKey, error) {
+	return scanAPIKey(s.queryRow(ctx, "SELECT "+apiKeyColumns+" FROM api_keys WHERE key_hash = ?", hash))
+}
+
+// ListAPIKeys returns the user's live keys, newest first.
+func (s *SQLStore) ListAPIKeys(ctx context.Context, userID uint64) ([]*model.APIKey, error) {
+	rows, err := s.query(ctx,
+		"SELECT "+apiKeyColumns+" FROM api_keys WHERE user_id = ? AND revoked_at IS NULL ORDER BY id DESC", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []*model.APIKey
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// RevokeAPIKey marks an owned key as revoked.
+func (s *SQLStore) RevokeAPIKey(ctx context.Context, userID, id uint64, now time.Time) error {
+	res, err := s.exec(ctx,
+		"UPDATE api_keys SET revoked_at = ? WHERE id = ? AND user_id = ? AND revoked_at IS NULL", now.UTC(), id, userID)
+	if err != nil {
+		return err
+	}
+	return requireRow(res)
+}
+
+// TouchAPIKey updates last_used_at unless it was set within the last minute.
+func (s *SQLStore) TouchAPIKey(ctx context.Context, id uint64, now time.Time) error {
+	now = now.UTC()
+	_, err := s.exec(ctx,
+		"UPDATE api_keys SET last_used_at = ? WHERE id = ? AND (last_used_at IS NULL OR last_used_at < ?)",
+		now, id, now.Add(-time.Minute))
+	return err
+}
+
+// SetTOTPSecret stores a pending secret unless 2FA is already enabled.
+func (s *SQLStore) SetTOTPSecret(ctx context.Context, userID uint64, secret string) error {
+	res, err := s.exec(ctx,
+		"UPDATE users SET totp_secret = ? WHERE id = ? AND totp_enabled_at IS NULL", secret, userID)
+	if err != nil {
+		return err
+	}
+	return requireRow(res)
+}
+
+// EnableTOTP confirms the pending secret and stores the recovery codes in one transaction.
+func (s *SQLStore) EnableTOTP(ctx context.Context, userID uint64, secret string, step int64, hashes []string, at time.Time) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, s.dialect.rebind(
+		"UPDATE users SET totp_enabled_at = ?, totp_last_step = ? WHERE id = ? AND totp_secret = ? AND totp_enabled_at IS NULL"),
+		at.UTC(), step, userID, secret)
+	if err != nil {
+		return err
+	}
+	if err := requireRow(res); err != nil {
+		return err
+	}
+	if err := s.replaceRecoveryCodes(ctx, tx, userID, hashes); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DisableTOTP clears the TOTP columns and deletes the recovery codes in one transaction.
+func (s *SQLStore) DisableTOTP(ctx context.Context, userID uint64) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, s.dialect.rebind(
+		"UPDATE users SET totp_secret = '', totp_enabled_at = NULL, totp_last_step = 0 WHERE id = ?"), userID)
+	if err != nil {
+		return err
+	}
+	if err := requireRow(res); err != nil {
+		return err
+	}
+	if err := s.replaceRecoveryCodes(ctx, tx, userID, nil); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UseTOTPStep advances totp_last_step in a single conditional UPDATE.
+func (s *SQLStore) UseTOTPStep(ctx context.Context, userID uint64, step int64) (bool, error) {
+	res, err := s.exec(ctx,
+		"UPDATE users SET totp_last_step = ? WHERE id = ? AND totp_last_step < ?", step, userID, step)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// ReplaceRecoveryCodes swaps the user's recovery codes in one transaction.
+func (s *SQLStore) ReplaceRecoveryCodes(ctx context.Context, userID uint64, hashes []string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := s.replaceRecoveryCodes(ctx, tx, userID, hashes); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// replaceRecoveryCodes deletes the user's recovery codes and inserts hashes within tx.
+func (s *SQLStore) replaceRecoveryCodes(ctx context.Context, tx *sql.Tx, userID uint64, hashes []string) error {
+	if _, err := tx.ExecContext(ctx, s.dialect.rebind("DELETE FROM recovery_codes WHERE user_id = ?"), userID); err != nil {
+		return err
+	}
+	if len(hashes) == 0 {
+		return nil
+	}
+	rows := make([]string, 0, len(hashes))
+	args := make([]any, 0, 2*len(hashes))
+	for _, hash := range hashes {
+		rows = append(rows, "(?, ?)")
+		args = append(args, userID, hash)
+	}
+	_, err := tx.ExecContext(ctx, s.dialect.rebind("INSERT INTO recovery_codes (user_id, code_hash) VALUES "+strings.Join(rows, ", ")), args...)
+	return err
+}
+
+// UseRecoveryCode marks a code used in a single conditional UPDATE.
+func (s *SQLStore) UseRecoveryCode(ctx context.Context, userID uint64, hash string, now time.Time) (bool, error) {
+	res, err := s.exec(ctx,
+		"UPDATE recovery_codes SET used_at = ? WHERE user_id = ? AND code_hash = ? AND used_at IS NULL", now.UTC(), userID, hash)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// CountRecoveryCodes counts the user's unused recovery codes.
+func (s *SQLStore) CountRecoveryCodes(ctx context.Context, userID uint64) (int, error) {
+	var n int
+	err := s.queryRow(ctx, "SELECT COUNT(*) FROM recovery_codes WHERE user_id = ? AND used_at IS NULL", userID).Scan(&n)
+	return n, err
+}
+
+// SetMFARequired updates the user's mfa_required flag.
+func (s *SQLStore) SetMFARequired(ctx context.Context, userID uint64, required bool) error {
+	res, err := s.exec(ctx, "UPDATE users SET mfa_required = ? WHERE id = ?", required, userID)
+	if err != nil {
+		return err
+	}
+	return requireRow(res)
+}
@@ -0,0 +1,57 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"modernc.org/sqlite"             // Pure Go SQLite driver, works with CGO_ENABLED=0
+	sqlite3 "modernc.org/sqlite/lib" // SQLite result codes
+)
+
+// OpenSQLite opens (creating if needed) a SQLite database file.
+// The schema is applied separately by the migrate package.
+func OpenSQLite(path string) (*SQLStore, error) {
+	// Enforce foreign keys and wait on locks instead of failing with SQLITE_BUSY
+	dsn := fmt.Sprintf("file:%s?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)", path)
+
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	// SQLite allows a single writer; one connection avoids lock contention
+	db.SetMaxOpenConns(1)
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &SQLStore{db: db, dialect: sqliteDialect{}}, nil
+}
+
+// sqliteDialect maps SQLite driver errors for SQLStore.
+type sqliteDialect struct{}
+
+// name identifies the driver's migration set.
+func (sqliteDialect) name() string { return "sqlite" }
+
+// rebind is a no-op: the driver understands ? placeholders.
+func (sqliteDialect) rebind(query string) string { return query }
+
+// returningID is false: the driver implements LastInsertId.
+func (sqliteDialect) returningID() bool { return false }
+
+// duplicateKey detects unique constraint failures, whose message reads
+// "UNIQUE constraint failed: users.username".
+func (sqliteDialect) duplicateKey(err error) (string, bool) {
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE {
+		return sqliteErr.Error(), true
+	}
+	return "", false
+}
+
+// ignoreDuplicate uses ON CONFLICT DO NOTHING (SQLite 3.24 and later).
+func (sqliteDialect) ignoreDuplicate(string) string { return "ON CONFLICT DO NOTHING" }
@@ -0,0 +1,122 @@
+package store
+
+import (
+	"sort"
+	"time"
+
+	"github.com/ConstantineCTF/URLSecure/backend/internal/model"
+)
+
+// Rollup dimensions. dimTotal has a single empty value and counts all clicks.
+const (
+	dimTotal    = "total"
+	dimReferrer = "referrer"
+	dimCountry  = "country"
+	dimDevice   = "device"
+	dimBrowser  = "browser"
+	dimOS       = "os"
+)
+
+// rollupKey identifies one hourly counter.
+type rollupKey struct {
+	linkID    uint64
+	bucket    time.Time
+	dimension string
+	value     string
+}
+
+// addRollup counts e in every dimension of its hour. Bot visits are not rolled up.
+func addRollup(counts map[rollupKey]int, linkID uint64, e *model.ClickEvent) {
+	if e.Bot {
+		return
+	}
+	hour := e.ClickedAt.UTC().Truncate(time.Hour)
+	for dimension, value := range map[string]string{
+		dimTotal:    "",
+		dimReferrer: e.ReferrerHost,
+		dimCountry:  e.Country,
+		dimDevice:   e.Device,
+		dimBrowser:  e.Browser,
+		dimOS:       e.OS,
+	} {
+		counts[rollupKey{linkID, hour, dimension, value}]++
+	}
+}
+
+// statsRange aligns q's bounds to whole hours in UTC, as rollups are hourly.
+func statsRange(q StatsQuery) (from, to time.Time) {
+	from = q.From.UTC().Truncate(time.Hour)
+	to = q.To.UTC().Truncate(time.Hour)
+	if to.Before(q.To) {
+		to = to.Add(time.Hour)
+	}
+	return from, to
+}
+
+// bucketStart returns the start of the bucket containing t.
+func bucketStart(t time.Time, bucket string) time.Time {
+	t = t.UTC()
+	switch bucket {
+	case model.BucketDay:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	case model.BucketWeek:
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	default:
+		return t.Truncate(time.Hour)
+	}
+}
+
+// nextBucket returns the start of the bucket after the one starting at t.
+func nextBucket(t time.Time, bucket string) time.Time {
+	switch bucket {
+	case model.BucketDay:
+		return t.AddDate(0, 0, 1)
+	case model.BucketWeek:
+		return t.AddDate(0, 0, 7)
+	default:
+		return t.Add(time.Hour)
+	}
+}
+
+// buildStats assembles LinkStats from hourly totals and per-dimension sums over the range.
+func buildStats(q StatsQuery, hourly map[time.Time]int, breakdowns map[string]map[string]int) *model.LinkStats {
+	from, to := statsRange(q)
+
+	// Every bucket of the range appears in the series, empty or not
+	perBucket := make(map[time.Time]int)
+	stats := &model.LinkStats{}
+	for hour, clicks := range hourly {
+		perBucket[bucketStart(hour, q.Bucket)] += clicks
+		stats.Clicks += clicks
+	}
+	stats.Series = []model.SeriesPoint{}
+	for start := bucketStart(from, q.Bucket); start.Before(to); start = nextBucket(start, q.Bucket) {
+		stats.Series = append(stats.Series, model.SeriesPoint{Start: start, Clicks: perBucket[start]})
+	}
+
+	stats.Referrers = topValues(breakdowns[dimReferrer], q.Top)
+	stats.Countries = topValues(breakdowns[dimCountry], q.Top)
+	stats.Devices = topValues(breakdowns[dimDevice], q.Top)
+	stats.Browsers = topValues(breakdowns[dimBrowser], q.Top)
+	stats.OS = topValues(breakdowns[dimOS], q.Top)
+	return stats
+}
+
+// topValues returns the n values with the most clicks, ties broken by value.
+func topValues(counts map[string]int, n int) []model.Breakdown {
+	top := make([]model.Breakdown, 0, len(counts))
+	for value, clicks := range counts {
+		top = append(top, model.Breakdown{Value: value, Clicks: clicks})
+	}
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].Clicks != top[j].Clicks {
+			return top[i].Clicks > top[j].Clicks
+		}
+		return top[i].Value < top[j].Value
+	})
+	if n > 0 && len(top) > n {
+		top = top[:n]
+	}
+	return top
+}
@@ -0,0 +1,123 @@
+package store
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ConstantineCTF/URLSecure/backend/internal/model"
+)
+
+func TestClickStatsCountsRolledUpClicks(t *testing.T) {
+	ctx := context.Background()
+	hour := time.Date(2026, 3, 30, 10, 0, 0, 0, time.UTC)
+	click := func(offset time.Duration, referrer string, bot bool) model.ClickEvent {
+		return model.ClickEvent{Code: "abc123", ClickedAt: hour.Add(offset), ReferrerHost: referrer,
+			Browser: "Firefox", OS: "Linux", Device: "desktop", Country: "DE", Bot: bot}
+	}
+
+	for name, st := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			link := &model.URL{Code: "abc123", Target: "https://example.com/", UserID: 1}
+			if err := st.CreateLink(ctx, link); err != nil {
+				t.Fatal(err)
+			}
+			events := []model.ClickEvent{
+				click(5*time.Minute, "news.example", false),
+				click(10*time.Minute, "news.example", false),
+				click(20*time.Minute, "news.example", true),
+				click(50*time.Minute, "news.example", false),
+				click(65*time.Minute, "", false),
+				{Code: "gone", ClickedAt: hour}, // Unknown links are skipped
+			}
+			if err := st.RecordClicks(ctx, events); err != nil {
+				t.Fatal(err)
+			}
+			if err := st.CountClicks(ctx, []model.ClickCount{{Code: "abc123", Hour: hour.Add(2*time.Hour + 30*time.Minute), Clicks: 2}}); err != nil {
+				t.Fatal(err)
+			}
+
+			// Events are only purged once their clicks are in the counters
+			if purged, err := st.PurgeClickEvents(ctx, hour.AddDate(1, 0, 0)); err != nil || purged != 0 {
+				t.Fatalf("purge before rollup: %d events, %v; want none", purged, err)
+			}
+
+			// Batches stop at the limit and each event is rolled up once
+			total := 0
+			for {
+				n, err := st.RollupClicks(ctx, 2)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if n > 2 {
+					t.Fatalf("RollupClicks processed %d events, limit 2", n)
+				}
+				if n == 0 {
+					break
+				}
+				total += n
+			}
+			if total != 5 {
+				t.Fatalf("rolled up %d events, want 5", total)
+			}
+
+			q := StatsQuery{LinkID: link.ID, From: hour, To: hour.Add(3 * time.Hour), Bucket: model.BucketHour, Top: 5}
+			stats, err := st.ClickStats(ctx, q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stats.Clicks != 6 {
+				t.Errorf("Clicks = %d, want 6", stats.Clicks)
+			}
+			series := []model.SeriesPoint{
+				{Start: hour, Clicks: 3},
+				{Start: hour.Add(time.Hour), Clicks: 1},
+				{Start: hour.Add(2 * time.Hour), Clicks: 2},
+			}
+			if !equalSeries(stats.Series, series) {
+				t.Errorf("Series = %v, want %v", stats.Series, series)
+			}
+			// Anonymous counts have no detail, and bots are left out
+			referrers := []model.Breakdown{{Value: "news.example", Clicks: 3}, {Value: "", Clicks: 1}}
+			if !reflect.DeepEqual(stats.Referrers, referrers) {
+				t.Errorf("Referrers = %v, want %v", stats.Referrers, referrers)
+			}
+
+			// Purging the events past retention keeps their clicks in the totals
+			purged, err := st.PurgeClickEvents(ctx, hour.AddDate(1, 0, 0))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if purged != 5 {
+				t.Errorf("purged %d events, want 5", purged)
+			}
+			if stats, err := st.ClickStats(ctx, q); err != nil {
+				t.Fatal(err)
+			} else if stats.Clicks != 6 || !reflect.DeepEqual(stats.Referrers, referrers) {
+				t.Errorf("after purge: %d clicks, referrers %v; want 6 and %v", stats.Clicks, stats.Referrers, referrers)
+			}
+
+			// Daily buckets sum the hours
+			q.Bucket = model.BucketDay
+			if stats, err := st.ClickStats(ctx, q); err != nil {
+				t.Fatal(err)
+			} else if want := []model.SeriesPoint{{Start: hour.Truncate(24 * time.Hour), Clicks: 6}}; !equalSeries(stats.Series, want) {
+				t.Errorf("daily Series = %v, want %v", stats.Series, want)
+			}
+		})
+	}
+}
+
+// equalSeries reports whether a and b have the same clicks at the same instants.
+func equalSeries(a, b []model.SeriesPoint) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Start.Equal(b[i].Start) || a[i].Clicks != b[i].Clicks {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,266 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/ConstantineCTF/URLSecure/backend/internal/model"
+)
+
+// Errors returned by store implementations so handlers can map them to HTTP
+// responses without knowing which backend is in use.
+var (
+	ErrNotFound      = errors.New("store: not found")
+	ErrCodeTaken     = errors.New("store: short code already in use")
+	ErrUsernameTaken = errors.New("store: username already taken")
+	ErrEmailTaken    = errors.New("store: email already registered")
+	ErrDailyLimit    = errors.New("store: daily link limit reached")
+	ErrMonthlyLimit  = errors.New("store: monthly link limit reached")
+)
+
+// Link listing sort orders.
+const (
+	SortByCreated = "created"
+	SortByClicks  = "clicks"
+)
+
+// LinkQuery selects a page of a user's links for ListLinks.
+type LinkQuery struct {
+	UserID    uint64      // Owner; 0 matches every owner (operator tooling only)
+	Status    string      // Only links in this review state; empty matches all
+	Search    string      // Case-insensitive substring to match in the target URL
+	SortBy    string      // SortByCreated (default) or SortByClicks
+	Ascending bool        // Oldest/least clicked first instead of newest/most clicked
+	After     *LinkCursor // Continue after this position (keyset pagination)
+	Limit     int         // Maximum number of links to return
+}
+
+// LinkCursor is the position of the last link on a page. Clicks is only
+// used when sorting by clicks; ID breaks ties and orders by creation.
+type LinkCursor struct {
+	Clicks int
+	ID     uint64
+}
+
+// LinkStore persists shortened links.
+type LinkStore interface {
+	// CreateLink inserts a new link and fills in its ID and CreatedAt.
+	// Returns ErrCodeTaken if the short code already exists.
+	CreateLink(ctx context.Context, link *model.URL) error
+
+	// GetLinkByCode returns the link for a short code or ErrNotFound.
+	GetLinkByCode(ctx context.Context, code string) (*model.URL, error)
+
+	// ListLinks returns one page of a user's links as selected by q.
+	ListLinks(ctx context.Context, q LinkQuery) ([]*model.URL, error)
+
+	// UpdateLink saves the target, expiry, click limit, review state, preview flag and password of a link owned by link.UserID.
+	// Returns ErrNotFound if no such link belongs to that user.
+	UpdateLink(ctx context.Context, link *model.URL) error
+
+	// DeleteLink removes the link with code owned by userID, or returns ErrNotFound.
+	DeleteLink(ctx context.Context, userID uint64, code string) error
+
+	// IncrementClicks bumps the click counter of a link by one. It reports false
+	// without counting when the link has already reached its MaxClicks, and returns
+	// ErrNotFound if no link has the code (e.g. it was deleted since it was read).
+	IncrementClicks(ctx context.Context, code string) (bool, error)
+
+	// IncrementBotClicks bumps the bot visit counter of a link by one. Bot visits
+	// never count towards MaxClicks.
+	IncrementBotClicks(ctx context.Context, code string) error
+
+	// NextSequence atomically increments the named counter and returns its new value,
+	// starting at 1. It backs the sequential short code strategy.
+	NextSequence(ctx context.Context, name string) (uint64, error)
+}
+
+// UserStore persists user accounts.
+type UserStore interface {
+	// CreateUser inserts a new user and fills in its ID and CreatedAt.
+	// Returns ErrUsernameTaken or ErrEmailTaken on uniqueness violations.
+	CreateUser(ctx context.Context, user *model.User) error
+
+	// GetUserByLogin looks a user up by email or username, returning ErrNotFound if neither matches.
+	GetUserByLogin(ctx context.Context, identifier string) (*model.User, error)
+
+	// GetUserByID returns the user with the given ID or ErrNotFound.
+	GetUserByID(ctx context.Context, id uint64) (*model.User, error)
+
+	// SetUserRetention sets the user's own click event retention in days; nil falls
+	// back to their plan's. Returns ErrNotFound if the user does not exist.
+	SetUserRetention(ctx context.Context, userID uint64, days *int) error
+
+	// MarkEmailVerified records at as the time the user verified email. Returns
+	// ErrNotFound if the user's address is no longer email or was already verified.
+	MarkEmailVerified(ctx context.Context, userID uint64, email string, at time.Time) error
+
+	// SetUserPassword replaces the user's password hash. Returns ErrNotFound if the
+	// user does not exist.
+	SetUserPassword(ctx context.Context, userID uint64, hash string) error
+}
+
+// QuotaStore persists plans and per-user link creation counters.
+type QuotaStore interface {
+	// GetPlan returns the named plan or ErrNotFound.
+	GetPlan(ctx context.Context, name string) (*model.Plan, error)
+
+	// ListPlans returns all plans ordered by name.
+	ListPlans(ctx context.Context) ([]*model.Plan, error)
+
+	// SetUserPlan moves a user to another plan. Returns ErrNotFound if the user or plan does not exist.
+	SetUserPlan(ctx context.Context, userID uint64, plan string) error
+
+	// GetUsage returns the user's link creations in the UTC day and month containing
+	// now, and the number of their links that are live at now.
+	GetUsage(ctx context.Context, userID uint64, now time.Time) (model.Usage, error)
+
+	// ReserveLinkCreation counts one link creation against the user's day and month at
+	// now, unless either counter has reached its limit (nil means unlimited). Checking
+	// and counting are one atomic step; when a limit is full nothing is counted and the
+	// error is ErrDailyLimit or ErrMonthlyLimit. Deleting the link later does not give
+	// the quota back.
+	ReserveLinkCreation(ctx context.Context, userID uint64, now time.Time, daily, monthly *int) error
+
+	// ReleaseLinkCreation gives back a reservation made at now whose link could not be created.
+	ReleaseLinkCreation(ctx context.Context, userID uint64, now time.Time) error
+}
+
+// ClickStore persists click events for analytics.
+type ClickStore interface {
+	// RecordClicks stores a batch of click events, resolving each event's Code to its
+	// link and setting LinkID. Events for codes that no longer exist are dropped and
+	// keep a zero LinkID.
+	RecordClicks(ctx context.Context, events []model.ClickEvent) error
+
+	// RollupClicks folds up to limit click events that have not been rolled up yet into
+	// the hourly per-link counters read by ClickStats, and returns how many it processed.
+	// It is safe to run from several processes at once.
+	RollupClicks(ctx context.Context, limit int) (int, error)
+
+	// CountClicks adds clicks to the hourly totals without storing any events, for
+	// visitors who opted out of tracking. Counts for unknown codes are dropped.
+	CountClicks(ctx context.Context, counts []model.ClickCount) error
+
+	// ClickStats returns the analytics of one link over q's range.
+	ClickStats(ctx context.Context, q StatsQuery) (*model.LinkStats, error)
+
+	// PurgeClickEvents deletes rolled-up click events that are older at now than the
+	// retention of their link owner (see model.Plan.Retention), and returns how many
+	// it deleted. The hourly rollups are kept.
+	PurgeClickEvents(ctx context.Context, now time.Time) (int64, error)
+}
+
+// TokenStore persists refresh tokens.
+type TokenStore interface {
+	// CreateRefreshToken inserts a new token and fills in its ID and CreatedAt.
+	CreateRefreshToken(ctx context.Context, token *model.RefreshToken) error
+
+	// GetRefreshToken returns the token with the given hash or ErrNotFound.
+	GetRefreshToken(ctx context.Context, hash string) (*model.RefreshToken, error)
+
+	// UseRefreshToken marks a token as exchanged at now. It reports false without
+	// changing anything if the token was already used or revoked, so of two
+	// concurrent refreshes with the same token only one succeeds.
+	UseRefreshToken(ctx context.Context, id uint64, now time.Time) (bool, error)
+
+	// RevokeTokenFamily revokes every token of a family that is not revoked yet.
+	RevokeTokenFamily(ctx context.Context, familyID string, now time.Time) error
+
+	// RevokeUserTokens revokes every refresh token of a user, logging them out everywhere.
+	RevokeUserTokens(ctx context.Context, userID uint64, now time.Time) error
+
+	// DeleteExpiredRefreshTokens removes tokens that expired before now and returns
+	// how many it removed.
+	DeleteExpiredRefreshTokens(ctx context.Context, now time.Time) (int64, error)
+}
+
+// APIKeyStore persists personal API keys.
+type APIKeyStore interface {
+	// CreateAPIKey inserts a new key and fills in its ID and CreatedAt.
+	CreateAPIKey(ctx context.Context, key *model.APIKey) error
+
+	// GetAPIKeyByHash returns the key with the given hash or ErrNotFound.
+	GetAPIKeyByHash(ctx context.Context, hash string) (*model.APIKey, error)
+
+	// ListAPIKeys returns a user's keys that are not revoked, newest first.
+	ListAPIKeys(ctx context.Context, userID uint64) ([]*model.APIKey, error)
+
+	// RevokeAPIKey revokes the key with id owned by userID at now. Returns ErrNotFound
+	// if the user has no such key or it is already revoked.
+	RevokeAPIKey(ctx context.Context, userID, id uint64, now time.Time) error
+
+	// TouchAPIKey records a use of the key at now. To keep authenticated requests
+	// cheap, the timestamp is only written once per minute.
+	TouchAPIKey(ctx context.Context, id uint64, now time.Time) error
+}
+
+// MFAStore persists TOTP two-factor authentication and recovery codes. The TOTP
+// state itself lives on model.User.
+type MFAStore interface {
+	// SetTOTPSecret starts enrollment with a new secret, replacing any unconfirmed one.
+	// Returns ErrNotFound if the user does not exist or already has 2FA enabled.
+	SetTOTPSecret(ctx context.Context, userID uint64, secret string) error
+
+	// EnableTOTP confirms enrollment of secret with a code from time step step, and
+	// replaces the user's recovery codes with hashes. Returns ErrNotFound if secret is
+	// no longer the user's pending secret or 2FA is already enabled.
+	EnableTOTP(ctx context.Context, userID uint64, secret string, step int64, hashes []string, at time.Time) error
+
+	// DisableTOTP turns 2FA off, forgetting the secret and all recovery codes.
+	// Returns ErrNotFound if the user does not exist.
+	DisableTOTP(ctx context.Context, userID uint64) error
+
+	// UseTOTPStep records step as the last one a code was accepted from. It reports
+	// false without changing anything unless step is newer, so a code works only once.
+	UseTOTPStep(ctx context.Context, userID uint64, step int64) (bool, error)
+
+	// ReplaceRecoveryCodes deletes the user's recovery codes and stores hashes instead.
+	ReplaceRecoveryCodes(ctx context.Context, userID uint64, hashes []string) error
+
+	// UseRecoveryCode marks the user's unused code with hash as used at now. It reports
+	// false if there is no such code.
+	UseRecoveryCode(ctx context.Context, userID uint64, hash string, now time.Time) (bool, error)
+
+	// CountRecoveryCodes returns how many of the user's recovery codes are unused.
+	CountRecoveryCodes(ctx context.Context, userID uint64) (int, error)
+
+	// SetMFARequired sets whether the user must use 2FA. Returns ErrNotFound if the
+	// user does not exist.
+	SetMFARequired(ctx context.Context, userID uint64, required bool) error
+}
+
+// StatsQuery selects the range and shape of ClickStats.
+type StatsQuery struct {
+	LinkID uint64
+	From   time.Time // Inclusive; rounded down to the hour
+	To     time.Time // Exclusive; rounded up to the hour
+	Bucket string    // model.BucketHour, BucketDay or BucketWeek
+	Top    int       // Entries per breakdown
+}
+
+// usagePeriods returns the counter keys for the UTC day and month containing now.
+func usagePeriods(now time.Time) (day, month string) {
+	now = now.UTC()
+	return now.Format("2006-01-02"), now.Format("2006-01")
+}
+
+// Store is the full set of persistence interfaces implemented by every backend.
+type Store interface {
+	LinkStore
+	UserStore
+	QuotaStore
+	ClickStore
+	TokenStore
+	APIKeyStore
+	MFAStore
+	io.Closer
+}
+
+// Compile-time checks that both backends satisfy the store interfaces
+var (
+	_ Store = (*MemoryStore)(nil)
+	_ Store = (*SQLStore)(nil)
+)
@@ -0,0 +1,86 @@
+package uniques
+
+import (
+	"context"
+	"crypto/rand"
+	"sync"
+	"time"
+)
+
+// MemoryCounter is a process-local Counter with exact per-day sets. Salts live only in
+// memory, and days older than the retention are dropped as new ones start.
+type MemoryCounter struct {
+	mu        sync.Mutex
+	salts     map[string][]byte                         // Day -> salt
+	visitors  map[uint64]map[string]map[string]struct{} // Link ID -> day -> fingerprints
+	retention time.Duration
+}
+
+// NewMemoryCounter returns an empty counter keeping days for retention.
+func NewMemoryCounter(retention time.Duration) *MemoryCounter {
+	return &MemoryCounter{
+		salts:     make(map[string][]byte),
+		visitors:  make(map[uint64]map[string]map[string]struct{}),
+		retention: retention,
+	}
+}
+
+// Add implements Counter.
+func (m *MemoryCounter) Add(ctx context.Context, visits []Visit) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, v := range visits {
+		if v.IP == "" {
+			continue
+		}
+		d := day(v.At)
+		salt, ok := m.salts[d]
+		if !ok {
+			salt = make([]byte, 32)
+			rand.Read(salt)
+			m.salts[d] = salt
+			m.expire(v.At)
+		}
+		if m.visitors[v.LinkID] == nil {
+			m.visitors[v.LinkID] = make(map[string]map[string]struct{})
+		}
+		if m.visitors[v.LinkID][d] == nil {
+			m.visitors[v.LinkID][d] = make(map[string]struct{})
+		}
+		m.visitors[v.LinkID][d][fingerprint(salt, v)] = struct{}{}
+	}
+	return nil
+}
+
+// expire drops salts older than two days and counts older than the retention.
+func (m *MemoryCounter) expire(now time.Time) {
+	saltCutoff, countCutoff := day(now.AddDate(0, 0, -2)), day(now.Add(-m.retention))
+	for d := range m.salts {
+		if d < saltCutoff {
+			delete(m.salts, d)
+		}
+	}
+	for linkID, byDay := range m.visitors {
+		for d := range byDay {
+			if d < countCutoff {
+				delete(byDay, d)
+			}
+		}
+		if len(byDay) == 0 {
+			delete(m.visitors, linkID)
+		}
+	}
+}
+
+// Count implements Counter.
+func (m *MemoryCounter) Count(ctx context.Context, linkID uint64, from, to time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	n := 0
+	for _, d := range days(from, to) {
+		n += len(m.visitors[linkID][d]) // Fingerprints never repeat across days
+	}
+	return n, nil
+}
@@ -0,0 +1,137 @@
+package uniques
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8" // Redis client library
+)
+
+// saltTTL keeps a day's salt until visits of that day can no longer arrive late.
+const saltTTL = 48 * time.Hour
+
+// mergeTTL is how long a merged range stays in Redis for repeated queries.
+const mergeTTL = time.Minute
+
+// RedisCounter is a Counter shared by every replica using the same Redis. Each link
+// and day has a HyperLogLog (about 0.8% standard error, at most 12 KB); ranges are
+// counted by merging the days with PFMERGE. The salt of each day is created by the
+// first replica that needs it and expires shortly after the day ends.
+type RedisCounter struct {
+	client    *redis.Client
+	prefix    string
+	retention time.Duration
+
+	mu    sync.Mutex
+	salts map[string][]byte // Day -> salt, cached from Redis
+}
+
+// NewRedisCounter stores counts under "uniques:" keys on client, each kept for retention.
+func NewRedisCounter(client *redis.Client, retention time.Duration) *RedisCounter {
+	return &RedisCounter{client: client, prefix: "uniques:", retention: retention, salts: make(map[string][]byte)}
+}
+
+// key names a link's HyperLogLog for one day. The hash tag keeps all days of a link
+// in one Redis Cluster slot so they can be merged.
+func (r *RedisCounter) key(linkID uint64, d string) string {
+	return fmt.Sprintf("%s{%d}:%s", r.prefix, linkID, d)
+}
+
+// salt returns the day's salt, creating it if no replica has yet.
+func (r *RedisCounter) salt(ctx context.Context, d string) ([]byte, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if salt, ok := r.salts[d]; ok {
+		return salt, nil
+	}
+
+	fresh := make([]byte, 32)
+	rand.Read(fresh)
+	key := r.prefix + "salt:" + d
+	if err := r.client.SetNX(ctx, key, hex.EncodeToString(fresh), saltTTL).Err(); err != nil {
+		return nil, err
+	}
+	stored, err := r.client.Get(ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+	salt, err := hex.DecodeString(stored)
+	if err != nil {
+		return nil, err
+	}
+
+	r.remember(d, salt)
+	return salt, nil
+}
+
+// remember caches the salt of day d and forgets all but the two latest days: visits
+// arrive for today and, around midnight, yesterday. Callers hold r.mu.
+func (r *RedisCounter) remember(d string, salt []byte) {
+	r.salts[d] = salt
+	for len(r.salts) > 2 {
+		oldest := d
+		for cached := range r.salts {
+			oldest = min(oldest, cached) // ISO dates sort as strings
+		}
+		delete(r.salts, oldest)
+	}
+}
+
+// Add implements Counter with one PFADD per link and day.
+func (r *RedisCounter) Add(ctx context.Context, visits []Visit) error {
+	batches := make(map[string][]any)
+	for _, v := range visits {
+		if v.IP == "" {
+			continue
+		}
+		d := day(v.At)
+		salt, err := r.salt(ctx, d)
+		if err != nil {
+			return err
+		}
+		key := r.key(v.LinkID, d)
+		batches[key] = append(batches[key], fingerprint(salt, v))
+	}
+	if len(batches) == 0 {
+		return nil
+	}
+
+	pipe := r.client.Pipeline()
+	for key, fingerprints := range batches {
+		pipe.PFAdd(ctx, key, fingerprints...)
+		pipe.Expire(ctx, key, r.retention)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// Count implements Counter. A single day is read with PFCOUNT; longer ranges are
+// merged into a short-lived key first.
+func (r *RedisCounter) Count(ctx context.Context, linkID uint64, from, to time.Time) (int, error) {
+	list := days(from, to)
+	if len(list) == 0 {
+		return 0, nil
+	}
+	keys := make([]string, len(list))
+	for i, d := range list {
+		keys[i] = r.key(linkID, d)
+	}
+	if len(keys) == 1 {
+		n, err := r.client.PFCount(ctx, keys[0]).Result()
+		return int(n), err
+	}
+
+	dest := fmt.Sprintf("%s{%d}:range:%s:%s", r.prefix, linkID, list[0], list[len(list)-1])
+	pipe := r.client.TxPipeline()
+	pipe.PFMerge(ctx, dest, keys...)
+	pipe.Expire(ctx, dest, mergeTTL)
+	count := pipe.PFCount(ctx, dest)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return int(count.Val()), nil
+}
@@ -0,0 +1,55 @@
+// Package uniques estimates unique visitors per link and UTC day without keeping
+// anything that identifies a visitor. IP address and User-Agent are hashed with a
+// random salt that is replaced every day and never stored alongside the counts, so
+// fingerprints cannot be linked across days or traced back to an address. RedisCounter
+// keeps one HyperLogLog per link and day; MemoryCounter keeps exact sets for a single process.
+package uniques
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// Visit is one visit to feed into the counts.
+type Visit struct {
+	LinkID    uint64
+	At        time.Time
+	IP        string
+	UserAgent string
+}
+
+// Counter counts distinct visitors per link and day.
+type Counter interface {
+	// Add records visits; those without an IP are ignored.
+	Add(ctx context.Context, visits []Visit) error
+
+	// Count estimates the distinct visitors of a link over the UTC days from from to
+	// to, both inclusive. A visitor returning on another day counts again, because
+	// the salt of their fingerprint has changed.
+	Count(ctx context.Context, linkID uint64, from, to time.Time) (int, error)
+}
+
+// day is the UTC date of t, e.g. "2006-01-02", used in keys and salt lookups.
+func day(t time.Time) string {
+	return t.UTC().Format(time.DateOnly)
+}
+
+// days lists the UTC dates from from to to inclusive.
+func days(from, to time.Time) []string {
+	var list []string
+	start := from.UTC().Truncate(24 * time.Hour)
+	for d := start; !d.After(to.UTC()); d = d.AddDate(0, 0, 1) {
+		list = append(list, day(d))
+	}
+	return list
+}
+
+// fingerprint hashes a visitor's IP and User-Agent with the salt of the day.
+func fingerprint(salt []byte, v Visit) string {
+	mac := hmac.New(sha256.New, salt)
+	mac.Write([]byte(v.IP + "\x00" + v.UserAgent))
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
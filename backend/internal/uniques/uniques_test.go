@@ -0,0 +1,109 @@
+package uniques
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestRedisCounterKeepsTwoLatestSalts(t *testing.T) {
+	r := NewRedisCounter(nil, 0)
+	steps := []struct {
+		day  string
+		want []string
+	}{
+		{"2026-03-01", []string{"2026-03-01"}},
+		{"2026-03-02", []string{"2026-03-01", "2026-03-02"}},
+		// A late visit of yesterday must not evict today
+		{"2026-03-01", []string{"2026-03-01", "2026-03-02"}},
+		{"2026-03-03", []string{"2026-03-02", "2026-03-03"}},
+		// A visit older than both is served but not kept
+		{"2026-02-28", []string{"2026-03-02", "2026-03-03"}},
+	}
+	for _, step := range steps {
+		r.remember(step.day, []byte(step.day))
+		if got := slices.Sorted(maps.Keys(r.salts)); !slices.Equal(got, step.want) {
+			t.Fatalf("after %s: cached %v, want %v", step.day, got, step.want)
+		}
+	}
+}
+
+func TestMemoryCounter(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryCounter(30 * 24 * time.Hour)
+	monday := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	tuesday := monday.Add(24 * time.Hour)
+	visits := []Visit{
+		{LinkID: 1, At: monday, IP: "203.0.113.0", UserAgent: "Firefox"},
+		{LinkID: 1, At: monday.Add(time.Hour), IP: "203.0.113.0", UserAgent: "Firefox"}, // Same visitor
+		{LinkID: 1, At: monday, IP: "203.0.113.0", UserAgent: "Chrome"},                 // Same network, other browser
+		{LinkID: 1, At: monday, UserAgent: "Firefox"},                                   // No IP: not counted
+		{LinkID: 1, At: tuesday, IP: "203.0.113.0", UserAgent: "Firefox"},               // Returns the next day
+		{LinkID: 2, At: monday, IP: "198.51.100.0", UserAgent: "Firefox"},
+	}
+	if err := m.Add(ctx, visits); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		linkID   uint64
+		from, to time.Time
+		want     int
+	}{
+		{1, monday, monday, 2},
+		{1, tuesday, tuesday, 1},
+		{1, monday, tuesday, 3}, // Sum of daily uniques
+		{1, monday.Add(-48 * time.Hour), monday.Add(-24 * time.Hour), 0},
+		{2, monday, tuesday, 1},
+		{3, monday, tuesday, 0},
+	}
+	for _, tt := range tests {
+		got, err := m.Count(ctx, tt.linkID, tt.from, tt.to)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("Count(link %d, %s..%s) = %d, want %d", tt.linkID, day(tt.from), day(tt.to), got, tt.want)
+		}
+	}
+}
+
+func TestMemoryCounterExpires(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryCounter(7 * 24 * time.Hour)
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	if err := m.Add(ctx, []Visit{{LinkID: 1, At: start, IP: "203.0.113.0"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The first visit of a new day drops salts older than two days and counts past retention
+	later := start.AddDate(0, 0, 10)
+	if err := m.Add(ctx, []Visit{{LinkID: 2, At: later, IP: "203.0.113.0"}}); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := m.Count(ctx, 1, start, start); n != 0 {
+		t.Errorf("count past retention = %d, want 0", n)
+	}
+	if got := slices.Sorted(maps.Keys(m.salts)); !slices.Equal(got, []string{day(later)}) {
+		t.Errorf("salts kept for %v, want only %s", got, day(later))
+	}
+}
+
+func TestDays(t *testing.T) {
+	from := time.Date(2026, 2, 27, 23, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 3, 1, 0, 30, 0, 0, time.UTC)
+	want := []string{"2026-02-27", "2026-02-28", "2026-03-01"}
+	if got := days(from, to); !slices.Equal(got, want) {
+		t.Errorf("days = %v, want %v", got, want)
+	}
+	// Other time zones count by their UTC date
+	cet := time.FixedZone("CET", 3600)
+	if got := days(time.Date(2026, 3, 1, 0, 30, 0, 0, cet), time.Date(2026, 3, 1, 0, 30, 0, 0, cet)); !slices.Equal(got, []string{"2026-02-28"}) {
+		t.Errorf("days in CET = %v, want [2026-02-28]", got)
+	}
+	if got := days(to, from); len(got) != 0 {
+		t.Errorf("reversed range = %v, want none", got)
+	}
+}
@@ -1,8 +0,0 @@
-CREATE TABLE IF NOT EXISTS urls (
-  id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
-  code VARCHAR(16) NOT NULL UNIQUE,
-  target TEXT NOT NULL,
-  clicks BIGINT UNSIGNED NOT NULL DEFAULT 0,
-  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
-  PRIMARY KEY (id)
-) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -1,4 +0,0 @@
-ALTER TABLE users
-  ADD COLUMN username VARCHAR(50) NOT NULL UNIQUE AFTER id,
-  MODIFY COLUMN email    VARCHAR(255) NOT NULL UNIQUE,
-  MODIFY COLUMN name     VARCHAR(255) NULL DEFAULT NULL;
@@ -0,0 +1,13 @@
+// Package migrations embeds the SQL schema files so the binary can apply them without
+// the migrations directory being present on disk.
+//
+// Each driver has its own directory of numbered files named
+// NNN_description.up.sql and NNN_description.down.sql.
+package migrations
+
+import "embed"
+
+// FS holds the migrations for every supported driver under mysql/, postgres/ and sqlite/.
+//
+//go:embed mysql/*.sql postgres/*.sql sqlite/*.sql
+var FS embed.FS
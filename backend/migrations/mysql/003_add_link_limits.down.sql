@@ -0,0 +1,3 @@
+ALTER TABLE links
+  DROP COLUMN expires_at,
+  DROP COLUMN max_clicks;
@@ -0,0 +1,3 @@
+ALTER TABLE links
+  ADD COLUMN expires_at TIMESTAMP NULL DEFAULT NULL,
+  ADD COLUMN max_clicks BIGINT UNSIGNED NULL DEFAULT NULL;
@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS sequences (
+  name VARCHAR(64) NOT NULL,
+  current_value BIGINT UNSIGNED NOT NULL DEFAULT 0,
+  PRIMARY KEY (name)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
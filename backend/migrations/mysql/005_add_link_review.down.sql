@@ -0,0 +1,5 @@
+ALTER TABLE links
+  DROP INDEX idx_links_status,
+  DROP COLUMN status,
+  DROP COLUMN risk_score,
+  DROP COLUMN risk_reasons;
@@ -0,0 +1,5 @@
+ALTER TABLE links
+  ADD COLUMN status VARCHAR(16) NOT NULL DEFAULT 'active',
+  ADD COLUMN risk_score INT NOT NULL DEFAULT 0,
+  ADD COLUMN risk_reasons TEXT NULL,
+  ADD INDEX idx_links_status (status);
@@ -0,0 +1 @@
+ALTER TABLE links ADD COLUMN bot_clicks BIGINT UNSIGNED NOT NULL DEFAULT 0;
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS click_events (
+  id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  link_id BIGINT UNSIGNED NOT NULL,
+  clicked_at TIMESTAMP NOT NULL,
+  referrer_host VARCHAR(255) NOT NULL DEFAULT '',
+  browser VARCHAR(32) NOT NULL DEFAULT '',
+  os VARCHAR(32) NOT NULL DEFAULT '',
+  device VARCHAR(16) NOT NULL DEFAULT '',
+  language VARCHAR(35) NOT NULL DEFAULT '',
+  country CHAR(2) NOT NULL DEFAULT '',
+  bot BOOLEAN NOT NULL DEFAULT FALSE,
+  PRIMARY KEY (id),
+  INDEX idx_click_events_link_time (link_id, clicked_at),
+  FOREIGN KEY (link_id) REFERENCES links(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS click_rollups;
+ALTER TABLE click_events
+  DROP INDEX idx_click_events_rollup,
+  DROP COLUMN rolled_up,
+  DROP COLUMN visitor;
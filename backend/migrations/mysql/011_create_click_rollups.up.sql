@@ -0,0 +1,13 @@
+ALTER TABLE click_events
+  ADD COLUMN visitor VARCHAR(32) NOT NULL DEFAULT '',
+  ADD COLUMN rolled_up BOOLEAN NOT NULL DEFAULT FALSE,
+  ADD INDEX idx_click_events_rollup (rolled_up, id);
+CREATE TABLE IF NOT EXISTS click_rollups (
+  link_id BIGINT UNSIGNED NOT NULL,
+  bucket TIMESTAMP NOT NULL,
+  dimension VARCHAR(16) NOT NULL,
+  value VARCHAR(255) NOT NULL,
+  clicks BIGINT UNSIGNED NOT NULL DEFAULT 0,
+  PRIMARY KEY (link_id, bucket, dimension, value),
+  FOREIGN KEY (link_id) REFERENCES links(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
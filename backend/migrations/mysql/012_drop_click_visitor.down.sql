@@ -0,0 +1 @@
+ALTER TABLE click_events ADD COLUMN visitor VARCHAR(32) NOT NULL DEFAULT '';
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS links (
+  id BIGSERIAL PRIMARY KEY,
+  user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  code VARCHAR(16) NOT NULL UNIQUE,
+  target TEXT NOT NULL,
+  clicks BIGINT NOT NULL DEFAULT 0,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS links_user_id_idx ON links (user_id);
@@ -0,0 +1,3 @@
+ALTER TABLE links
+  ADD COLUMN expires_at TIMESTAMPTZ NULL DEFAULT NULL,
+  ADD COLUMN max_clicks BIGINT NULL DEFAULT NULL;
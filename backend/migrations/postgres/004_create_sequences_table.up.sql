@@ -0,0 +1,4 @@
+CREATE TABLE IF NOT EXISTS sequences (
+  name VARCHAR(64) PRIMARY KEY,
+  current_value BIGINT NOT NULL DEFAULT 0
+);
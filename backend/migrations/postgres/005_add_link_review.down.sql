@@ -0,0 +1,5 @@
+DROP INDEX idx_links_status;
+ALTER TABLE links
+  DROP COLUMN status,
+  DROP COLUMN risk_score,
+  DROP COLUMN risk_reasons;
@@ -0,0 +1 @@
+ALTER TABLE links ADD COLUMN preview BOOLEAN NOT NULL DEFAULT FALSE;
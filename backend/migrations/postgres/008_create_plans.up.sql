@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS plans (
+  name VARCHAR(32) PRIMARY KEY,
+  daily_links INT NULL DEFAULT NULL,
+  monthly_links INT NULL DEFAULT NULL,
+  max_active_links INT NULL DEFAULT NULL,
+  custom_aliases BOOLEAN NOT NULL DEFAULT FALSE,
+  password_links BOOLEAN NOT NULL DEFAULT FALSE,
+  analytics_retention_days INT NULL DEFAULT NULL
+);
+INSERT INTO plans (name, daily_links, monthly_links, max_active_links, custom_aliases, password_links, analytics_retention_days) VALUES
+  ('free', 50, 500, 200, FALSE, FALSE, 30),
+  ('pro', 1000, 20000, 10000, TRUE, TRUE, 365),
+  ('unlimited', NULL, NULL, NULL, TRUE, TRUE, NULL);
+ALTER TABLE users ADD COLUMN plan VARCHAR(32) NOT NULL DEFAULT 'free';
+CREATE TABLE IF NOT EXISTS link_usage (
+  user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  period VARCHAR(10) NOT NULL,
+  links INT NOT NULL DEFAULT 0,
+  PRIMARY KEY (user_id, period)
+);
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS click_events (
+  id BIGSERIAL PRIMARY KEY,
+  link_id BIGINT NOT NULL REFERENCES links(id) ON DELETE CASCADE,
+  clicked_at TIMESTAMPTZ NOT NULL,
+  referrer_host VARCHAR(255) NOT NULL DEFAULT '',
+  browser VARCHAR(32) NOT NULL DEFAULT '',
+  os VARCHAR(32) NOT NULL DEFAULT '',
+  device VARCHAR(16) NOT NULL DEFAULT '',
+  language VARCHAR(35) NOT NULL DEFAULT '',
+  country CHAR(2) NOT NULL DEFAULT '',
+  bot BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE INDEX IF NOT EXISTS click_events_link_time_idx ON click_events (link_id, clicked_at);
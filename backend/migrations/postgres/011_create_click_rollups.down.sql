@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS click_rollups;
+DROP INDEX IF EXISTS click_events_rollup_idx;
+ALTER TABLE click_events
+  DROP COLUMN rolled_up,
+  DROP COLUMN visitor;
@@ -0,0 +1,12 @@
+ALTER TABLE click_events
+  ADD COLUMN visitor VARCHAR(32) NOT NULL DEFAULT '',
+  ADD COLUMN rolled_up BOOLEAN NOT NULL DEFAULT FALSE;
+CREATE INDEX IF NOT EXISTS click_events_rollup_idx ON click_events (rolled_up, id);
+CREATE TABLE IF NOT EXISTS click_rollups (
+  link_id BIGINT NOT NULL REFERENCES links(id) ON DELETE CASCADE,
+  bucket TIMESTAMPTZ NOT NULL,
+  dimension VARCHAR(16) NOT NULL,
+  value VARCHAR(255) NOT NULL,
+  clicks BIGINT NOT NULL DEFAULT 0,
+  PRIMARY KEY (link_id, bucket, dimension, value)
+);
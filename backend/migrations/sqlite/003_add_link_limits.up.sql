@@ -0,0 +1,2 @@
+ALTER TABLE links ADD COLUMN expires_at TIMESTAMP NULL DEFAULT NULL;
+ALTER TABLE links ADD COLUMN max_clicks INTEGER NULL DEFAULT NULL;
@@ -0,0 +1,4 @@
+DROP INDEX idx_links_status;
+ALTER TABLE links DROP COLUMN risk_reasons;
+ALTER TABLE links DROP COLUMN risk_score;
+ALTER TABLE links DROP COLUMN status;
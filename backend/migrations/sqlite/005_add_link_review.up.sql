@@ -0,0 +1,4 @@
+ALTER TABLE links ADD COLUMN status TEXT NOT NULL DEFAULT 'active';
+ALTER TABLE links ADD COLUMN risk_score INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE links ADD COLUMN risk_reasons TEXT NULL DEFAULT NULL;
+CREATE INDEX idx_links_status ON links (status);
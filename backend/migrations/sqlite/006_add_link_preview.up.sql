@@ -0,0 +1 @@
+ALTER TABLE links ADD COLUMN preview INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS link_usage;
+ALTER TABLE users DROP COLUMN plan;
+DROP TABLE IF EXISTS plans;
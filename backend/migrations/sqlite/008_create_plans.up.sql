@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS plans (
+  name VARCHAR(32) PRIMARY KEY,
+  daily_links INTEGER NULL DEFAULT NULL,
+  monthly_links INTEGER NULL DEFAULT NULL,
+  max_active_links INTEGER NULL DEFAULT NULL,
+  custom_aliases INTEGER NOT NULL DEFAULT 0,
+  password_links INTEGER NOT NULL DEFAULT 0,
+  analytics_retention_days INTEGER NULL DEFAULT NULL
+);
+INSERT INTO plans (name, daily_links, monthly_links, max_active_links, custom_aliases, password_links, analytics_retention_days) VALUES
+  ('free', 50, 500, 200, 0, 0, 30),
+  ('pro', 1000, 20000, 10000, 1, 1, 365),
+  ('unlimited', NULL, NULL, NULL, 1, 1, NULL);
+ALTER TABLE users ADD COLUMN plan VARCHAR(32) NOT NULL DEFAULT 'free';
+CREATE TABLE IF NOT EXISTS link_usage (
+  user_id INTEGER NOT NULL,
+  period VARCHAR(10) NOT NULL,
+  links INTEGER NOT NULL DEFAULT 0,
+  PRIMARY KEY (user_id, period),
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
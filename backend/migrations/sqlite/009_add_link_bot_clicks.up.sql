@@ -0,0 +1 @@
+ALTER TABLE links ADD COLUMN bot_clicks INTEGER NOT NULL DEFAULT 0;
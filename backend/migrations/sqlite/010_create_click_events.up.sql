@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS click_events (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  link_id INTEGER NOT NULL,
+  clicked_at TIMESTAMP NOT NULL,
+  referrer_host TEXT NOT NULL DEFAULT '',
+  browser TEXT NOT NULL DEFAULT '',
+  os TEXT NOT NULL DEFAULT '',
+  device TEXT NOT NULL DEFAULT '',
+  language TEXT NOT NULL DEFAULT '',
+  country TEXT NOT NULL DEFAULT '',
+  bot INTEGER NOT NULL DEFAULT 0,
+  FOREIGN KEY (link_id) REFERENCES links(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_click_events_link_time ON click_events (link_id, clicked_at);
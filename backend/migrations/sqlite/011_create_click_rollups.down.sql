@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS click_rollups;
+DROP INDEX idx_click_events_rollup;
+ALTER TABLE click_events DROP COLUMN rolled_up;
+ALTER TABLE click_events DROP COLUMN visitor;
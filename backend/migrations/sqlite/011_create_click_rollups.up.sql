@@ -0,0 +1,12 @@
+ALTER TABLE click_events ADD COLUMN visitor TEXT NOT NULL DEFAULT '';
+ALTER TABLE click_events ADD COLUMN rolled_up INTEGER NOT NULL DEFAULT 0;
+CREATE INDEX idx_click_events_rollup ON click_events (rolled_up, id);
+CREATE TABLE IF NOT EXISTS click_rollups (
+  link_id INTEGER NOT NULL,
+  bucket TIMESTAMP NOT NULL,
+  dimension TEXT NOT NULL,
+  value TEXT NOT NULL,
+  clicks INTEGER NOT NULL DEFAULT 0,
+  PRIMARY KEY (link_id, bucket, dimension, value),
+  FOREIGN KEY (link_id) REFERENCES links(id) ON DELETE CASCADE
+);
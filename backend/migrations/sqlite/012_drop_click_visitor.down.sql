@@ -0,0 +1 @@
+ALTER TABLE click_events ADD COLUMN visitor TEXT NOT NULL DEFAULT '';
@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN analytics_retention_days INTEGER NULL DEFAULT NULL;
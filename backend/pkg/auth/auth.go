@@ -1,36 +1,19 @@
 package auth
 
 import (
-	"errors"
-	"os"
-	"time"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 
 	"github.com/golang-jwt/jwt/v5" // JWT library for token creation and parsing
-	"github.com/joho/godotenv"     // Load .env environment variables
 	"golang.org/x/crypto/bcrypt"   // Password hashing and comparison
 )
 
-var jwtKey []byte
-
-func init() {
-	// Load .env file to have access to environment variables like JWT_SECRET
-	_ = godotenv.Load()
-
-	// Get JWT secret key from environment
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		// Panic if secret is not set, as JWT cannot function without it
-		panic("JWT_SECRET environment variable not set")
-	}
-
-	// Convert secret to byte slice for signing JWTs
-	jwtKey = []byte(secret)
-}
-
 // Claims represents the payload stored inside JWT token
 type Claims struct {
-	UserID uint64 `json:"userId"` // User ID stored in token claims
-	jwt.RegisteredClaims            // Standard JWT claims (expires, issued at, etc.)
+	UserID               uint64 `json:"userId"` // User ID stored in token claims
+	jwt.RegisteredClaims        // Standard JWT claims (expires, issued at, etc.)
 }
 
 // HashPassword hashes a plaintext password using bcrypt algorithm
@@ -44,46 +27,44 @@ func CheckPassword(hash, password string) error {
 	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 }
 
-// CreateJWT creates a signed JWT token for a given user ID valid for 24 hours
-func CreateJWT(userID uint64) (string, error) {
-	expiration := time.Now().Add(24 * time.Hour)
-
-	claims := &Claims{
-		UserID: userID,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expiration), // Expiration time
-			IssuedAt:  jwt.NewNumericDate(time.Now()), // Issue time
-		},
-	}
+// RandomID returns 128 random bits, hex encoded.
+func RandomID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b) // crypto/rand never fails on supported platforms
+	return hex.EncodeToString(b)
+}
 
-	// Create token with claims using HMAC SHA256 signing method
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	
-	// Sign token string with secret key
-	return token.SignedString(jwtKey)
+// NewRefreshToken returns a random opaque refresh token and the hash to store for it.
+func NewRefreshToken() (token, hash string) {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, HashRefreshToken(token)
 }
 
-// ParseJWT parses and validates a JWT token string and returns claims
-func ParseJWT(tokenStr string) (*Claims, error) {
-	claims := &Claims{}
+// HashRefreshToken returns the SHA-256 of a refresh token, hex encoded. Refresh tokens
+// are random, so a fast unsalted hash is enough to make a leaked table useless.
+func HashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
 
-	// Parse token with claims, validating signature with jwtKey
-	token, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
-		// Enforce expected signing method
-		if token.Method != jwt.SigningMethodHS256 {
-			return nil, errors.New("unexpected signing method")
-		}
-		return jwtKey, nil
-	})
+// APIKeyPrefix starts every personal API key, telling them apart from JWTs (and
+// making leaked keys easy to find with secret scanners).
+const APIKeyPrefix = "usk_"
 
-	if err != nil {
-		return nil, err
-	}
+// apiKeyPrefixLen is how much of a key is stored in clear to identify it.
+const apiKeyPrefixLen = len(APIKeyPrefix) + 8
 
-	// Check token validity (expiration, signature, etc.)
-	if !token.Valid {
-		return nil, errors.New("invalid token")
-	}
+// NewAPIKey returns a random API key, its identifying prefix and the hash to store.
+func NewAPIKey() (key, prefix, hash string) {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	key = APIKeyPrefix + hex.EncodeToString(b)
+	return key, key[:apiKeyPrefixLen], HashAPIKey(key)
+}
 
-	return claims, nil
+// HashAPIKey returns the SHA-256 of an API key, hex encoded, like HashRefreshToken.
+func HashAPIKey(key string) string {
+	return HashRefreshToken(key)
 }
@@ -0,0 +1,358 @@
+package auth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Signing algorithms supported by KeyManager.
+const (
+	AlgHS256 = "HS256" // Shared secret; tokens can only be verified by URLSecure itself
+	AlgRS256 = "RS256" // RSA keys, published in the JWKS
+	AlgEdDSA = "EdDSA" // Ed25519 keys, published in the JWKS
+)
+
+// JWKSMaxAge is how long verifiers may cache the JWKS. A new key is published this
+// long before it starts signing, so every verifier knows it by then.
+const JWKSMaxAge = 5 * time.Minute
+
+// rsaKeyBits is the size of generated RSA keys.
+const rsaKeyBits = 2048
+
+// KeyConfig configures a KeyManager.
+type KeyConfig struct {
+	Algorithm string        // AlgHS256 (default), AlgRS256 or AlgEdDSA
+	Secret    string        // HMAC secret; required for HS256
+	Dir       string        // Directory of PEM private keys; required for RS256 and EdDSA
+	Rotation  time.Duration // Age at which a new key is generated; 0 never rotates
+	Overlap   time.Duration // How long a replaced key still verifies tokens; at least the token lifetime
+}
+
+// signingKey is one key and what it signs with.
+type signingKey struct {
+	kid     string
+	method  jwt.SigningMethod
+	private any       // []byte, *rsa.PrivateKey or ed25519.PrivateKey
+	public  any       // []byte, *rsa.PublicKey or ed25519.PublicKey
+	created time.Time // File modification time; zero for the HMAC secret
+	path    string    // Key file; empty for the HMAC secret
+}
+
+// KeyManager signs access tokens with the current key and verifies them with any key
+// that is still valid, identified by the token's kid header. Asymmetric keys live as
+// PEM files in a directory, which several instances may share.
+type KeyManager struct {
+	cfg KeyConfig
+
+	mu      sync.RWMutex
+	current *signingKey            // Key new tokens are signed with
+	keys    map[string]*signingKey // Keys accepted for verification, by kid
+}
+
+// NewKeyManager validates cfg and loads (or, for an empty directory, generates) the keys.
+func NewKeyManager(cfg KeyConfig) (*KeyManager, error) {
+	if cfg.Algorithm == "" {
+		cfg.Algorithm = AlgHS256
+	}
+	m := &KeyManager{cfg: cfg}
+
+	switch cfg.Algorithm {
+	case AlgHS256:
+		if cfg.Secret == "" {
+			return nil, errors.New("HS256 needs a secret")
+		}
+		sum := sha256.Sum256([]byte(cfg.Secret))
+		key := &signingKey{kid: "hs256-" + hex.EncodeToString(sum[:4]), method: jwt.SigningMethodHS256, private: []byte(cfg.Secret), public: []byte(cfg.Secret)}
+		m.current, m.keys = key, map[string]*signingKey{key.kid: key}
+		return m, nil
+	case AlgRS256, AlgEdDSA:
+		if cfg.Dir == "" {
+			return nil, fmt.Errorf("%s needs a key directory", cfg.Algorithm)
+		}
+		if err := os.MkdirAll(cfg.Dir, 0o700); err != nil {
+			return nil, err
+		}
+		if err := m.Refresh(time.Now()); err != nil {
+			return nil, err
+		}
+		return m, nil
+	default:
+		return nil, fmt.Errorf("unknown JWT algorithm %q (want %s, %s or %s)", cfg.Algorithm, AlgHS256, AlgRS256, AlgEdDSA)
+	}
+}
+
+// Refresh re-reads the key directory, generates a new key if the newest one is due for
+// rotation, deletes keys past their overlap and swaps in the result. On error the
+// previously loaded keys stay in effect. It does nothing for HS256.
+func (m *KeyManager) Refresh(now time.Time) error {
+	if m.cfg.Dir == "" {
+		return nil
+	}
+	keys, err := loadKeys(m.cfg.Dir)
+	if err != nil {
+		return err
+	}
+
+	// Only keys of the configured algorithm sign; others remain until they retire
+	var newest *signingKey
+	for _, key := range keys {
+		if key.method.Alg() == m.cfg.Algorithm {
+			newest = key
+		}
+	}
+	if newest == nil || (m.cfg.Rotation > 0 && now.Sub(newest.created) >= m.cfg.Rotation) {
+		key, err := generateKey(m.cfg.Dir, m.cfg.Algorithm, now)
+		if err != nil {
+			return fmt.Errorf("generate %s key: %w", m.cfg.Algorithm, err)
+		}
+		log.Printf("generated JWT signing key %s", key.kid)
+		keys = append(keys, key)
+	}
+
+	// Sign with the newest key that has been published long enough; on a fresh start
+	// there is none yet, so the newest key signs right away
+	var current *signingKey
+	for _, key := range keys {
+		if key.method.Alg() == m.cfg.Algorithm && (current == nil || now.Sub(key.created) >= JWKSMaxAge) {
+			current = key
+		}
+	}
+
+	// A key verifies until Overlap after its successor took over signing
+	valid := make(map[string]*signingKey, len(keys))
+	for i, key := range keys {
+		if key != current && i+1 < len(keys) && now.After(keys[i+1].created.Add(JWKSMaxAge+m.cfg.Overlap)) {
+			if err := os.Remove(key.path); err != nil {
+				log.Printf("failed to delete retired JWT key %s: %v", key.kid, err)
+			} else {
+				log.Printf("deleted retired JWT signing key %s", key.kid)
+			}
+			continue
+		}
+		valid[key.kid] = key
+	}
+
+	m.mu.Lock()
+	m.current, m.keys = current, valid
+	m.mu.Unlock()
+	return nil
+}
+
+// Watch calls Refresh every interval until ctx is done. It returns at once for HS256.
+func (m *KeyManager) Watch(ctx context.Context, interval time.Duration) {
+	if m.cfg.Dir == "" {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := m.Refresh(time.Now()); err != nil {
+				log.Printf("JWT key refresh failed: %v", err)
+			}
+		}
+	}
+}
+
+// CreateJWT creates a signed access token for a given user ID valid for ttl. Each
+// token gets a random ID (jti) so it can be revoked before it expires.
+func (m *KeyManager) CreateJWT(userID uint64, ttl time.Duration) (string, error) {
+	m.mu.RLock()
+	key := m.current
+	m.mu.RUnlock()
+
+	now := time.Now()
+	claims := &Claims{
+		UserID: userID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        RandomID(),                       // Token ID for the revocation denylist
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)), // Expiration time
+			IssuedAt:  jwt.NewNumericDate(now),          // Issue time
+		},
+	}
+
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.kid
+	return token.SignedString(key.private)
+}
+
+// ParseJWT parses and validates a JWT token string and returns claims. The kid header
+// selects the key, and the token must use that key's algorithm. HS256 tokens without
+// a kid (issued before keys had IDs) are checked against the secret.
+func (m *KeyManager) ParseJWT(tokenStr string) (*Claims, error) {
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (any, error) {
+		m.mu.RLock()
+		defer m.mu.RUnlock()
+
+		kid, _ := token.Header["kid"].(string)
+		key, ok := m.keys[kid]
+		if kid == "" && m.cfg.Algorithm == AlgHS256 {
+			key, ok = m.current, true
+		}
+		if !ok {
+			return nil, fmt.Errorf("unknown key %q", kid)
+		}
+		// Enforce the key's own signing method
+		if token.Method.Alg() != key.method.Alg() {
+			return nil, errors.New("unexpected signing method")
+		}
+		return key.public, nil
+	}, jwt.WithValidMethods([]string{AlgHS256, AlgRS256, AlgEdDSA}))
+	if err != nil {
+		return nil, err
+	}
+
+	// Check token validity (expiration, signature, etc.)
+	if !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+	return claims, nil
+}
+
+// JWK is one public key in a JSON Web Key Set (RFC 7517).
+type JWK struct {
+	Kty string `json:"kty"`           // RSA or OKP
+	Use string `json:"use"`           // Always "sig"
+	Alg string `json:"alg"`           // RS256 or EdDSA
+	Kid string `json:"kid"`           // Matches the kid header of tokens
+	N   string `json:"n,omitempty"`   // RSA modulus
+	E   string `json:"e,omitempty"`   // RSA exponent
+	Crv string `json:"crv,omitempty"` // OKP curve (Ed25519)
+	X   string `json:"x,omitempty"`   // OKP public key
+}
+
+// JWKS returns the public keys that currently verify tokens, for other services.
+// With HS256 it is empty: a shared secret must never be published.
+func (m *KeyManager) JWKS() []JWK {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	b64 := base64.RawURLEncoding.EncodeToString
+	keys := make([]JWK, 0, len(m.keys))
+	for _, key := range m.keys {
+		switch pub := key.public.(type) {
+		case *rsa.PublicKey:
+			keys = append(keys, JWK{Kty: "RSA", Use: "sig", Alg: AlgRS256, Kid: key.kid, N: b64(pub.N.Bytes()), E: b64(big.NewInt(int64(pub.E)).Bytes())})
+		case ed25519.PublicKey:
+			keys = append(keys, JWK{Kty: "OKP", Use: "sig", Alg: AlgEdDSA, Kid: key.kid, Crv: "Ed25519", X: b64(pub)})
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Kid < keys[j].Kid })
+	return keys
+}
+
+// loadKeys reads every *.pem file in dir, oldest first. The file name without the
+// extension is the kid and its modification time is the key's age.
+func loadKeys(dir string) ([]*signingKey, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]*signingKey, 0, len(paths))
+	for _, path := range paths {
+		key, err := loadKey(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if !keys[i].created.Equal(keys[j].created) {
+			return keys[i].created.Before(keys[j].created)
+		}
+		return keys[i].kid < keys[j].kid
+	})
+	return keys, nil
+}
+
+// loadKey parses one PEM private key (PKCS #8, or PKCS #1 for RSA).
+func loadKey(path string) (*signingKey, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, errors.New("no PEM block")
+	}
+
+	var private any
+	if block.Type == "RSA PRIVATE KEY" {
+		private, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	} else {
+		private, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key := &signingKey{kid: strings.TrimSuffix(filepath.Base(path), ".pem"), private: private, created: info.ModTime(), path: path}
+	switch private := private.(type) {
+	case *rsa.PrivateKey:
+		key.method, key.public = jwt.SigningMethodRS256, &private.PublicKey
+	case ed25519.PrivateKey:
+		key.method, key.public = jwt.SigningMethodEdDSA, private.Public()
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", private)
+	}
+	return key, nil
+}
+
+// generateKey creates a key for alg and writes it to dir. The kid is the creation time
+// plus a random suffix, so instances sharing the directory never collide.
+func generateKey(dir, alg string, now time.Time) (*signingKey, error) {
+	var private any
+	var err error
+	if alg == AlgRS256 {
+		private, err = rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	} else {
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	}
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return nil, err
+	}
+
+	// Write under a temporary name so other instances never read a partial file
+	kid := now.UTC().Format("20060102T150405Z") + "-" + RandomID()[:6]
+	path := filepath.Join(dir, kid+".pem")
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return nil, err
+	}
+	return loadKey(path)
+}
@@ -0,0 +1,200 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// tokenKid returns the kid header of a signed token without verifying it.
+func tokenKid(t *testing.T, token string) string {
+	t.Helper()
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, &Claims{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	kid, _ := parsed.Header["kid"].(string)
+	return kid
+}
+
+// createJWT signs a token for user 1, failing the test on error.
+func createJWT(t *testing.T, m *KeyManager) string {
+	t.Helper()
+	token, err := m.CreateJWT(1, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestKeyManagerRotation(t *testing.T) {
+	dir := t.TempDir()
+	m, err := NewKeyManager(KeyConfig{Algorithm: AlgEdDSA, Dir: dir, Rotation: time.Hour, Overlap: 15 * time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := createJWT(t, m)
+	oldKid := tokenKid(t, first)
+
+	// Age the key past the rotation interval
+	now := time.Now()
+	oldPath := filepath.Join(dir, oldKid+".pem")
+	if err := os.Chtimes(oldPath, now.Add(-2*time.Hour), now.Add(-2*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	// The new key is published but does not sign until verifiers had time to fetch it
+	if err := m.Refresh(now); err != nil {
+		t.Fatal(err)
+	}
+	if jwks := m.JWKS(); len(jwks) != 2 {
+		t.Fatalf("JWKS has %d keys after rotation, want 2", len(jwks))
+	}
+	if kid := tokenKid(t, createJWT(t, m)); kid != oldKid {
+		t.Fatalf("signing with %s before the JWKS cache expired, want %s", kid, oldKid)
+	}
+
+	// Then it takes over, and the old key still verifies during the overlap
+	if err := m.Refresh(now.Add(JWKSMaxAge + time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	second := createJWT(t, m)
+	if kid := tokenKid(t, second); kid == oldKid {
+		t.Fatal("new key did not take over signing")
+	}
+	for _, token := range []string{first, second} {
+		if _, err := m.ParseJWT(token); err != nil {
+			t.Fatalf("during overlap: %v", err)
+		}
+	}
+
+	// After the overlap the old key is deleted and its tokens are refused
+	if err := m.Refresh(now.Add(JWKSMaxAge + 16*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(oldPath); !os.IsNotExist(err) {
+		t.Fatalf("retired key file still exists: %v", err)
+	}
+	if _, err := m.ParseJWT(first); err == nil {
+		t.Fatal("token of a retired key accepted")
+	}
+	if _, err := m.ParseJWT(second); err != nil {
+		t.Fatalf("token of the current key: %v", err)
+	}
+	if jwks := m.JWKS(); len(jwks) != 1 || jwks[0].Kid != tokenKid(t, second) {
+		t.Fatalf("JWKS after retirement: %+v", jwks)
+	}
+
+	// Another instance sharing the directory picks up the same keys
+	other, err := NewKeyManager(KeyConfig{Algorithm: AlgEdDSA, Dir: dir, Rotation: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.ParseJWT(second); err != nil {
+		t.Fatalf("second instance: %v", err)
+	}
+}
+
+// validClaims are unexpired claims for user 1.
+func validClaims() *Claims {
+	return &Claims{UserID: 1, RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour))}}
+}
+
+// sign signs claims with method and key under kid; an empty kid leaves the header out.
+func sign(t *testing.T, method jwt.SigningMethod, key any, kid string, claims *Claims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestParseJWTRejectsKidAlgMismatch(t *testing.T) {
+	m, err := NewKeyManager(KeyConfig{Algorithm: AlgRS256, Dir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	kid := tokenKid(t, createJWT(t, m))
+
+	// HS256 keyed with the published RSA public key: the classic algorithm confusion
+	der, err := x509.MarshalPKIXPublicKey(m.keys[kid].public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	public := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	if _, err := m.ParseJWT(sign(t, jwt.SigningMethodHS256, public, kid, validClaims())); err == nil {
+		t.Fatal("HS256 token under an RS256 kid accepted")
+	}
+	if _, err := m.ParseJWT(sign(t, jwt.SigningMethodHS256, der, kid, validClaims())); err == nil {
+		t.Fatal("HS256 token keyed with the DER public key accepted")
+	}
+
+	// Without a kid there is no legacy fallback for asymmetric keys
+	if _, err := m.ParseJWT(sign(t, jwt.SigningMethodRS256, m.keys[kid].private, "", validClaims())); err == nil {
+		t.Fatal("RS256 token without kid accepted")
+	}
+	if _, err := m.ParseJWT(sign(t, jwt.SigningMethodRS256, m.keys[kid].private, kid, validClaims())); err != nil {
+		t.Fatalf("genuine token: %v", err)
+	}
+}
+
+func TestParseJWTHS256(t *testing.T) {
+	m, err := NewKeyManager(KeyConfig{Secret: "test secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	kid := tokenKid(t, createJWT(t, m))
+	if !strings.HasPrefix(kid, "hs256-") {
+		t.Fatalf("kid %q, want hs256- prefix", kid)
+	}
+	if jwks := m.JWKS(); len(jwks) != 0 {
+		t.Fatalf("JWKS publishes %d keys for a shared secret", len(jwks))
+	}
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired := validClaims()
+	expired.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+	none, err := jwt.NewWithClaims(jwt.SigningMethodNone, validClaims()).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		ok    bool
+	}{
+		{"current kid", createJWT(t, m), true},
+		{"no kid, issued before key IDs", sign(t, jwt.SigningMethodHS256, []byte("test secret"), "", validClaims()), true},
+		{"wrong secret", sign(t, jwt.SigningMethodHS256, []byte("other secret"), kid, validClaims()), false},
+		{"unknown kid", sign(t, jwt.SigningMethodHS256, []byte("test secret"), "hs256-00000000", validClaims()), false},
+		{"EdDSA under the HS256 kid", sign(t, jwt.SigningMethodEdDSA, edKey, kid, validClaims()), false},
+		{"alg none", none, false},
+		{"expired", sign(t, jwt.SigningMethodHS256, []byte("test secret"), kid, expired), false},
+	}
+	for _, tt := range tests {
+		claims, err := m.ParseJWT(tt.token)
+		if tt.ok && (err != nil || claims.UserID != 1) {
+			t.Errorf("%s: %v, want accepted", tt.name, err)
+		}
+		if !tt.ok && err == nil {
+			t.Errorf("%s: accepted", tt.name)
+		}
+	}
+}
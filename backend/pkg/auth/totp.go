@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TOTP parameters (RFC 6238). These are the defaults of every authenticator app;
+// several apps ignore other values in the provisioning URI.
+const (
+	TOTPDigits = 6
+	TOTPPeriod = 30 * time.Second
+	totpSkew   = 1 // Steps accepted either side of the current one, for clock drift
+)
+
+// RecoveryCodeCount is how many single-use recovery codes an account gets.
+const RecoveryCodeCount = 10
+
+// totpEncoding is the unpadded base32 authenticator apps expect secrets in.
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// NewTOTPSecret returns a random 160-bit secret (the size RFC 4226 recommends), base32 encoded.
+func NewTOTPSecret() string {
+	b := make([]byte, 20)
+	_, _ = rand.Read(b)
+	return totpEncoding.EncodeToString(b)
+}
+
+// TOTPStep returns the number of the time step containing t.
+func TOTPStep(t time.Time) int64 {
+	return t.Unix() / int64(TOTPPeriod/time.Second)
+}
+
+// TOTPCode returns the code for secret in time step step.
+func TOTPCode(secret string, step int64) (string, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", fmt.Errorf("invalid TOTP secret: %w", err)
+	}
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	// Dynamic truncation (RFC 4226 section 5.3)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", TOTPDigits, value%1000000), nil
+}
+
+// ValidateTOTP checks code against secret around now and returns the time step it
+// belongs to. Callers must reject steps at or before the last one accepted, so a
+// code cannot be used twice.
+func ValidateTOTP(secret, code string, now time.Time) (int64, bool) {
+	code = strings.ReplaceAll(code, " ", "")
+	if len(code) != TOTPDigits {
+		return 0, false
+	}
+	current := TOTPStep(now)
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		want, err := TOTPCode(secret, step)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(code), []byte(want)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// TOTPURI returns the otpauth:// provisioning URI that authenticator apps read from a
+// QR code, labelled "issuer:account".
+func TOTPURI(issuer, account, secret string) string {
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", issuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprint(TOTPDigits))
+	params.Set("period", fmt.Sprint(int(TOTPPeriod/time.Second)))
+	label := url.PathEscape(issuer) + ":" + url.PathEscape(account)
+	return "otpauth://totp/" + label + "?" + params.Encode()
+}
+
+// recoveryEncoding is Crockford's base32 in lower case: no i, l, o or u to confuse
+// with digits when a code is typed back from paper.
+var recoveryEncoding = base32.NewEncoding("0123456789abcdefghjkmnpqrstvwxyz").WithPadding(base32.NoPadding)
+
+// NewRecoveryCodes returns RecoveryCodeCount random codes formatted "xxxxx-xxxxx",
+// and the hashes to store for them.
+func NewRecoveryCodes() (codes, hashes []string) {
+	for range RecoveryCodeCount {
+		b := make([]byte, 7)
+		_, _ = rand.Read(b)
+		s := recoveryEncoding.EncodeToString(b)[:10] // 50 bits
+		code := s[:5] + "-" + s[5:]
+		codes = append(codes, code)
+		hashes = append(hashes, HashRecoveryCode(code))
+	}
+	return codes, hashes
+}
+
+// HashRecoveryCode returns the SHA-256 of a recovery code, hex encoded. Case, spaces
+// and dashes are ignored, so codes can be typed the way they were written down.
+func HashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	return HashRefreshToken(code)
+}
@@ -0,0 +1,135 @@
+package auth
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA-1 test key of RFC 6238 appendix B, "12345678901234567890", in base32.
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCodeRFC6238Vectors(t *testing.T) {
+	// Appendix B lists 8-digit codes; ours are their last 6 digits
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		got, err := TOTPCode(rfc6238Secret, TOTPStep(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("T=%d: code %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+
+	// Secrets are accepted in lower case, as some apps display them
+	if got, _ := TOTPCode(strings.ToLower(rfc6238Secret), 1); got != "287082" {
+		t.Errorf("lower-case secret: code %s", got)
+	}
+	if _, err := TOTPCode("not base32!", 1); err == nil {
+		t.Error("invalid secret accepted")
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	now := time.Unix(1111111109, 0) // Step 37037036, code 081804
+	current := TOTPStep(now)
+	previous, _ := TOTPCode(rfc6238Secret, current-1)
+	next, _ := TOTPCode(rfc6238Secret, current+1)
+	tooOld, _ := TOTPCode(rfc6238Secret, current-2)
+
+	tests := []struct {
+		code string
+		step int64
+		ok   bool
+	}{
+		{"081804", current, true},
+		{"081 804", current, true},
+		{previous, current - 1, true}, // Clock drift either way
+		{next, current + 1, true},
+		{tooOld, 0, false},
+		{"000000", 0, false},
+		{"81804", 0, false},
+		{"0818040", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		step, ok := ValidateTOTP(rfc6238Secret, tt.code, now)
+		if ok != tt.ok || step != tt.step {
+			t.Errorf("ValidateTOTP(%q) = %d, %t, want %d, %t", tt.code, step, ok, tt.step, tt.ok)
+		}
+	}
+}
+
+func TestNewTOTPSecret(t *testing.T) {
+	secret := NewTOTPSecret()
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil || len(key) != 20 {
+		t.Fatalf("secret %q decodes to %d bytes (%v), want 20", secret, len(key), err)
+	}
+	if NewTOTPSecret() == secret {
+		t.Fatal("two secrets are equal")
+	}
+}
+
+func TestTOTPURI(t *testing.T) {
+	u, err := url.Parse(TOTPURI("URL Secure", "alice@example.com", rfc6238Secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Scheme != "otpauth" || u.Host != "totp" || u.Path != "/URL Secure:alice@example.com" {
+		t.Fatalf("URI %s has the wrong type or label", u)
+	}
+	q := u.Query()
+	if q.Get("secret") != rfc6238Secret || q.Get("issuer") != "URL Secure" || q.Get("digits") != "6" || q.Get("period") != "30" || q.Get("algorithm") != "SHA1" {
+		t.Fatalf("URI parameters %v", q)
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, hashes := NewRecoveryCodes()
+	if len(codes) != RecoveryCodeCount || len(hashes) != RecoveryCodeCount {
+		t.Fatalf("got %d codes and %d hashes, want %d", len(codes), len(hashes), RecoveryCodeCount)
+	}
+	format := regexp.MustCompile(`^[0-9a-hjkmnp-tv-z]{5}-[0-9a-hjkmnp-tv-z]{5}$`)
+	seen := make(map[string]bool)
+	for i, code := range codes {
+		if !format.MatchString(code) {
+			t.Errorf("code %q is not xxxxx-xxxxx in Crockford base32", code)
+		}
+		if seen[code] {
+			t.Errorf("code %q issued twice", code)
+		}
+		seen[code] = true
+		if hashes[i] != HashRecoveryCode(code) {
+			t.Errorf("hash %d does not belong to code %d", i, i)
+		}
+		if hashes[i] == code || strings.Contains(hashes[i], strings.ReplaceAll(code, "-", "")) {
+			t.Errorf("hash %d contains the code", i)
+		}
+	}
+}
+
+func TestHashRecoveryCodeNormalizes(t *testing.T) {
+	want := HashRecoveryCode("abcde-fghjk")
+	for _, typed := range []string{"ABCDE-FGHJK", "abcdefghjk", "abcde fghjk", " abcde-fghjk "} {
+		if got := HashRecoveryCode(typed); got != want {
+			t.Errorf("HashRecoveryCode(%q) differs from the issued form", typed)
+		}
+	}
+	if HashRecoveryCode("abcde-fghjm") == want {
+		t.Error("different codes hash equal")
+	}
+}
@@ -0,0 +1,248 @@
+// Package blocklist matches URLs against locally stored threat-intelligence lists:
+// hosts files, plain domain lists and Safe-Browsing-style SHA-256 hash prefixes.
+// Lists are read from disk and can be reloaded at runtime without a restart.
+package blocklist
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Supported list formats.
+const (
+	FormatHosts   = "hosts"   // "0.0.0.0 evil.example" lines, as used by ad/malware hosts files
+	FormatDomains = "domains" // One domain per line; subdomains are blocked too
+	FormatHashes  = "hashes"  // Hex SHA-256 prefixes (4-32 bytes) of canonical URL expressions
+)
+
+// Source is one list file on disk.
+type Source struct {
+	Format string
+	Path   string
+}
+
+// ParseSources parses "format:path" entries, e.g. "hosts:/etc/urlsecure/malware.hosts".
+func ParseSources(entries []string) ([]Source, error) {
+	sources := make([]Source, 0, len(entries))
+	for _, entry := range entries {
+		format, path, ok := strings.Cut(entry, ":")
+		if !ok || path == "" {
+			return nil, fmt.Errorf("blocklist entry %q must look like format:path", entry)
+		}
+		switch format {
+		case FormatHosts, FormatDomains, FormatHashes:
+		default:
+			return nil, fmt.Errorf("blocklist entry %q: unknown format %q", entry, format)
+		}
+		sources = append(sources, Source{Format: format, Path: path})
+	}
+	return sources, nil
+}
+
+// Match describes why a URL is blocked.
+type Match struct {
+	Source string // Base name of the list file that matched
+	Rule   string // Matching domain or hash prefix
+}
+
+// snapshot is an immutable set of loaded rules, swapped atomically on reload.
+type snapshot struct {
+	domains  map[string]string         // Domain -> source name
+	prefixes map[int]map[string]string // Prefix length in bytes -> hex prefix -> source name
+}
+
+// List is a reloadable blocklist. The zero value is not usable; use New.
+// A List with no sources is valid and never matches.
+type List struct {
+	sources []Source
+
+	mu     sync.RWMutex
+	rules  *snapshot
+	mtimes map[string]time.Time // Modification times seen at the last load
+}
+
+// New loads all sources. It fails if any file cannot be read or parsed.
+func New(sources []Source) (*List, error) {
+	l := &List{sources: sources}
+	if err := l.Reload(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Reload re-reads every source and swaps in the new rules. On error the
+// previously loaded rules stay in effect.
+func (l *List) Reload() error {
+	rules := &snapshot{domains: make(map[string]string), prefixes: make(map[int]map[string]string)}
+	mtimes := make(map[string]time.Time, len(l.sources))
+
+	for _, src := range l.sources {
+		info, err := os.Stat(src.Path)
+		if err != nil {
+			return err
+		}
+		mtimes[src.Path] = info.ModTime()
+
+		if err := rules.load(src); err != nil {
+			return fmt.Errorf("%s: %w", src.Path, err)
+		}
+	}
+
+	l.mu.Lock()
+	l.rules, l.mtimes = rules, mtimes
+	l.mu.Unlock()
+	return nil
+}
+
+// Watch polls the source files every interval and reloads when any of them
+// changed. It returns when ctx is cancelled.
+func (l *List) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !l.changed() {
+				continue
+			}
+			if err := l.Reload(); err != nil {
+				log.Printf("blocklist reload failed: %v", err)
+				continue
+			}
+			log.Printf("blocklist reloaded: %d domains", l.Size())
+		}
+	}
+}
+
+// changed reports whether any source file's modification time differs from the last load.
+func (l *List) changed() bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	for _, src := range l.sources {
+		info, err := os.Stat(src.Path)
+		if err != nil || !info.ModTime().Equal(l.mtimes[src.Path]) {
+			return true
+		}
+	}
+	return false
+}
+
+// Size returns the number of loaded domain rules.
+func (l *List) Size() int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return len(l.rules.domains)
+}
+
+// Match reports whether rawURL is covered by any loaded rule.
+func (l *List) Match(rawURL string) (Match, bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Hostname() == "" {
+		return Match{}, false
+	}
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+
+	l.mu.RLock()
+	rules := l.rules
+	l.mu.RUnlock()
+
+	// The host itself or any parent domain
+	for d := host; d != ""; {
+		if src, ok := rules.domains[d]; ok {
+			return Match{Source: src, Rule: d}, true
+		}
+		_, parent, ok := strings.Cut(d, ".")
+		if !ok {
+			break
+		}
+		d = parent
+	}
+
+	if len(rules.prefixes) == 0 {
+		return Match{}, false
+	}
+	for _, expr := range expressions(host, u) {
+		sum := sha256.Sum256([]byte(expr))
+		for n, set := range rules.prefixes {
+			prefix := hex.EncodeToString(sum[:n])
+			if src, ok := set[prefix]; ok {
+				return Match{Source: src, Rule: prefix}, true
+			}
+		}
+	}
+	return Match{}, false
+}
+
+// load parses one source file into the snapshot.
+func (s *snapshot) load(src Source) error {
+	f, err := os.Open(src.Path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	name := filepath.Base(src.Path)
+	scanner := bufio.NewScanner(f)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch src.Format {
+		case FormatHosts:
+			// First field is the sink address; the rest are hostnames
+			for _, host := range fields[1:] {
+				s.addDomain(host, name)
+			}
+		case FormatDomains:
+			s.addDomain(fields[0], name)
+		case FormatHashes:
+			prefix := strings.ToLower(fields[0])
+			raw, err := hex.DecodeString(prefix)
+			if err != nil || len(raw) < 4 || len(raw) > sha256.Size {
+				return fmt.Errorf("line %d: expected 8-64 hex characters", lineNo)
+			}
+			if s.prefixes[len(raw)] == nil {
+				s.prefixes[len(raw)] = make(map[string]string)
+			}
+			s.prefixes[len(raw)][prefix] = name
+		}
+	}
+	return scanner.Err()
+}
+
+// hostsFileNoise are names every hosts file maps to loopback; they are not threats.
+var hostsFileNoise = map[string]bool{
+	"localhost": true, "localhost.localdomain": true, "local": true, "broadcasthost": true,
+	"ip6-localhost": true, "ip6-loopback": true, "ip6-localnet": true, "ip6-mcastprefix": true,
+	"ip6-allnodes": true, "ip6-allrouters": true, "ip6-allhosts": true, "0.0.0.0": true,
+}
+
+// addDomain records a domain rule, accepting "*.example.com" and ".example.com" forms.
+func (s *snapshot) addDomain(domain, source string) {
+	domain = strings.TrimSuffix(strings.ToLower(domain), ".")
+	domain = strings.TrimPrefix(strings.TrimPrefix(domain, "*"), ".")
+	if domain == "" || hostsFileNoise[domain] {
+		return
+	}
+	s.domains[domain] = source
+}
@@ -0,0 +1,137 @@
+package blocklist
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestExpressions(t *testing.T) {
+	// The examples of the Safe Browsing v4 URL hashing documentation
+	tests := []struct {
+		url  string
+		want []string
+	}{
+		{"http://a.b.c/1/2.html?param=1", []string{
+			"a.b.c/1/2.html?param=1", "a.b.c/1/2.html", "a.b.c/", "a.b.c/1/",
+			"b.c/1/2.html?param=1", "b.c/1/2.html", "b.c/", "b.c/1/",
+		}},
+		{"http://a.b.c.d.e.f.g/1.html", []string{
+			"a.b.c.d.e.f.g/1.html", "a.b.c.d.e.f.g/",
+			"c.d.e.f.g/1.html", "c.d.e.f.g/",
+			"d.e.f.g/1.html", "d.e.f.g/",
+			"e.f.g/1.html", "e.f.g/",
+			"f.g/1.html", "f.g/",
+		}},
+		{"http://1.2.3.4/1/", []string{"1.2.3.4/1/", "1.2.3.4/"}},
+		{"http://example.com", []string{"example.com/"}},
+	}
+	for _, tt := range tests {
+		u, err := url.Parse(tt.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := expressions(u.Hostname(), u); !slices.Equal(got, tt.want) {
+			t.Errorf("expressions(%s) =\n%q\nwant\n%q", tt.url, got, tt.want)
+		}
+	}
+}
+
+// writeList writes content to name in dir and returns its path.
+func writeList(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// hashPrefix returns the hex SHA-256 prefix of n bytes of expr.
+func hashPrefix(expr string, n int) string {
+	sum := sha256.Sum256([]byte(expr))
+	return hex.EncodeToString(sum[:n])
+}
+
+func TestMatch(t *testing.T) {
+	dir := t.TempDir()
+	hosts := writeList(t, dir, "malware.hosts", `# Comment
+127.0.0.1 localhost
+0.0.0.0 evil.example tracker.example # Trailing comment
+`)
+	domains := writeList(t, dir, "phish.txt", "*.phish.example\nBAD.example.\n")
+	hashes := writeList(t, dir, "sb.hashes", hashPrefix("hashed.example/login/", 4)+"\n"+hashPrefix("whole.example/", 32)+"\n")
+
+	list, err := New([]Source{{FormatHosts, hosts}, {FormatDomains, domains}, {FormatHashes, hashes}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		url    string
+		source string // Empty when the URL must not match
+	}{
+		{"http://evil.example/", "malware.hosts"},
+		{"https://cdn.tracker.example/x.js", "malware.hosts"},
+		{"https://www.phish.example/login", "phish.txt"},
+		{"https://phish.example/", "phish.txt"},
+		{"https://bad.example./", "phish.txt"},
+		{"https://hashed.example/login/form?next=1", "sb.hashes"},
+		{"https://a.hashed.example/login/", "sb.hashes"},
+		{"https://whole.example/any/path", "sb.hashes"},
+		{"https://hashed.example/logout/", ""},
+		{"http://localhost/", ""},
+		{"https://notevil.example/", ""},
+		{"https://example.com/", ""},
+		{"not a url", ""},
+	}
+	for _, tt := range tests {
+		match, ok := list.Match(tt.url)
+		if tt.source == "" {
+			if ok {
+				t.Errorf("Match(%s) = %+v, want no match", tt.url, match)
+			}
+			continue
+		}
+		if !ok || match.Source != tt.source {
+			t.Errorf("Match(%s) = %+v, %t, want source %s", tt.url, match, ok, tt.source)
+		}
+	}
+}
+
+func TestReloadKeepsRulesOnError(t *testing.T) {
+	dir := t.TempDir()
+	path := writeList(t, dir, "list.hashes", hashPrefix("evil.example/", 4)+"\n")
+	list, err := New([]Source{{FormatHashes, path}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeList(t, dir, "list.hashes", "not hex\n")
+	if err := list.Reload(); err == nil {
+		t.Fatal("Reload accepted a malformed list")
+	}
+	if _, ok := list.Match("http://evil.example/"); !ok {
+		t.Fatal("rules were dropped by a failed reload")
+	}
+}
+
+func TestParseSources(t *testing.T) {
+	sources, err := ParseSources([]string{"hosts:/etc/a.hosts", "hashes:C:/lists/b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Source{{FormatHosts, "/etc/a.hosts"}, {FormatHashes, "C:/lists/b"}}
+	if !slices.Equal(sources, want) {
+		t.Fatalf("ParseSources = %+v, want %+v", sources, want)
+	}
+	for _, bad := range []string{"/etc/a.hosts", "hosts:", "csv:/etc/a"} {
+		if _, err := ParseSources([]string{bad}); err == nil {
+			t.Errorf("ParseSources(%q) succeeded", bad)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package blocklist
+
+import (
+	"net/netip"
+	"net/url"
+	"strings"
+)
+
+// expressions returns the host-suffix/path-prefix combinations that Safe Browsing
+// hashes for a URL: up to 5 host variants times up to 6 path variants, e.g. for
+// http://a.b.c/1/2.html?param=1 it yields "a.b.c/1/2.html?param=1", "a.b.c/1/2.html",
+// "a.b.c/", "a.b.c/1/", "b.c/1/2.html?param=1", and so on.
+func expressions(host string, u *url.URL) []string {
+	hosts := []string{host}
+
+	// IP hosts are only checked as-is; names also by their last 2-5 labels
+	if _, err := netip.ParseAddr(host); err != nil {
+		labels := strings.Split(host, ".")
+		start := max(1, len(labels)-5)
+		for i := start; i < len(labels)-1 && len(hosts) < 5; i++ {
+			hosts = append(hosts, strings.Join(labels[i:], "."))
+		}
+	}
+
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	paths := []string{}
+	if u.RawQuery != "" {
+		paths = append(paths, path+"?"+u.RawQuery)
+	}
+	paths = append(paths, path)
+
+	// Directory prefixes: "/", "/1/", "/1/2/" ... at most 4 in total
+	prefix := "/"
+	if path != "/" {
+		paths = append(paths, prefix)
+	}
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i := 0; i < len(segments)-1 && len(paths) < 6; i++ {
+		prefix += segments[i] + "/"
+		if prefix != path {
+			paths = append(paths, prefix)
+		}
+	}
+
+	exprs := make([]string, 0, len(hosts)*len(paths))
+	for _, h := range hosts {
+		for _, p := range paths {
+			exprs = append(exprs, h+p)
+		}
+	}
+	return exprs
+}
@@ -0,0 +1,142 @@
+// Package botdetect classifies HTTP clients by User-Agent: search and SEO crawlers,
+// link unfurlers (chat apps and social networks fetching a preview), headless
+// browsers and HTTP libraries. It is a heuristic for keeping analytics honest,
+// not an access control; any client can send any User-Agent.
+package botdetect
+
+import "strings"
+
+// Client kinds returned in a Result.
+const (
+	KindHuman    = "human"    // Looks like a regular browser
+	KindCrawler  = "crawler"  // Search engines, SEO tools, AI scrapers
+	KindUnfurler = "unfurler" // Fetches a link to render a preview card
+	KindHeadless = "headless" // Automated or headless browsers
+	KindTool     = "tool"     // Command line tools and HTTP libraries
+	KindUnknown  = "unknown"  // No User-Agent at all
+)
+
+// Result is the classification of one User-Agent.
+type Result struct {
+	Bot  bool   // Anything but KindHuman
+	Kind string // One of the Kind constants
+	Name string // Product that matched, e.g. "Slackbot"; empty for generic matches
+}
+
+// signature is a lowercase User-Agent substring identifying a client.
+type signature struct {
+	token string
+	name  string
+	kind  string
+}
+
+// signatures are checked in order, so specific products come before the generic
+// "bot"/"crawl"/"spider" catch-alls.
+var signatures = []signature{
+	// Unfurlers
+	{"slackbot", "Slackbot", KindUnfurler},
+	{"slack-imgproxy", "Slackbot", KindUnfurler},
+	{"twitterbot", "Twitterbot", KindUnfurler},
+	{"facebookexternalhit", "Facebook", KindUnfurler},
+	{"facebookcatalog", "Facebook", KindUnfurler},
+	{"linkedinbot", "LinkedInBot", KindUnfurler},
+	{"discordbot", "Discordbot", KindUnfurler},
+	{"telegrambot", "TelegramBot", KindUnfurler},
+	{"whatsapp/", "WhatsApp", KindUnfurler},
+	{"skypeuripreview", "Skype", KindUnfurler},
+	{"microsoftpreview", "Microsoft Teams", KindUnfurler},
+	{"redditbot", "Redditbot", KindUnfurler},
+	{"pinterestbot", "Pinterestbot", KindUnfurler},
+	{"embedly", "Embedly", KindUnfurler},
+	{"iframely", "Iframely", KindUnfurler},
+	{"vkshare", "VK", KindUnfurler},
+	{"mastodon/", "Mastodon", KindUnfurler},
+	{"bluesky cardyb", "Bluesky", KindUnfurler},
+	{"google-pagerenderer", "Google Page Renderer", KindUnfurler},
+
+	// Headless and automated browsers
+	{"headlesschrome", "HeadlessChrome", KindHeadless},
+	{"phantomjs", "PhantomJS", KindHeadless},
+	{"chrome-lighthouse", "Lighthouse", KindHeadless},
+	{"selenium", "Selenium", KindHeadless},
+	{"puppeteer", "Puppeteer", KindHeadless},
+	{"playwright", "Playwright", KindHeadless},
+
+	// Named crawlers
+	{"googlebot", "Googlebot", KindCrawler},
+	{"google-inspectiontool", "Googlebot", KindCrawler},
+	{"bingbot", "Bingbot", KindCrawler},
+	{"bingpreview", "Bingbot", KindCrawler},
+	{"duckduckbot", "DuckDuckBot", KindCrawler},
+	{"yandex", "YandexBot", KindCrawler},
+	{"baiduspider", "Baiduspider", KindCrawler},
+	{"yahoo! slurp", "Yahoo Slurp", KindCrawler},
+	{"applebot", "Applebot", KindCrawler},
+	{"petalbot", "PetalBot", KindCrawler},
+	{"ahrefsbot", "AhrefsBot", KindCrawler},
+	{"semrushbot", "SemrushBot", KindCrawler},
+	{"mj12bot", "MJ12bot", KindCrawler},
+	{"dotbot", "DotBot", KindCrawler},
+	{"gptbot", "GPTBot", KindCrawler},
+	{"claudebot", "ClaudeBot", KindCrawler},
+	{"ccbot", "CCBot", KindCrawler},
+	{"bytespider", "Bytespider", KindCrawler},
+	{"amazonbot", "Amazonbot", KindCrawler},
+	{"scrapy", "Scrapy", KindCrawler},
+
+	// Tools and libraries
+	{"curl/", "curl", KindTool},
+	{"wget/", "Wget", KindTool},
+	{"httpie/", "HTTPie", KindTool},
+	{"python-requests", "python-requests", KindTool},
+	{"python-urllib", "urllib", KindTool},
+	{"python-httpx", "httpx", KindTool},
+	{"aiohttp", "aiohttp", KindTool},
+	{"go-http-client", "Go http client", KindTool},
+	{"okhttp", "OkHttp", KindTool},
+	{"java/", "Java", KindTool},
+	{"apache-httpclient", "Apache HttpClient", KindTool},
+	{"libwww-perl", "libwww-perl", KindTool},
+	{"axios/", "axios", KindTool},
+	{"node-fetch", "node-fetch", KindTool},
+	{"postmanruntime", "Postman", KindTool},
+
+	// Generic catch-alls
+	{"bot", "", KindCrawler},
+	{"crawl", "", KindCrawler},
+	{"spider", "", KindCrawler},
+	{"preview", "", KindUnfurler},
+}
+
+// Detector classifies User-Agents. It is safe for concurrent use.
+type Detector struct {
+	extra []signature
+}
+
+// New builds a Detector. extra are additional User-Agent substrings (case-insensitive)
+// to treat as crawlers; they are checked before the built-in list.
+func New(extra []string) *Detector {
+	d := &Detector{}
+	for _, token := range extra {
+		if token = strings.ToLower(strings.TrimSpace(token)); token != "" {
+			d.extra = append(d.extra, signature{token: token, name: token, kind: KindCrawler})
+		}
+	}
+	return d
+}
+
+// Classify reports what kind of client sent userAgent.
+func (d *Detector) Classify(userAgent string) Result {
+	ua := strings.ToLower(strings.TrimSpace(userAgent))
+	if ua == "" {
+		return Result{Bot: true, Kind: KindUnknown}
+	}
+	for _, list := range [][]signature{d.extra, signatures} {
+		for _, sig := range list {
+			if strings.Contains(ua, sig.token) {
+				return Result{Bot: true, Kind: sig.kind, Name: sig.name}
+			}
+		}
+	}
+	return Result{Kind: KindHuman}
+}
@@ -0,0 +1,52 @@
+package botdetect
+
+import "testing"
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		ua   string
+		want Result
+	}{
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36", Result{Kind: KindHuman}},
+		{"Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Mobile/15E148 Safari/604.1", Result{Kind: KindHuman}},
+		{"", Result{Bot: true, Kind: KindUnknown}},
+		{"   ", Result{Bot: true, Kind: KindUnknown}},
+		{"Slackbot-LinkExpanding 1.0 (+https://api.slack.com/robots)", Result{true, KindUnfurler, "Slackbot"}},
+		{"facebookexternalhit/1.1 (+http://www.facebook.com/externalhit_uatext.php)", Result{true, KindUnfurler, "Facebook"}},
+		{"WhatsApp/2.23.20.0", Result{true, KindUnfurler, "WhatsApp"}},
+		{"Mozilla/5.0 (compatible; Discordbot/2.0; +https://discordapp.com)", Result{true, KindUnfurler, "Discordbot"}}, // Named before the "bot" catch-all
+		{"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) HeadlessChrome/124.0.0.0 Safari/537.36", Result{true, KindHeadless, "HeadlessChrome"}},
+		{"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)", Result{true, KindCrawler, "Googlebot"}},
+		{"Mozilla/5.0 AppleWebKit/537.36 (KHTML, like Gecko; compatible; GPTBot/1.2; +https://openai.com/gptbot)", Result{true, KindCrawler, "GPTBot"}},
+		{"curl/8.5.0", Result{true, KindTool, "curl"}},
+		{"python-requests/2.31.0", Result{true, KindTool, "python-requests"}},
+		{"Go-http-client/1.1", Result{true, KindTool, "Go http client"}},
+		{"SomeNewBot/0.1", Result{true, KindCrawler, ""}},
+		{"example-spider", Result{true, KindCrawler, ""}},
+		{"LinkPreview/1.0", Result{true, KindUnfurler, ""}},
+	}
+	d := New(nil)
+	for _, tt := range tests {
+		if got := d.Classify(tt.ua); got != tt.want {
+			t.Errorf("Classify(%q) = %+v, want %+v", tt.ua, got, tt.want)
+		}
+	}
+}
+
+func TestExtraSignatures(t *testing.T) {
+	d := New([]string{" MonitorAgent ", "", "curl"})
+
+	tests := []struct {
+		ua   string
+		want Result
+	}{
+		{"Mozilla/5.0 monitoragent/3", Result{true, KindCrawler, "monitoragent"}},
+		{"curl/8.5.0", Result{true, KindCrawler, "curl"}}, // Extra tokens win over the built-in list
+		{"Mozilla/5.0 (Macintosh; Intel Mac OS X 14_4) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Safari/605.1.15", Result{Kind: KindHuman}},
+	}
+	for _, tt := range tests {
+		if got := d.Classify(tt.ua); got != tt.want {
+			t.Errorf("Classify(%q) = %+v, want %+v", tt.ua, got, tt.want)
+		}
+	}
+}
@@ -1,23 +1,74 @@
 package config
 
 import (
+	"errors"
+	"io/fs"
+	"strings"
+
 	"github.com/spf13/viper" // Configuration library for reading env and config files
 )
 
 // Config holds all configuration values loaded from environment or config file
 type Config struct {
-	AppEnv             string // Application environment (development, production, etc.)
-	HTTPPort           string // Port for HTTP server
-	DBHost             string // Database host address
-	DBPort             string // Database port
-	DBUser             string // Database user name
-	DBPass             string // Database password
-	DBName             string // Database name
-	RedisHost          string // Redis host address
-	RedisPort          string // Redis port
-	JWTSecret          string // JWT secret key
-	RateLimitRequests  int    // Number of requests allowed in rate limit window
-	RateLimitWindowSec int    // Duration of rate limit window in seconds
+	AppEnv              string   // Application environment (development, production, etc.)
+	HTTPPort            string   // Port for HTTP server
+	DBDriver            string   // Storage backend: mysql, postgres, sqlite or memory
+	DBPath              string   // SQLite database file (sqlite driver only)
+	DBHost              string   // Database host address
+	DBPort              string   // Database port
+	DBUser              string   // Database user name
+	DBPass              string   // Database password
+	DBName              string   // Database name
+	DBSSLMode           string   // PostgreSQL sslmode (disable, require, verify-full, ...)
+	AutoMigrate         bool     // Apply pending migrations on start (always on for sqlite)
+	RedisHost           string   // Redis host address; empty uses an in-process cache instead
+	RedisPort           string   // Redis port
+	JWTSecret           string   // JWT secret key; also signs link access cookies
+	JWTAlgorithm        string   // HS256 (with JWTSecret), RS256 or EdDSA (with keys in JWTKeyDir)
+	JWTKeyDir           string   // Directory of PEM signing keys for RS256/EdDSA
+	JWTRotationDays     int      // Age in days at which a new signing key is generated; 0 never rotates
+	JWTKeyOverlapHours  int      // Hours a replaced signing key still verifies tokens
+	AccessTokenSec      int      // Lifetime of access tokens (JWTs) in seconds
+	AppURL              string   // Public base URL used in emails; empty uses http://localhost:HTTP_PORT
+	Mailer              string   // How emails are sent: smtp, or log (written to MailLogFile)
+	MailFrom            string   // Sender address of outgoing emails
+	MailLogFile         string   // File the log mailer appends to; empty writes to stdout
+	SMTPHost            string   // SMTP server for the smtp mailer
+	SMTPPort            int      // SMTP server port (submission, STARTTLS)
+	SMTPUsername        string   // SMTP login; empty skips authentication
+	SMTPPassword        string   // SMTP password
+	RequireVerified     bool     // Only users with a verified email address may create links
+	Require2FA          bool     // Every account must log in with TOTP two-factor authentication
+	TOTPIssuer          string   // Name authenticator apps show next to URLSecure accounts
+	RefreshTokenDays    int      // Lifetime of refresh tokens in days; each refresh starts a new one
+	RateLimitRequests   int      // Number of requests allowed in rate limit window (authenticated API); 0 disables
+	RateLimitWindowSec  int      // Duration of rate limit window in seconds
+	AuthRateLimitReqs   int      // Requests per window for register/login; 0 disables
+	AuthRateLimitWindow int      // Window in seconds for register/login
+	RedirectLimitReqs   int      // Requests per window for short link visits; 0 disables
+	RedirectLimitWindow int      // Window in seconds for short link visits
+	BotUserAgents       []string // Extra User-Agent substrings counted as bot visits
+	GeoIPDB             string   // MaxMind-format country database for click events; empty disables
+	ClickBatchSize      int      // Click events written per batch
+	ClickFlushSec       int      // Longest a click event waits before being written, in seconds
+	ClickRollupSec      int      // How often click events are rolled up for stats; 0 disables
+	UniquesRetention    int      // Days unique visitor counts are kept
+	ClickPurgeSec       int      // How often click events past retention are deleted; 0 disables
+	IPv4Prefix          int      // Bits of client IPv4 addresses kept for analytics and logs; 0 keeps all
+	IPv6Prefix          int      // Bits of client IPv6 addresses kept for analytics and logs; 0 keeps all
+	AliasBlocklist      []string // Extra words that may not be used as custom aliases
+	CodeStrategy        string   // Short code generator: random, sequential or words
+	CodeLength          int      // Starting code length; 0 uses the strategy's default
+	CodeSalt            string   // Salt that shuffles the sequential strategy's alphabet
+	AllowedSchemes      []string // URL schemes allowed as link destinations
+	SelfHosts           []string // Our own public hostnames; links to them are rejected as loops
+	AllowPrivateTargets bool     // Allow destinations on private/loopback networks (development only)
+	BlocklistFiles      []string // Threat-intel lists as format:path (hosts, domains or hashes)
+	BlocklistReloadSec  int      // How often to check the lists for changes; 0 reloads on SIGHUP only
+	PhishThreshold      int      // Heuristics score that holds a link for review; 0 uses the default, negative disables
+	PhishBrands         []string // Extra brand names to protect from lookalike domains
+	PhishSuspiciousTLDs []string // Extra TLDs that add to the score
+	PreviewPolicy       string   // Which links show an interstitial first: link, risky or all
 }
 
 // Load reads configuration from .env file and environment variables
@@ -25,23 +76,115 @@ func Load() (*Config, error) {
 	viper.SetConfigFile(".env") // Load config values from .env file
 	viper.AutomaticEnv()        // Override with environment variables if set
 
-	if err := viper.ReadInConfig(); err != nil {
+	// Defaults for a self-contained deployment
+	viper.SetDefault("HTTP_PORT", "8080")
+	viper.SetDefault("DB_DRIVER", "mysql")
+	viper.SetDefault("DB_PATH", "urlsecure.db")
+	viper.SetDefault("DB_SSLMODE", "disable")
+	viper.SetDefault("CODE_STRATEGY", "random")
+	viper.SetDefault("ALLOWED_SCHEMES", "http,https")
+	viper.SetDefault("JWT_ALGORITHM", "HS256")
+	viper.SetDefault("JWT_KEY_DIR", "jwt-keys")
+	viper.SetDefault("JWT_ROTATION_DAYS", 30)
+	viper.SetDefault("JWT_KEY_OVERLAP_HOURS", 24)
+	viper.SetDefault("ACCESS_TOKEN_TTL", 900)
+	viper.SetDefault("MAILER", "log")
+	viper.SetDefault("MAIL_FROM", "URLSecure <no-reply@localhost>")
+	viper.SetDefault("SMTP_PORT", 587)
+	viper.SetDefault("TOTP_ISSUER", "URLSecure")
+	viper.SetDefault("REFRESH_TOKEN_TTL_DAYS", 30)
+	viper.SetDefault("RATE_LIMIT_REQUESTS", 100)
+	viper.SetDefault("RATE_LIMIT_WINDOW", 60)
+	viper.SetDefault("AUTH_RATE_LIMIT_REQUESTS", 10)
+	viper.SetDefault("AUTH_RATE_LIMIT_WINDOW", 60)
+	viper.SetDefault("REDIRECT_RATE_LIMIT_REQUESTS", 60)
+	viper.SetDefault("REDIRECT_RATE_LIMIT_WINDOW", 60)
+	viper.SetDefault("BLOCKLIST_RELOAD_SEC", 60)
+	viper.SetDefault("PREVIEW_POLICY", "link")
+	viper.SetDefault("CLICK_BATCH_SIZE", 500)
+	viper.SetDefault("CLICK_FLUSH_INTERVAL", 2)
+	viper.SetDefault("CLICK_ROLLUP_INTERVAL", 30)
+	viper.SetDefault("UNIQUES_RETENTION_DAYS", 400)
+	viper.SetDefault("CLICK_PURGE_INTERVAL", 3600)
+	viper.SetDefault("IP_V4_PREFIX", 24)
+	viper.SetDefault("IP_V6_PREFIX", 48)
+
+	// A missing .env file is fine when everything comes from the environment
+	if err := viper.ReadInConfig(); err != nil && !errors.Is(err, fs.ErrNotExist) {
 		return nil, err
 	}
 
 	// Populate Config struct using Viper getters
 	return &Config{
-		AppEnv:             viper.GetString("APP_ENV"),
-		HTTPPort:           viper.GetString("HTTP_PORT"),
-		DBHost:             viper.GetString("DB_HOST"),
-		DBPort:             viper.GetString("DB_PORT"),
-		DBUser:             viper.GetString("DB_USER"),
-		DBPass:             viper.GetString("DB_PASS"),
-		DBName:             viper.GetString("DB_NAME"),
-		RedisHost:          viper.GetString("REDIS_HOST"),
-		RedisPort:          viper.GetString("REDIS_PORT"),
-		JWTSecret:          viper.GetString("JWT_SECRET"),
-		RateLimitRequests:  viper.GetInt("RATE_LIMIT_REQUESTS"),
-		RateLimitWindowSec: viper.GetInt("RATE_LIMIT_WINDOW"),
+		AppEnv:              viper.GetString("APP_ENV"),
+		HTTPPort:            viper.GetString("HTTP_PORT"),
+		DBDriver:            viper.GetString("DB_DRIVER"),
+		DBPath:              viper.GetString("DB_PATH"),
+		DBHost:              viper.GetString("DB_HOST"),
+		DBPort:              viper.GetString("DB_PORT"),
+		DBUser:              viper.GetString("DB_USER"),
+		DBPass:              viper.GetString("DB_PASS"),
+		DBName:              viper.GetString("DB_NAME"),
+		DBSSLMode:           viper.GetString("DB_SSLMODE"),
+		AutoMigrate:         viper.GetBool("AUTO_MIGRATE"),
+		RedisHost:           viper.GetString("REDIS_HOST"),
+		RedisPort:           viper.GetString("REDIS_PORT"),
+		JWTSecret:           viper.GetString("JWT_SECRET"),
+		JWTAlgorithm:        viper.GetString("JWT_ALGORITHM"),
+		JWTKeyDir:           viper.GetString("JWT_KEY_DIR"),
+		JWTRotationDays:     viper.GetInt("JWT_ROTATION_DAYS"),
+		JWTKeyOverlapHours:  viper.GetInt("JWT_KEY_OVERLAP_HOURS"),
+		AccessTokenSec:      viper.GetInt("ACCESS_TOKEN_TTL"),
+		AppURL:              viper.GetString("APP_URL"),
+		Mailer:              viper.GetString("MAILER"),
+		MailFrom:            viper.GetString("MAIL_FROM"),
+		MailLogFile:         viper.GetString("MAIL_LOG_FILE"),
+		SMTPHost:            viper.GetString("SMTP_HOST"),
+		SMTPPort:            viper.GetInt("SMTP_PORT"),
+		SMTPUsername:        viper.GetString("SMTP_USERNAME"),
+		SMTPPassword:        viper.GetString("SMTP_PASSWORD"),
+		RequireVerified:     viper.GetBool("REQUIRE_VERIFIED_EMAIL"),
+		Require2FA:          viper.GetBool("REQUIRE_2FA"),
+		TOTPIssuer:          viper.GetString("TOTP_ISSUER"),
+		RefreshTokenDays:    viper.GetInt("REFRESH_TOKEN_TTL_DAYS"),
+		RateLimitRequests:   viper.GetInt("RATE_LIMIT_REQUESTS"),
+		RateLimitWindowSec:  viper.GetInt("RATE_LIMIT_WINDOW"),
+		AuthRateLimitReqs:   viper.GetInt("AUTH_RATE_LIMIT_REQUESTS"),
+		AuthRateLimitWindow: viper.GetInt("AUTH_RATE_LIMIT_WINDOW"),
+		RedirectLimitReqs:   viper.GetInt("REDIRECT_RATE_LIMIT_REQUESTS"),
+		RedirectLimitWindow: viper.GetInt("REDIRECT_RATE_LIMIT_WINDOW"),
+		BotUserAgents:       splitList(viper.GetString("BOT_USER_AGENTS")),
+		GeoIPDB:             viper.GetString("GEOIP_DB"),
+		ClickBatchSize:      viper.GetInt("CLICK_BATCH_SIZE"),
+		ClickFlushSec:       viper.GetInt("CLICK_FLUSH_INTERVAL"),
+		ClickRollupSec:      viper.GetInt("CLICK_ROLLUP_INTERVAL"),
+		UniquesRetention:    viper.GetInt("UNIQUES_RETENTION_DAYS"),
+		ClickPurgeSec:       viper.GetInt("CLICK_PURGE_INTERVAL"),
+		IPv4Prefix:          viper.GetInt("IP_V4_PREFIX"),
+		IPv6Prefix:          viper.GetInt("IP_V6_PREFIX"),
+		AliasBlocklist:      splitList(viper.GetString("ALIAS_BLOCKLIST")),
+		CodeStrategy:        viper.GetString("CODE_STRATEGY"),
+		CodeLength:          viper.GetInt("CODE_LENGTH"),
+		CodeSalt:            viper.GetString("CODE_SALT"),
+		AllowedSchemes:      splitList(viper.GetString("ALLOWED_SCHEMES")),
+		SelfHosts:           splitList(viper.GetString("SELF_HOSTS")),
+		AllowPrivateTargets: viper.GetBool("ALLOW_PRIVATE_TARGETS"),
+		BlocklistFiles:      splitList(viper.GetString("BLOCKLIST_FILES")),
+		BlocklistReloadSec:  viper.GetInt("BLOCKLIST_RELOAD_SEC"),
+		PhishThreshold:      viper.GetInt("PHISH_REVIEW_THRESHOLD"),
+		PhishBrands:         splitList(viper.GetString("PHISH_BRANDS")),
+		PhishSuspiciousTLDs: splitList(viper.GetString("PHISH_SUSPICIOUS_TLDS")),
+		PreviewPolicy:       viper.GetString("PREVIEW_POLICY"),
 	}, nil
 }
+
+// splitList parses a comma-separated env value, dropping blanks and surrounding spaces.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
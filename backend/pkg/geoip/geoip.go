@@ -0,0 +1,63 @@
+// Package geoip maps IP addresses to ISO country codes using a local MaxMind-format
+// database (GeoLite2-Country, GeoIP2-City, DB-IP's free mmdb files, ...).
+// Lookups never leave the process.
+package geoip
+
+import (
+	"net"
+	"net/netip"
+
+	"github.com/oschwald/maxminddb-golang" // MaxMind DB reader (memory-mapped)
+)
+
+// DB looks up countries. The zero value and a nil *DB know no countries, so
+// callers can use one unconditionally when no database is configured.
+type DB struct {
+	reader *maxminddb.Reader
+}
+
+// record is the part of a Country/City record we read.
+type record struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	RegisteredCountry struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"registered_country"`
+}
+
+// Open memory-maps the database at path. An empty path returns an empty DB.
+func Open(path string) (*DB, error) {
+	if path == "" {
+		return &DB{}, nil
+	}
+	reader, err := maxminddb.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &DB{reader: reader}, nil
+}
+
+// Country returns the two-letter ISO code for addr, or "" if it is unknown.
+// Anycast and satellite ranges without a country fall back to the registered country.
+func (db *DB) Country(addr netip.Addr) string {
+	if db == nil || db.reader == nil || !addr.IsValid() {
+		return ""
+	}
+	var rec record
+	if err := db.reader.Lookup(net.IP(addr.Unmap().AsSlice()), &rec); err != nil {
+		return ""
+	}
+	if rec.Country.ISOCode != "" {
+		return rec.Country.ISOCode
+	}
+	return rec.RegisteredCountry.ISOCode
+}
+
+// Close unmaps the database.
+func (db *DB) Close() error {
+	if db == nil || db.reader == nil {
+		return nil
+	}
+	return db.reader.Close()
+}
@@ -0,0 +1,145 @@
+// Package mailer sends plain-text emails through SMTP, or writes them to a log for
+// development and tests.
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Message is one plain-text email.
+type Message struct {
+	To      string // Single recipient address
+	Subject string
+	Body    string
+}
+
+// Mailer delivers messages.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// render builds the RFC 5322 message, rejecting header injection through To or Subject.
+func render(from string, msg Message, now time.Time) ([]byte, error) {
+	if strings.ContainsAny(msg.To, "\r\n") || strings.ContainsAny(msg.Subject, "\r\n") {
+		return nil, errors.New("mailer: line break in header")
+	}
+	if _, err := mail.ParseAddress(msg.To); err != nil {
+		return nil, fmt.Errorf("mailer: invalid recipient: %w", err)
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(msg.Body, "\r\n", "\n"), "\n", "\r\n"))
+	return b.Bytes(), nil
+}
+
+// SMTP sends mail through an SMTP server, upgrading to TLS with STARTTLS when the
+// server offers it and authenticating with PLAIN when a username is set.
+type SMTP struct {
+	Host     string // Server host name, also used to verify its certificate
+	Port     int    // Usually 587 (submission)
+	Username string // Empty skips authentication
+	Password string
+	From     string // Sender, e.g. "URLSecure <no-reply@example.com>"
+}
+
+// Send delivers msg, giving up when ctx is done.
+func (s *SMTP) Send(ctx context.Context, msg Message) error {
+	data, err := render(s.From, msg, time.Now())
+	if err != nil {
+		return err
+	}
+	from, err := mail.ParseAddress(s.From)
+	if err != nil {
+		return fmt.Errorf("mailer: invalid sender: %w", err)
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(s.Host, fmt.Sprint(s.Port)))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, s.Host)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: s.Host}); err != nil {
+			return err
+		}
+	}
+	if s.Username != "" {
+		// PlainAuth refuses to send the password over an unencrypted connection
+		if err := client.Auth(smtp.PlainAuth("", s.Username, s.Password, s.Host)); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(from.Address); err != nil {
+		return err
+	}
+	to, _ := mail.ParseAddress(msg.To) // Validated by render
+	if err := client.Rcpt(to.Address); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// Log writes every message to a writer instead of sending it, for development and tests.
+type Log struct {
+	From string
+
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewLog returns a Log mailer writing to w.
+func NewLog(from string, w io.Writer) *Log {
+	return &Log{From: from, w: w}
+}
+
+// Send writes msg followed by a separator line.
+func (l *Log) Send(ctx context.Context, msg Message) error {
+	data, err := render(l.From, msg, time.Now())
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, err = fmt.Fprintf(l.w, "%s\r\n----\r\n", data)
+	return err
+}
@@ -0,0 +1,107 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRender(t *testing.T) {
+	now := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	const from = "URLSecure <no-reply@example.com>"
+
+	tests := []struct {
+		name string
+		msg  Message
+		err  string
+		want []string // Substrings of the rendered message
+	}{
+		{
+			name: "plain message",
+			msg:  Message{To: "alice@example.com", Subject: "Verify your email", Body: "Hello\nworld"},
+			want: []string{
+				"From: URLSecure <no-reply@example.com>\r\n",
+				"To: alice@example.com\r\n",
+				"Subject: Verify your email\r\n",
+				"Date: Wed, 02 Jan 2030 03:04:05 +0000\r\n",
+				"\r\n\r\nHello\r\nworld",
+			},
+		},
+		{
+			name: "non-ASCII subject is encoded",
+			msg:  Message{To: "alice@example.com", Subject: "Café", Body: "x"},
+			want: []string{"Subject: =?utf-8?q?Caf=C3=A9?=\r\n"},
+		},
+		{
+			name: "CRLF body normalized",
+			msg:  Message{To: "alice@example.com", Subject: "s", Body: "a\r\nb\nc"},
+			want: []string{"\r\n\r\na\r\nb\r\nc"},
+		},
+		{
+			name: "LF in recipient",
+			msg:  Message{To: "alice@example.com\nBcc: mallory@example.com", Subject: "s"},
+			err:  "mailer: line break in header",
+		},
+		{
+			name: "CR in recipient",
+			msg:  Message{To: "alice@example.com\rBcc: mallory@example.com", Subject: "s"},
+			err:  "mailer: line break in header",
+		},
+		{
+			name: "CRLF in subject",
+			msg:  Message{To: "alice@example.com", Subject: "Hi\r\nBcc: mallory@example.com"},
+			err:  "mailer: line break in header",
+		},
+		{
+			name: "invalid recipient",
+			msg:  Message{To: "not an address", Subject: "s"},
+			err:  "mailer: invalid recipient",
+		},
+		{
+			name: "several recipients",
+			msg:  Message{To: "alice@example.com, mallory@example.com", Subject: "s"},
+			err:  "mailer: invalid recipient",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := render(from, tt.msg, now)
+			if tt.err != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), tt.err) {
+					t.Fatalf("err = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.want {
+				if !bytes.Contains(data, []byte(want)) {
+					t.Errorf("rendered message lacks %q:\n%s", want, data)
+				}
+			}
+		})
+	}
+}
+
+func TestLogSend(t *testing.T) {
+	var buf bytes.Buffer
+	m := NewLog("no-reply@example.com", &buf)
+
+	if err := m.Send(context.Background(), Message{To: "alice@example.com", Subject: "Hi", Body: "Hello"}); err != nil {
+		t.Fatal(err)
+	}
+	if out := buf.String(); !strings.Contains(out, "To: alice@example.com\r\n") || !strings.HasSuffix(out, "Hello\r\n----\r\n") {
+		t.Errorf("Log wrote %q", out)
+	}
+
+	buf.Reset()
+	if err := m.Send(context.Background(), Message{To: "alice@example.com", Subject: "Hi\nBcc: mallory@example.com"}); err == nil {
+		t.Error("Send accepted a subject with a line break")
+	}
+	if buf.Len() != 0 {
+		t.Errorf("rejected message was written: %q", buf.String())
+	}
+}
@@ -0,0 +1,58 @@
+package phishscore
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm" // Decomposition to strip accents
+)
+
+// confusables maps characters that render like an ASCII letter to that letter.
+// A small subset of Unicode TR39, covering the scripts seen in real homograph attacks.
+var confusables = map[rune]string{
+	// Cyrillic
+	'а': "a", 'в': "b", 'е': "e", 'ё': "e", 'һ': "h", 'і': "i", 'ї': "i", 'ј': "j", 'к': "k",
+	'ӏ': "l", 'м': "m", 'о': "o", 'р': "p", 'ԛ': "q", 'с': "c", 'ѕ': "s", 'т': "t", 'у': "y",
+	'ү': "y", 'х': "x", 'ԝ': "w", 'ԁ': "d",
+	// Greek
+	'α': "a", 'β': "b", 'ε': "e", 'ι': "i", 'κ': "k", 'ν': "v", 'ο': "o", 'ρ': "p", 'τ': "t",
+	'υ': "u", 'χ': "x", 'ϲ': "c",
+	// Latin extensions and symbols
+	'ı': "i", 'ɩ': "i", 'ɡ': "g", 'ɑ': "a", 'ℓ': "l", 'ƅ': "b", 'ꮃ': "w",
+	// Digits and ASCII pairs used in lookalike registrations
+	'0': "o", '1': "l", '3': "e", '5': "s", '7': "t",
+}
+
+// asciiPairs are multi-letter sequences that read as one letter at a glance.
+var asciiPairs = strings.NewReplacer("rn", "m", "vv", "w", "cl", "d")
+
+// skeleton reduces label to the ASCII letters it visually resembles:
+// accents are stripped, confusable characters replaced and hyphens dropped.
+func skeleton(label string) string {
+	var b strings.Builder
+	for _, r := range norm.NFD.String(strings.ToLower(label)) {
+		switch {
+		case unicode.Is(unicode.Mn, r), r == '-':
+			// Combining accents and hyphens do not change how a brand reads
+		case confusables[r] != "":
+			b.WriteString(confusables[r])
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return asciiPairs.Replace(b.String())
+}
+
+// mixedScript reports whether label combines Latin letters with Cyrillic or Greek ones.
+func mixedScript(label string) bool {
+	var latin, other bool
+	for _, r := range label {
+		switch {
+		case unicode.Is(unicode.Latin, r):
+			latin = true
+		case unicode.Is(unicode.Cyrillic, r), unicode.Is(unicode.Greek, r):
+			other = true
+		}
+	}
+	return latin && other
+}
@@ -0,0 +1,239 @@
+// Package phishscore scores URLs for signs of phishing: brand homographs and lookalikes,
+// userinfo tricks, raw IP hosts, deep subdomain chains and TLDs popular with abusers.
+// A score says "worth a human look", not "malicious"; hard blocks belong in urlpolicy.
+package phishscore
+
+import (
+	"fmt"
+	"net/netip"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/idna"         // Punycode back to Unicode for homograph checks
+	"golang.org/x/net/publicsuffix" // Registrable domain (eTLD+1) detection
+)
+
+// Signal codes returned in a Result.
+const (
+	SignalHomograph        = "homograph"
+	SignalMixedScript      = "mixed_script"
+	SignalBrandLookalike   = "brand_lookalike"
+	SignalBrandInSubdomain = "brand_in_subdomain"
+	SignalBrandInDomain    = "brand_in_domain"
+	SignalSubdomains       = "excessive_subdomains"
+	SignalUserinfo         = "userinfo"
+	SignalIPHost           = "ip_host"
+	SignalSuspiciousTLD    = "suspicious_tld"
+)
+
+// DefaultThreshold is the score at which a link is held for review.
+const DefaultThreshold = 50
+
+// DefaultBrands are frequently impersonated names, matched against the registrable label.
+var DefaultBrands = []string{
+	"adobe", "amazon", "apple", "bankofamerica", "binance", "chase", "coinbase", "dhl",
+	"docusign", "dropbox", "facebook", "fedex", "github", "google", "icloud", "instagram",
+	"linkedin", "microsoft", "netflix", "office365", "outlook", "paypal", "steam",
+	"twitter", "wellsfargo", "whatsapp", "yahoo",
+}
+
+// DefaultSuspiciousTLDs are top-level domains heavily used for throwaway phishing sites.
+var DefaultSuspiciousTLDs = []string{
+	"buzz", "cam", "cf", "click", "country", "ga", "gq", "icu", "kim", "loan", "men",
+	"ml", "mov", "quest", "rest", "review", "support", "tk", "top", "work", "xyz", "zip",
+}
+
+// Signal is one heuristic that fired, with the points it added.
+type Signal struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Weight  int    `json:"weight"`
+}
+
+// Result is the outcome of scoring a URL.
+type Result struct {
+	Score   int      // Sum of signal weights, capped at 100
+	Signals []Signal // Heuristics that fired
+	Flagged bool     // Score reached the threshold; hold for review
+}
+
+// Config configures a Scorer.
+type Config struct {
+	Threshold      int      // Score at which Result.Flagged is set; 0 uses DefaultThreshold, negative never flags
+	Brands         []string // Added to DefaultBrands
+	SuspiciousTLDs []string // Added to DefaultSuspiciousTLDs
+}
+
+// Scorer applies the heuristics. It is safe for concurrent use.
+type Scorer struct {
+	threshold int
+	brands    map[string]struct{}
+	skeletons map[string]string // skeleton(brand) -> brand
+	tlds      map[string]struct{}
+}
+
+// New builds a Scorer from cfg.
+func New(cfg Config) *Scorer {
+	s := &Scorer{
+		threshold: cfg.Threshold,
+		brands:    make(map[string]struct{}),
+		skeletons: make(map[string]string),
+		tlds:      make(map[string]struct{}),
+	}
+	if s.threshold == 0 {
+		s.threshold = DefaultThreshold
+	}
+	for _, brand := range append(DefaultBrands, cfg.Brands...) {
+		brand = strings.ToLower(brand)
+		s.brands[brand] = struct{}{}
+		s.skeletons[skeleton(brand)] = brand
+	}
+	for _, tld := range append(DefaultSuspiciousTLDs, cfg.SuspiciousTLDs...) {
+		s.tlds[strings.TrimPrefix(strings.ToLower(tld), ".")] = struct{}{}
+	}
+	return s
+}
+
+// Score evaluates rawURL, which should already be normalized (see urlpolicy.Normalize).
+// Unparseable URLs score 0; rejecting them is the policy's job.
+func (s *Scorer) Score(rawURL string) Result {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return Result{}
+	}
+
+	var r Result
+	add := func(code string, weight int, format string, args ...any) {
+		r.Signals = append(r.Signals, Signal{Code: code, Message: fmt.Sprintf(format, args...), Weight: weight})
+		r.Score += weight
+	}
+
+	// https://paypal.com@evil.tld shows a trusted name where the user looks first
+	if u.User != nil {
+		if name := u.User.Username(); strings.Contains(name, ".") {
+			add(SignalUserinfo, 60, "URL starts with %q before an @, disguising the real host", name)
+		} else {
+			add(SignalUserinfo, 30, "URL contains credentials before the host")
+		}
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if _, err := netip.ParseAddr(host); err == nil {
+		add(SignalIPHost, 30, "host is a raw IP address")
+		return s.finish(r)
+	}
+
+	s.scoreHost(host, add)
+	return s.finish(r)
+}
+
+// scoreHost applies the domain-name heuristics.
+func (s *Scorer) scoreHost(host string, add func(code string, weight int, format string, args ...any)) {
+	suffix, _ := publicsuffix.PublicSuffix(host)
+	if _, ok := s.tlds[suffix[strings.LastIndexByte(suffix, '.')+1:]]; ok {
+		add(SignalSuspiciousTLD, 20, "top-level domain .%s is common on phishing sites", suffix)
+	}
+
+	registrable, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return
+	}
+	label := strings.TrimSuffix(registrable, "."+suffix)
+
+	var subdomains []string
+	if sub := strings.TrimSuffix(host, registrable); sub != "" {
+		subdomains = strings.Split(strings.TrimSuffix(sub, "."), ".")
+	}
+	if n := len(subdomains); n > 3 {
+		add(SignalSubdomains, min(30, 10+5*(n-4)), "host has %d levels of subdomains", n)
+	}
+
+	// Homographs: punycode labels whose Unicode form imitates a brand
+	unicodeLabel, err := idna.ToUnicode(label)
+	if err != nil {
+		unicodeLabel = label
+	}
+	if unicodeLabel != label {
+		if brand, ok := s.brand(skeleton(unicodeLabel)); ok {
+			add(SignalHomograph, 70, "%s.%s uses look-alike characters to imitate %s", unicodeLabel, suffix, brand)
+			return
+		}
+		if mixedScript(unicodeLabel) {
+			add(SignalMixedScript, 30, "%s.%s mixes characters from different alphabets", unicodeLabel, suffix)
+		}
+	}
+
+	// ASCII lookalikes (paypa1, rnicrosoft) and one-letter typos (gooogle)
+	if _, isBrand := s.brands[label]; !isBrand {
+		if brand, ok := s.brand(skeleton(label)); ok {
+			add(SignalBrandLookalike, 50, "%s imitates %s", registrable, brand)
+		} else if brand, ok := s.typoOf(label); ok {
+			add(SignalBrandLookalike, 40, "%s is one letter away from %s", registrable, brand)
+		} else if brand, ok := s.containedBrand(label); ok {
+			add(SignalBrandInDomain, 25, "%s contains the brand name %s", registrable, brand)
+		}
+	}
+
+	// paypal.com.account-verify.tld puts the brand where users expect the domain
+	for _, sub := range subdomains {
+		if _, ok := s.brands[sub]; ok {
+			add(SignalBrandInSubdomain, 35, "brand name %s appears as a subdomain of %s", sub, registrable)
+			break
+		}
+	}
+}
+
+// finish caps the score and applies the threshold.
+func (s *Scorer) finish(r Result) Result {
+	r.Score = min(r.Score, 100)
+	r.Flagged = s.threshold > 0 && r.Score >= s.threshold
+	return r
+}
+
+// brand finds the known brand whose skeleton equals skel.
+func (s *Scorer) brand(skel string) (string, bool) {
+	brand, ok := s.skeletons[skel]
+	return brand, ok
+}
+
+// typoOf finds a brand of at least 5 letters within one edit of label.
+func (s *Scorer) typoOf(label string) (string, bool) {
+	for brand := range s.brands {
+		if len(brand) >= 5 && withinOneEdit(label, brand) {
+			return brand, true
+		}
+	}
+	return "", false
+}
+
+// containedBrand finds a brand of at least 5 letters inside label, e.g. paypal-secure.
+func (s *Scorer) containedBrand(label string) (string, bool) {
+	for brand := range s.brands {
+		if len(brand) >= 5 && strings.Contains(label, brand) {
+			return brand, true
+		}
+	}
+	return "", false
+}
+
+// withinOneEdit reports whether a and b differ by exactly one insertion, deletion or substitution.
+func withinOneEdit(a, b string) bool {
+	if a == b {
+		return false
+	}
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	if len(b)-len(a) > 1 {
+		return false
+	}
+
+	i := 0
+	for i < len(a) && a[i] == b[i] {
+		i++
+	}
+	if len(a) == len(b) {
+		return a[i+1:] == b[i+1:] // Substitution
+	}
+	return a[i:] == b[i+1:] // Insertion
+}
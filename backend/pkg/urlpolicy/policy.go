@@ -0,0 +1,253 @@
+// Package urlpolicy decides whether a URL may be used as a short link destination.
+// It normalizes the URL, enforces a scheme allowlist, resolves the host and rejects
+// private or loopback addresses, refuses links that point back at our own domains and,
+// when configured, links whose destination is on a threat-intelligence blocklist.
+package urlpolicy
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ConstantineCTF/URLSecure/backend/pkg/blocklist" // Local threat-intel lists
+	"golang.org/x/net/idna"                                     // Internationalized domain names to punycode
+)
+
+// Reason codes returned in a Rejection.
+const (
+	ReasonInvalidURL     = "invalid_url"
+	ReasonScheme         = "scheme_not_allowed"
+	ReasonPrivateAddress = "private_address"
+	ReasonUnresolvable   = "unresolvable_host"
+	ReasonSelfReference  = "self_reference"
+	ReasonBlocklisted    = "blocklisted"
+)
+
+// Reason is one structured explanation for rejecting a URL.
+type Reason struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Rejection is returned by Check when a URL violates the policy.
+type Rejection struct {
+	Reasons []Reason
+}
+
+// Error joins the reason messages.
+func (r *Rejection) Error() string {
+	msgs := make([]string, len(r.Reasons))
+	for i, reason := range r.Reasons {
+		msgs[i] = reason.Message
+	}
+	return "url rejected: " + strings.Join(msgs, "; ")
+}
+
+// reject builds a single-reason Rejection.
+func reject(code, format string, args ...any) *Rejection {
+	return &Rejection{Reasons: []Reason{{Code: code, Message: fmt.Sprintf(format, args...)}}}
+}
+
+// Resolver looks up the addresses of a host; *net.Resolver implements it.
+type Resolver interface {
+	LookupNetIP(ctx context.Context, network, host string) ([]netip.Addr, error)
+}
+
+// Config configures a Policy.
+type Config struct {
+	AllowedSchemes []string        // Defaults to http and https
+	SelfHosts      []string        // Our own hostnames; links to them would loop through the shortener
+	AllowPrivate   bool            // Skip the private address check (local development only)
+	Blocklist      *blocklist.List // Known-bad destinations; nil disables the check
+	Resolver       Resolver        // Defaults to net.DefaultResolver
+	LookupTimeout  time.Duration   // Defaults to 3s
+}
+
+// Policy validates short link destinations. It is safe for concurrent use.
+type Policy struct {
+	schemes       map[string]struct{}
+	selfHosts     map[string]struct{}
+	allowPrivate  bool
+	blocklist     *blocklist.List
+	resolver      Resolver
+	lookupTimeout time.Duration
+}
+
+// New builds a Policy from cfg, filling in defaults.
+func New(cfg Config) *Policy {
+	p := &Policy{
+		schemes:       make(map[string]struct{}),
+		selfHosts:     make(map[string]struct{}),
+		allowPrivate:  cfg.AllowPrivate,
+		blocklist:     cfg.Blocklist,
+		resolver:      cfg.Resolver,
+		lookupTimeout: cfg.LookupTimeout,
+	}
+
+	schemes := cfg.AllowedSchemes
+	if len(schemes) == 0 {
+		schemes = []string{"http", "https"}
+	}
+	for _, scheme := range schemes {
+		p.schemes[strings.ToLower(scheme)] = struct{}{}
+	}
+	for _, host := range cfg.SelfHosts {
+		if h, err := normalizeHost(host); err == nil {
+			p.selfHosts[h] = struct{}{}
+		}
+	}
+
+	if p.resolver == nil {
+		p.resolver = net.DefaultResolver
+	}
+	if p.lookupTimeout == 0 {
+		p.lookupTimeout = 3 * time.Second
+	}
+	return p
+}
+
+// Check validates raw and returns its normalized form. selfHosts adds hosts that
+// count as our own for this call only, typically the Host header of the request.
+// Violations are reported as a *Rejection; other errors do not occur.
+func (p *Policy) Check(ctx context.Context, raw string, selfHosts ...string) (string, error) {
+	u, err := Normalize(raw)
+	if err != nil {
+		return "", err
+	}
+
+	if _, ok := p.schemes[u.Scheme]; !ok {
+		return "", reject(ReasonScheme, "scheme %q is not allowed", u.Scheme)
+	}
+
+	host := u.Hostname()
+	if p.isSelf(host, selfHosts) {
+		return "", reject(ReasonSelfReference, "links to %s would redirect back to this service", host)
+	}
+
+	normalized := u.String()
+	if p.blocklist != nil {
+		if match, ok := p.blocklist.Match(normalized); ok {
+			return "", reject(ReasonBlocklisted, "destination is listed as malicious (%s)", match.Source)
+		}
+	}
+
+	if !p.allowPrivate {
+		if err := p.checkAddresses(ctx, host); err != nil {
+			return "", err
+		}
+	}
+
+	return normalized, nil
+}
+
+// Normalize parses raw into a canonical absolute URL: lower-case scheme and host,
+// punycode host, no trailing dot, default ports dropped and an empty path written as "/".
+func Normalize(raw string) (*url.URL, error) {
+	raw = strings.TrimSpace(raw)
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, reject(ReasonInvalidURL, "url could not be parsed")
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	if u.Scheme == "" {
+		return nil, reject(ReasonInvalidURL, "url must be absolute")
+	}
+	if u.Opaque != "" || u.Host == "" {
+		// javascript:, data:, mailto: and similar have no host; report them as disallowed schemes
+		return nil, reject(ReasonScheme, "scheme %q is not allowed", u.Scheme)
+	}
+
+	host, err := normalizeHost(u.Hostname())
+	if err != nil {
+		return nil, reject(ReasonInvalidURL, "invalid host: %v", err)
+	}
+
+	// Drop ports that are implied by the scheme
+	port := u.Port()
+	if (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		port = ""
+	}
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]" // IPv6 literal
+	}
+	if port != "" {
+		host += ":" + port
+	}
+	u.Host = host
+
+	if u.Path == "" && u.RawPath == "" {
+		u.Path = "/"
+	}
+	return u, nil
+}
+
+// normalizeHost lower-cases host, strips a trailing dot and converts IDNs to punycode.
+func normalizeHost(host string) (string, error) {
+	host = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(host)), ".")
+	if host == "" {
+		return "", fmt.Errorf("empty host")
+	}
+	if _, err := netip.ParseAddr(host); err == nil {
+		return host, nil
+	}
+	return idna.Lookup.ToASCII(host)
+}
+
+// isSelf reports whether host is one of our own domains or a subdomain of one.
+func (p *Policy) isSelf(host string, extra []string) bool {
+	matches := func(self string) bool {
+		return host == self || strings.HasSuffix(host, "."+self)
+	}
+	for self := range p.selfHosts {
+		if matches(self) {
+			return true
+		}
+	}
+	for _, self := range extra {
+		// Request Host headers may carry a port
+		if h, _, err := net.SplitHostPort(self); err == nil {
+			self = h
+		}
+		if self, err := normalizeHost(self); err == nil && matches(self) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkAddresses rejects hosts that are, or resolve to, non-public addresses.
+func (p *Policy) checkAddresses(ctx context.Context, host string) error {
+	// localhost and *.localhost always mean loopback (RFC 6761), whatever DNS says
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return reject(ReasonPrivateAddress, "%s is a loopback host", host)
+	}
+
+	// IP literals, including the decimal/octal/hex IPv4 forms browsers accept
+	if addr, ok := parseIPHost(host); ok {
+		if !isPublic(addr) {
+			return reject(ReasonPrivateAddress, "%s is not a public address", addr)
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.lookupTimeout)
+	defer cancel()
+
+	addrs, err := p.resolver.LookupNetIP(ctx, "ip", host)
+	if err != nil || len(addrs) == 0 {
+		return reject(ReasonUnresolvable, "host %s could not be resolved", host)
+	}
+
+	// Every address must be public, otherwise a round-robin entry could point inside
+	for _, addr := range addrs {
+		if !isPublic(addr) {
+			return reject(ReasonPrivateAddress, "%s resolves to non-public address %s", host, addr.Unmap())
+		}
+	}
+	return nil
+}
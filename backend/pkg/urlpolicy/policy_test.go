@@ -0,0 +1,178 @@
+package urlpolicy
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"testing"
+)
+
+// fakeResolver answers lookups from a fixed table; unknown hosts do not resolve.
+type fakeResolver map[string][]string
+
+func (f fakeResolver) LookupNetIP(ctx context.Context, network, host string) ([]netip.Addr, error) {
+	var addrs []netip.Addr
+	for _, s := range f[host] {
+		addrs = append(addrs, netip.MustParseAddr(s))
+	}
+	if len(addrs) == 0 {
+		return nil, errors.New("no such host")
+	}
+	return addrs, nil
+}
+
+func TestParseIPHost(t *testing.T) {
+	tests := []struct {
+		host string
+		want string // Empty when host is not an IP literal
+	}{
+		{"127.0.0.1", "127.0.0.1"},
+		{"2130706433", "127.0.0.1"},       // Single decimal number
+		{"0x7f000001", "127.0.0.1"},       // Single hex number
+		{"017700000001", "127.0.0.1"},     // Single octal number
+		{"0177.0.0.1", "127.0.0.1"},       // Octal first part
+		{"0x7f.0x0.0x0.0x1", "127.0.0.1"}, // Hex parts
+		{"0X7F.1", "127.0.0.1"},           // Short form, upper-case prefix
+		{"127.1", "127.0.0.1"},            // Short form: last part fills three bytes
+		{"10.1.1", "10.1.0.1"},            // Short form: last part fills two bytes
+		{"0", "0.0.0.0"},
+		{"0x", "0.0.0.0"},
+		{"::1", "::1"},
+		{"[::1]", "::1"},
+		{"::ffff:127.0.0.1", "::ffff:127.0.0.1"},
+		{"[::ffff:7f00:1]", "::ffff:127.0.0.1"},
+		{"256.0.0.1", ""},   // Non-final part over 255
+		{"127.0.0.256", ""}, // Final part too big for one byte
+		{"127.0.65536", ""}, // Final part too big for two bytes
+		{"4294967296", ""},  // Over 32 bits
+		{"1.2.3.4.5", ""},   // Too many parts
+		{"08.0.0.1", ""},    // 8 is not an octal digit
+		{"127.0.0.", ""},    // Empty part
+		{"example.com", ""},
+		{"1e100.net", ""},
+	}
+	for _, tt := range tests {
+		addr, ok := parseIPHost(tt.host)
+		switch {
+		case tt.want == "" && ok:
+			t.Errorf("parseIPHost(%q) = %v, want no IP", tt.host, addr)
+		case tt.want != "" && (!ok || addr != netip.MustParseAddr(tt.want)):
+			t.Errorf("parseIPHost(%q) = %v, %t, want %s", tt.host, addr, ok, tt.want)
+		}
+	}
+}
+
+func TestIsPublic(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"10.0.0.1", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false}, // Cloud metadata service
+		{"100.64.0.1", false},
+		{"0.0.0.0", false},
+		{"255.255.255.255", false},
+		{"224.0.0.1", false},
+		{"::1", false},
+		{"::", false},
+		{"fe80::1", false},
+		{"fc00::1", false},
+		{"2001:db8::1", false},
+		{"::ffff:127.0.0.1", false}, // IPv4-mapped loopback
+		{"::ffff:10.0.0.1", false},
+		{"::ffff:93.184.216.34", true},
+	}
+	for _, tt := range tests {
+		if got := isPublic(netip.MustParseAddr(tt.addr)); got != tt.want {
+			t.Errorf("isPublic(%s) = %t, want %t", tt.addr, got, tt.want)
+		}
+	}
+}
+
+// rejectionCode returns the first reason code of err, or "" if err is nil.
+func rejectionCode(t *testing.T, err error) string {
+	t.Helper()
+	if err == nil {
+		return ""
+	}
+	var rejection *Rejection
+	if !errors.As(err, &rejection) {
+		t.Fatalf("error %v is not a *Rejection", err)
+	}
+	return rejection.Reasons[0].Code
+}
+
+func TestCheck(t *testing.T) {
+	p := New(Config{
+		SelfHosts: []string{"short.example"},
+		Resolver: fakeResolver{
+			"public.example":        {"93.184.216.34"},
+			"internal.example":      {"10.0.0.5"},
+			"roundrobin.example":    {"93.184.216.34", "127.0.0.1"},
+			"mapped.example":        {"::ffff:192.168.0.1"},
+			"xn--bcher-kva.example": {"93.184.216.34"},
+		},
+	})
+	tests := []struct {
+		raw  string
+		want string // Reason code, or "" when allowed
+	}{
+		{"https://public.example/path", ""},
+		{"https://93.184.216.34/", ""},
+		{"https://bücher.example/", ""},
+		{"http://127.0.0.1/", ReasonPrivateAddress},
+		{"http://2130706433/", ReasonPrivateAddress},
+		{"http://0x7f.1/", ReasonPrivateAddress},
+		{"http://0177.0.0.1/", ReasonPrivateAddress},
+		{"http://127.1:8080/", ReasonPrivateAddress},
+		{"http://0xa9fea9fe/latest/meta-data", ReasonPrivateAddress}, // 169.254.169.254
+		{"http://[::1]/", ReasonPrivateAddress},
+		{"http://[::ffff:127.0.0.1]/", ReasonPrivateAddress},
+		{"http://[::ffff:a00:1]/", ReasonPrivateAddress},
+		{"http://localhost/", ReasonPrivateAddress},
+		{"http://app.localhost/", ReasonPrivateAddress},
+		{"https://internal.example/", ReasonPrivateAddress},
+		{"https://roundrobin.example/", ReasonPrivateAddress},
+		{"https://mapped.example/", ReasonPrivateAddress},
+		{"https://unknown.example/", ReasonUnresolvable},
+		{"https://short.example/abc", ReasonSelfReference},
+		{"https://www.SHORT.example./abc", ReasonSelfReference},
+		{"https://api.test:8443/", ReasonSelfReference}, // From the request's Host header
+		{"ftp://public.example/", ReasonScheme},
+		{"javascript:alert(1)", ReasonScheme},
+		{"/relative", ReasonInvalidURL},
+	}
+	for _, tt := range tests {
+		_, err := p.Check(context.Background(), tt.raw, "api.test:8443")
+		if got := rejectionCode(t, err); got != tt.want {
+			t.Errorf("Check(%q) = %q (%v), want %q", tt.raw, got, err, tt.want)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct{ raw, want string }{
+		{"HTTP://Example.COM", "http://example.com/"},
+		{"https://example.com:443/a?b=c", "https://example.com/a?b=c"},
+		{"http://example.com:8080", "http://example.com:8080/"},
+		{"https://example.com./", "https://example.com/"},
+		{"https://Bücher.example/ü", "https://xn--bcher-kva.example/%C3%BC"},
+		{"  https://example.com/  ", "https://example.com/"},
+		{"http://[::1]:80/", "http://[::1]/"},
+	}
+	for _, tt := range tests {
+		u, err := Normalize(tt.raw)
+		if err != nil {
+			t.Errorf("Normalize(%q): %v", tt.raw, err)
+			continue
+		}
+		if got := u.String(); got != tt.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
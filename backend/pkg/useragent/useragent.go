@@ -0,0 +1,91 @@
+// Package useragent extracts browser, operating system and device type from a
+// User-Agent header. It covers the browsers that make up nearly all real traffic
+// and reports everything else as "Other"; bot detection lives in botdetect.
+package useragent
+
+import "strings"
+
+// Device types.
+const (
+	Desktop = "desktop"
+	Mobile  = "mobile"
+	Tablet  = "tablet"
+)
+
+// Other is reported for browsers and systems that are not recognized.
+const Other = "Other"
+
+// Agent is what a User-Agent says about the visitor's software.
+type Agent struct {
+	Browser string // e.g. "Chrome", "Safari", "Firefox"
+	OS      string // e.g. "Windows", "iOS", "Android"
+	Device  string // Desktop, Mobile or Tablet
+}
+
+// token maps a User-Agent substring to a name.
+type token struct {
+	substr string
+	name   string
+}
+
+// browsers are checked in order: Chromium derivatives mention Chrome and Safari
+// too, so they must come before them.
+var browsers = []token{
+	{"edg/", "Edge"}, {"edga/", "Edge"}, {"edgios/", "Edge"},
+	{"opr/", "Opera"}, {"opera", "Opera"},
+	{"samsungbrowser/", "Samsung Internet"},
+	{"yabrowser/", "Yandex Browser"},
+	{"vivaldi/", "Vivaldi"},
+	{"ucbrowser/", "UC Browser"},
+	{"fxios/", "Firefox"}, {"firefox/", "Firefox"},
+	{"crios/", "Chrome"}, {"chrome/", "Chrome"}, {"chromium/", "Chrome"},
+	{"version/", "Safari"}, // Safari puts its version here; checked after Chrome
+	{"msie ", "Internet Explorer"}, {"trident/", "Internet Explorer"},
+}
+
+// systems are checked in order: iOS and Android user agents also mention
+// "Mac OS X" and "Linux".
+var systems = []token{
+	{"windows", "Windows"},
+	{"iphone", "iOS"}, {"ipad", "iOS"}, {"ipod", "iOS"},
+	{"android", "Android"},
+	{"cros", "ChromeOS"},
+	{"mac os x", "macOS"}, {"macintosh", "macOS"},
+	{"linux", "Linux"},
+}
+
+// Parse describes userAgent. An empty header yields an empty Agent.
+func Parse(userAgent string) Agent {
+	ua := strings.ToLower(userAgent)
+	if strings.TrimSpace(ua) == "" {
+		return Agent{}
+	}
+	return Agent{
+		Browser: match(ua, browsers),
+		OS:      match(ua, systems),
+		Device:  device(ua),
+	}
+}
+
+// match returns the name of the first token found in ua, or Other.
+func match(ua string, tokens []token) string {
+	for _, t := range tokens {
+		if strings.Contains(ua, t.substr) {
+			return t.name
+		}
+	}
+	return Other
+}
+
+// device guesses the form factor. Android tablets omit "Mobile" from their user agent.
+func device(ua string) string {
+	switch {
+	case strings.Contains(ua, "ipad"), strings.Contains(ua, "tablet"),
+		strings.Contains(ua, "android") && !strings.Contains(ua, "mobile"):
+		return Tablet
+	case strings.Contains(ua, "mobi"), strings.Contains(ua, "iphone"), strings.Contains(ua, "ipod"):
+		return Mobile
+	default:
+		return Desktop
+	}
+}
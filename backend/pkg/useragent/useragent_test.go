@@ -0,0 +1,44 @@
+package useragent
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		ua   string
+		want Agent
+	}{
+		{"Chrome on Windows", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36",
+			Agent{"Chrome", "Windows", Desktop}},
+		{"Edge before Chrome", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36 Edg/124.0.2478.51",
+			Agent{"Edge", "Windows", Desktop}},
+		{"Opera", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/123.0.0.0 Safari/537.36 OPR/109.0.0.0",
+			Agent{"Opera", "Windows", Desktop}},
+		{"Safari on macOS", "Mozilla/5.0 (Macintosh; Intel Mac OS X 14_4) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Safari/605.1.15",
+			Agent{"Safari", "macOS", Desktop}},
+		{"Firefox on Linux", "Mozilla/5.0 (X11; Linux x86_64; rv:125.0) Gecko/20100101 Firefox/125.0",
+			Agent{"Firefox", "Linux", Desktop}},
+		{"Safari on iPhone", "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Mobile/15E148 Safari/604.1",
+			Agent{"Safari", "iOS", Mobile}},
+		{"Chrome on iPad", "Mozilla/5.0 (iPad; CPU OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) CriOS/124.0.6367.88 Mobile/15E148 Safari/604.1",
+			Agent{"Chrome", "iOS", Tablet}},
+		{"Firefox on iPhone", "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) FxiOS/125.0 Mobile/15E148 Safari/605.1.15",
+			Agent{"Firefox", "iOS", Mobile}},
+		{"Samsung Internet on Android phone", "Mozilla/5.0 (Linux; Android 14; SM-S918B) AppleWebKit/537.36 (KHTML, like Gecko) SamsungBrowser/24.0 Chrome/117.0.0.0 Mobile Safari/537.36",
+			Agent{"Samsung Internet", "Android", Mobile}},
+		{"Android tablet without Mobile", "Mozilla/5.0 (Linux; Android 13; SM-X700) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36",
+			Agent{"Chrome", "Android", Tablet}},
+		{"ChromeOS", "Mozilla/5.0 (X11; CrOS x86_64 14541.0.0) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36",
+			Agent{"Chrome", "ChromeOS", Desktop}},
+		{"Internet Explorer", "Mozilla/5.0 (Windows NT 10.0; Trident/7.0; rv:11.0) like Gecko",
+			Agent{"Internet Explorer", "Windows", Desktop}},
+		{"unrecognized", "curl/8.5.0", Agent{Other, Other, Desktop}},
+		{"empty", "", Agent{}},
+		{"blank", "  ", Agent{}},
+	}
+	for _, tt := range tests {
+		if got := Parse(tt.ua); got != tt.want {
+			t.Errorf("%s: Parse(%q) = %+v, want %+v", tt.name, tt.ua, got, tt.want)
+		}
+	}
+}
@@ -1,5 +1,50 @@
 // /assets/main.js
 
+// Session helpers shared by the pages. Access tokens live only minutes; when the API
+// answers 401 the refresh token is exchanged for a new pair and the request retried.
+async function refreshSession() {
+  const refreshToken = localStorage.getItem('refreshToken');
+  if (!refreshToken) return false;
+  const res = await fetch('/api/token/refresh', {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify({ refreshToken })
+  });
+  if (!res.ok) {
+    localStorage.removeItem('token');
+    localStorage.removeItem('refreshToken');
+    return false;
+  }
+  const data = await res.json();
+  localStorage.setItem('token', data.token);
+  localStorage.setItem('refreshToken', data.refreshToken);
+  return true;
+}
+
+async function authFetch(url, options = {}) {
+  const send = () => fetch(url, {
+    ...options,
+    headers: { ...options.headers, 'Authorization': `Bearer ${localStorage.getItem('token')}` }
+  });
+  const res = await send();
+  if (res.status === 401 && await refreshSession()) return send();
+  return res;
+}
+
+async function logout() {
+  const refreshToken = localStorage.getItem('refreshToken');
+  try {
+    await authFetch('/api/logout', {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ refreshToken })
+    });
+  } finally {
+    localStorage.removeItem('token');
+    localStorage.removeItem('refreshToken');
+  }
+}
+
 document.addEventListener('DOMContentLoaded', () => {
   const form     = document.getElementById('shorten-form');
   const input    = document.getElementById('url-input');
@@ -29,12 +74,9 @@ document.addEventListener('DOMContentLoaded', () => {
     btn?.setAttribute('disabled', '');
 
     try {
-      const res = await fetch('/api/shorten', {
+      const res = await authFetch('/api/shorten', {
         method: 'POST',
-        headers: {
-          'Content-Type': 'application/json',
-          'Authorization': `Bearer ${token}`
-        },
+        headers: { 'Content-Type': 'application/json' },
         body: JSON.stringify({ url })
       });
       const data = await res.json();